@@ -0,0 +1,81 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/gen-ai-bootcamp-2025/backend_go/internal/importer"
+	"github.com/gen-ai-bootcamp-2025/backend_go/internal/models"
+	"github.com/gen-ai-bootcamp-2025/backend_go/internal/service"
+)
+
+// runImport implements the import subcommand:
+//
+//	server import [--group name | --group-id id] [--format csv|tsv|apkg]
+//	              [--mapping json] [--dry-run] file
+//
+// Without a group the words go into a group named after the file.
+func runImport(db *sql.DB, args []string) error {
+	flags := flag.NewFlagSet("import", flag.ContinueOnError)
+	group := flags.String("group", "", "name of the group to import into, created if missing")
+	groupID := flags.Int64("group-id", 0, "id of an existing group to import into")
+	format := flags.String("format", "", "csv, tsv or apkg (default: from the file extension)")
+	mapping := flags.String("mapping", "", `JSON object mapping word fields to columns, e.g. {"japanese":"Kanji"}`)
+	dryRun := flags.Bool("dry-run", false, "report what would change without writing anything")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if flags.NArg() != 1 {
+		return errors.New("usage: server import [flags] file")
+	}
+
+	path := flags.Arg(0)
+	if *group == "" && *groupID == 0 {
+		*group = strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+	}
+	m, err := importer.ParseMapping(*mapping)
+	if err != nil {
+		return err
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	info, err := file.Stat()
+	if err != nil {
+		return err
+	}
+
+	svc := service.NewService(models.NewDB(db))
+	report, err := svc.ImportWords(context.Background(), service.ImportRequest{
+		FileName:  path,
+		File:      file,
+		Size:      info.Size(),
+		Format:    *format,
+		Mapping:   m,
+		GroupID:   *groupID,
+		GroupName: *group,
+		DryRun:    *dryRun,
+	})
+	if err != nil {
+		return err
+	}
+
+	fmt.Println(importer.Summary(report.ImportResult))
+	for _, e := range report.Errors {
+		if e.Field != "" {
+			fmt.Printf("  row %d: %s: %s\n", e.Row, e.Field, e.Message)
+		} else {
+			fmt.Printf("  row %d: %s\n", e.Row, e.Message)
+		}
+	}
+	return nil
+}
@@ -1,18 +1,41 @@
 package main
 
 import (
+	"context"
 	"database/sql"
+	"errors"
+	"flag"
+	"fmt"
+	"github.com/gen-ai-bootcamp-2025/backend_go/db/migrations"
 	"github.com/gin-gonic/gin"
+	"github.com/gen-ai-bootcamp-2025/backend_go/internal/auth"
+	"github.com/gen-ai-bootcamp-2025/backend_go/internal/config"
 	"github.com/gen-ai-bootcamp-2025/backend_go/internal/handlers"
 	"github.com/gen-ai-bootcamp-2025/backend_go/internal/models"
 	"github.com/gen-ai-bootcamp-2025/backend_go/internal/service"
 	_ "github.com/mattn/go-sqlite3"
 	"log"
+	"log/slog"
+	"net"
+	"os"
+	"os/signal"
+	"syscall"
 )
 
-func setupRouter(h *handlers.Handler) *gin.Engine {
-	r := gin.Default()
-	api := r.Group("/api")
+func setupRouter(h *handlers.Handler, cfg *config.Config, logger *slog.Logger) *gin.Engine {
+	r := gin.New()
+	r.Use(handlers.Logger(logger), gin.Recovery(), handlers.CORS(cfg.CORSOrigins), handlers.Errors())
+	r.NoRoute(handlers.NoRoute)
+	r.POST("/api/auth/login", h.Login)
+
+	// Every other route needs an API key or a session token. Learners may
+	// study and read; teachers also edit vocabulary and the activity catalog;
+	// admins also manage users and reset data.
+	api := r.Group("/api", h.Authenticate)
+	teacher := api.Group("", handlers.Require(models.RoleTeacher))
+	admin := api.Group("", handlers.Require(models.RoleAdmin))
+
+	api.GET("/auth/me", h.GetCurrentUser)
 
 	// Dashboard routes
 	api.GET("/dashboard/last_study_session", h.GetLastStudySession)
@@ -20,46 +43,188 @@ func setupRouter(h *handlers.Handler) *gin.Engine {
 	api.GET("/dashboard/quick-stats", h.GetQuickStats)
 
 	// Study activities routes
+	api.GET("/study_activities", h.GetStudyActivities)
 	api.GET("/study_activities/:id", h.GetStudyActivity)
 	api.GET("/study_activities/:id/study_sessions", h.GetStudyActivitySessions)
-	api.POST("/study_activities", h.CreateStudyActivity)
+	teacher.POST("/study_activities", h.CreateStudyActivity)
+	teacher.PUT("/study_activities/:id", h.UpdateStudyActivity)
+	teacher.DELETE("/study_activities/:id", h.DeleteStudyActivity)
 
 	// Words routes
 	api.GET("/words", h.GetWords)
+	api.GET("/words/search", h.SearchWords)
 	api.GET("/words/:id", h.GetWord)
+	teacher.POST("/words", h.CreateWord)
+	teacher.PUT("/words/:id", h.UpdateWord)
+	teacher.PATCH("/words/:id", h.PatchWord)
+	teacher.DELETE("/words/:id", h.DeleteWord)
 
 	// Groups routes
 	api.GET("/groups", h.GetGroups)
 	api.GET("/groups/:id", h.GetGroup)
+	teacher.POST("/groups", h.CreateGroup)
+	teacher.PUT("/groups/:id", h.RenameGroup)
+	teacher.DELETE("/groups/:id", h.DeleteGroup)
 	api.GET("/groups/:id/words", h.GetGroupWords)
+	teacher.POST("/groups/:id/words", h.AddGroupWords)
+	teacher.DELETE("/groups/:id/words", h.RemoveGroupWords)
+	teacher.PUT("/groups/:id/words/:word_id", h.AddGroupWord)
+	teacher.DELETE("/groups/:id/words/:word_id", h.RemoveGroupWord)
 	api.GET("/groups/:id/study_sessions", h.GetGroupStudySessions)
+	api.GET("/groups/:id/due_words", h.GetGroupDueWords)
+	api.GET("/groups/:id/export", h.ExportGroupWords)
+
+	// Spaced repetition routes
+	api.GET("/review_queue", h.GetReviewQueue)
 
 	// Study sessions routes
 	api.GET("/study_sessions", h.GetStudySessions)
+	api.GET("/study_sessions/export", h.ExportReviews)
+	api.GET("/reviews", h.GetReviews)
+	api.POST("/study_sessions", h.CreateStudySession)
 	api.GET("/study_sessions/:id", h.GetStudySession)
+	api.GET("/study_sessions/:id/summary", h.GetStudySessionSummary)
+	api.POST("/study_sessions/:id/end", h.EndStudySession)
 	api.GET("/study_sessions/:id/words", h.GetStudySessionWords)
 	api.POST("/study_sessions/:id/words/:word_id/review", h.ReviewWord)
 
+	// Import routes
+	teacher.POST("/import", h.ImportWords)
+
+	// xAPI learning record store
+	api.GET("/xapi/statements", h.GetStatements)
+	api.POST("/xapi/statements", h.PostStatements)
+
+	// User routes
+	teacher.GET("/users", h.GetUsers)
+	teacher.GET("/users/:id", h.GetUser)
+	admin.POST("/users", h.CreateUser)
+	api.PATCH("/users/:id", h.UpdateUser)
+	admin.GET("/users/:id/api_keys", h.GetAPIKeys)
+	admin.POST("/users/:id/api_keys", h.CreateAPIKey)
+	admin.DELETE("/users/:id/api_keys/:key_id", h.DeleteAPIKey)
+
 	// System routes
-	api.POST("/reset_history", h.ResetHistory)
-	api.POST("/full_reset", h.FullReset)
+	admin.POST("/reset_history", h.ResetHistory)
+	admin.POST("/full_reset", h.FullReset)
 
 	return r
 }
 
 func main() {
-	db, err := sql.Open("sqlite3", "words.db")
+	cfg, args, err := config.Load(os.Args[1:], os.Getenv)
+	if errors.Is(err, flag.ErrHelp) {
+		fmt.Fprintln(os.Stderr, "usage: server [flags] [migrate | seed | import | user] [args]")
+		config.Usage(os.Stderr)
+		return
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
+	level, _ := cfg.SlogLevel()
+	logger := slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: level}))
+	gin.SetMode(cfg.GinMode)
+
+	command := ""
+	if len(args) > 0 {
+		command, args = args[0], args[1:]
+	}
+
+	db, err := sql.Open("sqlite3", models.DSN(cfg.DBPath))
 	if err != nil {
 		log.Fatal("Failed to open database:", err)
 	}
 	defer db.Close()
 
+	if command == "migrate" {
+		if err := runMigrate(db, args); err != nil {
+			log.Fatal("Migration failed:", err)
+		}
+		return
+	}
+
+	migrator, err := migrations.New(db, migrations.FS)
+	if err != nil {
+		log.Fatal("Failed to load migrations:", err)
+	}
+	if err := migrator.Check(); err != nil {
+		log.Fatal("Database schema is out of date (run `server migrate` or `mage migrate`): ", err)
+	}
+
+	switch command {
+	case "":
+	case "seed":
+		if err := runSeed(db, args); err != nil {
+			log.Fatal("Seeding failed:", err)
+		}
+		return
+	case "import":
+		if err := runImport(db, args); err != nil {
+			log.Fatal("Import failed:", err)
+		}
+		return
+	case "user":
+		if err := runUser(db, args); err != nil {
+			log.Fatal("User update failed:", err)
+		}
+		return
+	default:
+		log.Fatalf("Unknown command %q (want migrate, seed, import or user)", command)
+	}
+
 	modelDB := models.NewDB(db)
+	modelDB.SetQueryTimeout(cfg.QueryTimeout)
+	if err := modelDB.InitSearchIndex(context.Background()); err != nil {
+		log.Fatal("Failed to build search index:", err)
+	}
+	if !models.FullTextSearch {
+		logger.Warn("built without the sqlite_fts5 tag; word search falls back to a slow LIKE scan of every word")
+	}
+
 	svc := service.NewService(modelDB)
-	h := handlers.NewHandler(svc)
+	svc.SetPageSize(cfg.PerPage, cfg.MaxPerPage)
+	svc.SetSessionIdleTimeout(cfg.SessionIdleTimeout)
+	svc.SetCrossGroupReviews(cfg.CrossGroupReviews)
+	svc.SetActivityBase(cfg.APIBase())
+	h := handlers.NewHandler(svc, auth.NewTokens(tokenSecret(cfg, logger), cfg.TokenTTL))
+	h.SetMaxImportSize(int64(cfg.MaxImportMB) << 20)
 
-	r := setupRouter(h)
-	if err := r.Run(":8080"); err != nil {
+	ln, err := net.Listen("tcp", cfg.Addr)
+	if err != nil {
 		log.Fatal("Failed to start server:", err)
 	}
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	sweepDone := make(chan struct{})
+	go func() {
+		defer close(sweepDone)
+		if cfg.SessionIdleTimeout > 0 {
+			abandonIdleSessions(ctx, svc, sweepInterval(cfg.SessionIdleTimeout), logger)
+		}
+	}()
+
+	logger.Info("listening", "addr", ln.Addr().String(), "tls", cfg.TLS(), "db", cfg.DBPath, "gin_mode", cfg.GinMode)
+	serveErr := serve(ctx, newServer(cfg, setupRouter(h, cfg, logger), logger), ln, cfg, logger)
+	stop()
+	<-sweepDone
+	if err := modelDB.Close(); err != nil {
+		logger.Error("closing database", "error", err)
+	}
+	if serveErr != nil {
+		log.Fatal("Server stopped: ", serveErr)
+	}
+	logger.Info("stopped")
+}
+
+// tokenSecret returns the key session tokens are signed with. Without a
+// configured token_secret a random key is used, so signing in again is
+// needed after every restart.
+func tokenSecret(cfg *config.Config, logger *slog.Logger) []byte {
+	if cfg.TokenSecret != "" {
+		return []byte(cfg.TokenSecret)
+	}
+	logger.Warn("token_secret is not set; session tokens will not survive a restart")
+	return auth.RandomSecret()
 }
@@ -0,0 +1,65 @@
+package main
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"strconv"
+
+	"github.com/gen-ai-bootcamp-2025/backend_go/db/migrations"
+)
+
+const migrateUsage = "usage: server migrate [up | status | rollback [steps]]"
+
+// runMigrate implements the migrate subcommand
+func runMigrate(db *sql.DB, args []string) error {
+	m, err := migrations.New(db, migrations.FS)
+	if err != nil {
+		return err
+	}
+
+	command := "up"
+	if len(args) > 0 {
+		command = args[0]
+	}
+
+	switch command {
+	case "up":
+		applied, err := m.Up()
+		for _, migration := range applied {
+			fmt.Printf("Applied migration: %04d_%s\n", migration.Version, migration.Name)
+		}
+		return err
+	case "status":
+		statuses, err := m.Status()
+		if err != nil {
+			return err
+		}
+		for _, s := range statuses {
+			state := "pending"
+			if s.Applied {
+				state = "applied " + s.AppliedAt.Format("2006-01-02 15:04:05")
+			}
+			if s.Modified {
+				state += " (modified since applied)"
+			}
+			fmt.Printf("%04d_%s  %s\n", s.Version, s.Name, state)
+		}
+		return nil
+	case "rollback":
+		steps := 1
+		if len(args) > 1 {
+			steps, err = strconv.Atoi(args[1])
+			if err != nil || steps < 1 {
+				return fmt.Errorf("steps must be a positive number")
+			}
+		}
+		reverted, err := m.Rollback(steps)
+		for _, migration := range reverted {
+			fmt.Printf("Rolled back migration: %04d_%s\n", migration.Version, migration.Name)
+		}
+		return err
+	default:
+		return errors.New(migrateUsage)
+	}
+}
@@ -0,0 +1,33 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"flag"
+	"fmt"
+
+	"github.com/gen-ai-bootcamp-2025/backend_go/internal/importer"
+	"github.com/gen-ai-bootcamp-2025/backend_go/internal/models"
+)
+
+// runSeed implements the seed subcommand:
+//
+//	server seed [--dry-run] [dir]
+func runSeed(db *sql.DB, args []string) error {
+	flags := flag.NewFlagSet("seed", flag.ContinueOnError)
+	dryRun := flags.Bool("dry-run", false, "report what would change without writing anything")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	dir := "db/seeds"
+	if flags.NArg() > 0 {
+		dir = flags.Arg(0)
+	}
+
+	results, err := importer.SeedDir(context.Background(), models.NewDB(db), dir, *dryRun)
+	for _, result := range results {
+		fmt.Println(importer.Summary(result))
+	}
+	return err
+}
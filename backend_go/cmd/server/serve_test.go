@@ -0,0 +1,141 @@
+package main
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"io"
+	"log/slog"
+	"math/big"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/gen-ai-bootcamp-2025/backend_go/internal/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var discardLogger = slog.New(slog.NewTextHandler(io.Discard, nil))
+
+// startServer serves handler on a free port until the returned cancel is
+// called; the result of serve is sent on the returned channel
+func startServer(t *testing.T, cfg *config.Config, handler http.Handler) (string, context.CancelFunc, <-chan error) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+
+	done := make(chan error, 1)
+	go func() { done <- serve(ctx, newServer(cfg, handler, discardLogger), ln, cfg, discardLogger) }()
+	return ln.Addr().String(), cancel, done
+}
+
+// slowHandler answers after delay, closing started when a request arrives
+func slowHandler(delay time.Duration, started chan struct{}) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		close(started)
+		time.Sleep(delay)
+		io.WriteString(w, "done")
+	})
+}
+
+func TestServeDrainsRequestsOnShutdown(t *testing.T) {
+	started := make(chan struct{})
+	addr, stop, done := startServer(t, config.Default(), slowHandler(200*time.Millisecond, started))
+
+	type result struct {
+		body string
+		err  error
+	}
+	responses := make(chan result, 1)
+	go func() {
+		resp, err := http.Get("http://" + addr + "/")
+		if err != nil {
+			responses <- result{err: err}
+			return
+		}
+		defer resp.Body.Close()
+		body, err := io.ReadAll(resp.Body)
+		responses <- result{string(body), err}
+	}()
+
+	<-started
+	stop()
+	res := <-responses
+	require.NoError(t, res.err)
+	assert.Equal(t, "done", res.body, "the request in flight finishes")
+	assert.NoError(t, <-done)
+
+	_, err := net.DialTimeout("tcp", addr, time.Second)
+	assert.Error(t, err, "no new connections after shutdown")
+}
+
+func TestServeShutdownTimeout(t *testing.T) {
+	cfg := config.Default()
+	cfg.ShutdownTimeout = 50 * time.Millisecond
+	started := make(chan struct{})
+	addr, stop, done := startServer(t, cfg, slowHandler(2*time.Second, started))
+
+	go http.Get("http://" + addr + "/")
+	<-started
+	stop()
+	err := <-done
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "requests still running after 50ms")
+}
+
+func TestServeTLS(t *testing.T) {
+	cfg := config.Default()
+	cfg.TLSCertFile, cfg.TLSKeyFile = writeSelfSignedCert(t)
+	require.NoError(t, cfg.Validate())
+
+	started := make(chan struct{})
+	addr, stop, done := startServer(t, cfg, slowHandler(0, started))
+
+	client := &http.Client{Transport: &http.Transport{
+		TLSClientConfig: &tls.Config{InsecureSkipVerify: true},
+	}}
+	resp, err := client.Get("https://" + addr + "/")
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.NotNil(t, resp.TLS)
+
+	stop()
+	assert.NoError(t, <-done)
+}
+
+// writeSelfSignedCert writes a certificate for 127.0.0.1 and its key as PEM
+// files and returns their paths
+func writeSelfSignedCert(t *testing.T) (certFile, keyFile string) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "localhost"},
+		IPAddresses:  []net.IP{net.ParseIP("127.0.0.1")},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	require.NoError(t, err)
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	require.NoError(t, err)
+
+	dir := t.TempDir()
+	certFile, keyFile = filepath.Join(dir, "cert.pem"), filepath.Join(dir, "key.pem")
+	require.NoError(t, os.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0o600))
+	require.NoError(t, os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), 0o600))
+	return certFile, keyFile
+}
@@ -0,0 +1,41 @@
+package main
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"github.com/gen-ai-bootcamp-2025/backend_go/internal/service"
+)
+
+// abandonIdleSessions abandons idle study sessions now, which catches
+// sessions left open while the server was down, and then every interval
+// until ctx is done
+func abandonIdleSessions(ctx context.Context, svc *service.Service, interval time.Duration, logger *slog.Logger) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		n, err := svc.AbandonIdleStudySessions(ctx)
+		switch {
+		case err != nil && ctx.Err() == nil:
+			logger.Error("abandoning idle study sessions", "error", err)
+		case n > 0:
+			logger.Info("abandoned idle study sessions", "count", n)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// sweepInterval is how often to look for idle study sessions: often enough
+// that a session is abandoned soon after its idle timeout
+func sweepInterval(idleTimeout time.Duration) time.Duration {
+	if interval := idleTimeout / 10; interval < time.Minute {
+		return max(interval, time.Second)
+	}
+	return time.Minute
+}
@@ -0,0 +1,68 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"flag"
+	"fmt"
+
+	"github.com/gen-ai-bootcamp-2025/backend_go/internal/models"
+	"github.com/gen-ai-bootcamp-2025/backend_go/internal/service"
+)
+
+// runUser implements the user subcommand, which is how the first admin is
+// made:
+//
+//	server user [--role learner|teacher|admin] [--password pw] [--api-key name] name
+//
+// The user is created if there is none with that name. --api-key prints a
+// new API key for the user.
+func runUser(db *sql.DB, args []string) error {
+	flags := flag.NewFlagSet("user", flag.ContinueOnError)
+	role := flags.String("role", "", "role to give the user (default: learner for new users)")
+	password := flags.String("password", "", "password to sign in with")
+	apiKey := flags.String("api-key", "", "create an API key with this name")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if flags.NArg() != 1 {
+		return errors.New("usage: server user [flags] name")
+	}
+
+	svc := service.NewService(models.NewDB(db))
+	// Whoever can run the server against its database is an admin
+	ctx := service.WithUser(context.Background(), &models.User{Role: models.RoleAdmin})
+
+	user, err := svc.CreateUser(ctx, flags.Arg(0), models.Role(*role))
+	if errors.Is(err, service.ErrUserExists) {
+		user, err = svc.GetUserByName(ctx, flags.Arg(0))
+	}
+	if err != nil {
+		return err
+	}
+
+	patch := service.UserPatch{}
+	if *role != "" {
+		r := models.Role(*role)
+		patch.Role = &r
+	}
+	if *password != "" {
+		patch.Password = password
+	}
+	if patch.Role != nil || patch.Password != nil {
+		if user, err = svc.UpdateUser(ctx, user.ID, patch); err != nil {
+			return err
+		}
+	}
+	fmt.Printf("user %d %q (%s)\n", user.ID, user.Name, user.Role)
+
+	if *apiKey != "" {
+		key, err := svc.CreateAPIKey(ctx, user.ID, *apiKey)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("api key %q: %s\n", key.Name, key.Key)
+	}
+	return nil
+}
@@ -26,6 +26,7 @@ CREATE TABLE IF NOT EXISTS study_activities (
     study_session_id INTEGER,
     group_id INTEGER NOT NULL,
     created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (study_session_id) REFERENCES study_sessions(id),
     FOREIGN KEY (group_id) REFERENCES groups(id)
 );
 
@@ -38,9 +39,6 @@ CREATE TABLE IF NOT EXISTS study_sessions (
     FOREIGN KEY (study_activity_id) REFERENCES study_activities(id)
 );
 
--- Add the study_session_id foreign key constraint after both tables exist
-ALTER TABLE study_activities ADD FOREIGN KEY (study_session_id) REFERENCES study_sessions(id);
-
 CREATE TABLE IF NOT EXISTS word_review_items (
     id INTEGER PRIMARY KEY AUTOINCREMENT,
     word_id INTEGER NOT NULL,
@@ -0,0 +1,12 @@
+-- Catalog entries have no equivalent in the original table and are dropped.
+
+DROP TABLE study_activities;
+
+CREATE TABLE study_activities (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    study_session_id INTEGER,
+    group_id INTEGER NOT NULL,
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (study_session_id) REFERENCES study_sessions(id),
+    FOREIGN KEY (group_id) REFERENCES groups(id)
+);
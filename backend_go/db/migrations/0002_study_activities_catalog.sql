@@ -0,0 +1,17 @@
+-- Turn study_activities into a catalog of launchable learning apps.
+-- The original table modeled a session link (study_session_id, group_id),
+-- which duplicated what study_sessions already records.
+
+CREATE TABLE study_activities_new (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    name TEXT NOT NULL,
+    description TEXT NOT NULL DEFAULT '',
+    thumbnail_url TEXT NOT NULL DEFAULT '',
+    launch_url TEXT NOT NULL,
+    enabled BOOLEAN NOT NULL DEFAULT 1,
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP
+);
+
+DROP TABLE study_activities;
+
+ALTER TABLE study_activities_new RENAME TO study_activities;
@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS idx_word_progress_due_at;
+DROP TABLE IF EXISTS word_progress;
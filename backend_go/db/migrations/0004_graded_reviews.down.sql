@@ -0,0 +1,4 @@
+ALTER TABLE word_review_items DROP COLUMN direction;
+ALTER TABLE word_review_items DROP COLUMN answer;
+ALTER TABLE word_review_items DROP COLUMN response_time_ms;
+ALTER TABLE word_review_items DROP COLUMN grade;
@@ -0,0 +1,9 @@
+-- Record how well a word was recalled instead of a bare correct/incorrect flag.
+-- correct is kept in sync (grade <> 'again') so existing statistics keep working.
+
+ALTER TABLE word_review_items ADD COLUMN grade TEXT NOT NULL DEFAULT 'good';
+ALTER TABLE word_review_items ADD COLUMN response_time_ms INTEGER;
+ALTER TABLE word_review_items ADD COLUMN answer TEXT;
+ALTER TABLE word_review_items ADD COLUMN direction TEXT;
+
+UPDATE word_review_items SET grade = CASE WHEN correct THEN 'good' ELSE 'again' END;
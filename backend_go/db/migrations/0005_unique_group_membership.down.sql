@@ -0,0 +1 @@
+DROP INDEX IF EXISTS idx_words_groups_word_group;
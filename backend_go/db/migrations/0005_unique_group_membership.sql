@@ -0,0 +1,8 @@
+-- A word can only be in a group once
+
+DELETE FROM words_groups
+WHERE id NOT IN (
+    SELECT MIN(id) FROM words_groups GROUP BY word_id, group_id
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_words_groups_word_group ON words_groups(word_id, group_id);
@@ -0,0 +1,4 @@
+DROP INDEX IF EXISTS idx_word_review_items_statement_id;
+DROP INDEX IF EXISTS idx_study_sessions_registration;
+ALTER TABLE word_review_items DROP COLUMN statement_id;
+ALTER TABLE study_sessions DROP COLUMN registration;
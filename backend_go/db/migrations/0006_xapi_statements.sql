@@ -0,0 +1,8 @@
+-- Remember the xAPI statements reviews came from, so a statement sent twice is
+-- only recorded once, and the registration a study session was reported under.
+
+ALTER TABLE study_sessions ADD COLUMN registration TEXT;
+ALTER TABLE word_review_items ADD COLUMN statement_id TEXT;
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_study_sessions_registration ON study_sessions(registration);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_word_review_items_statement_id ON word_review_items(statement_id);
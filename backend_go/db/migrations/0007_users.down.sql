@@ -0,0 +1,26 @@
+-- Only the default user's spaced-repetition state survives a rollback
+CREATE TABLE word_progress_by_word (
+    word_id INTEGER PRIMARY KEY,
+    ease_factor REAL NOT NULL DEFAULT 2.5,
+    interval_days INTEGER NOT NULL DEFAULT 0,
+    repetitions INTEGER NOT NULL DEFAULT 0,
+    lapses INTEGER NOT NULL DEFAULT 0,
+    due_at DATETIME NOT NULL,
+    last_reviewed_at DATETIME,
+    FOREIGN KEY (word_id) REFERENCES words(id)
+);
+
+INSERT INTO word_progress_by_word
+    (word_id, ease_factor, interval_days, repetitions, lapses, due_at, last_reviewed_at)
+SELECT word_id, ease_factor, interval_days, repetitions, lapses, due_at, last_reviewed_at
+FROM word_progress WHERE user_id = 1;
+
+DROP TABLE word_progress;
+ALTER TABLE word_progress_by_word RENAME TO word_progress;
+CREATE INDEX IF NOT EXISTS idx_word_progress_due_at ON word_progress(due_at);
+
+DROP INDEX IF EXISTS idx_word_review_items_user;
+DROP INDEX IF EXISTS idx_study_sessions_user;
+ALTER TABLE word_review_items DROP COLUMN user_id;
+ALTER TABLE study_sessions DROP COLUMN user_id;
+DROP TABLE IF EXISTS users;
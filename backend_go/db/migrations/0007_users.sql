@@ -0,0 +1,40 @@
+-- Learner accounts. Study history now belongs to a user; everything recorded
+-- before this migration is given to the "default" user (id 1).
+
+CREATE TABLE IF NOT EXISTS users (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    name TEXT NOT NULL UNIQUE,
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP
+);
+
+INSERT OR IGNORE INTO users (id, name) VALUES (1, 'default');
+
+ALTER TABLE study_sessions ADD COLUMN user_id INTEGER NOT NULL DEFAULT 1;
+ALTER TABLE word_review_items ADD COLUMN user_id INTEGER NOT NULL DEFAULT 1;
+
+CREATE INDEX IF NOT EXISTS idx_study_sessions_user ON study_sessions(user_id, created_at);
+CREATE INDEX IF NOT EXISTS idx_word_review_items_user ON word_review_items(user_id, word_id);
+
+-- Spaced-repetition state is kept per user and word
+CREATE TABLE word_progress_by_user (
+    user_id INTEGER NOT NULL,
+    word_id INTEGER NOT NULL,
+    ease_factor REAL NOT NULL DEFAULT 2.5,
+    interval_days INTEGER NOT NULL DEFAULT 0,
+    repetitions INTEGER NOT NULL DEFAULT 0,
+    lapses INTEGER NOT NULL DEFAULT 0,
+    due_at DATETIME NOT NULL,
+    last_reviewed_at DATETIME,
+    PRIMARY KEY (user_id, word_id),
+    FOREIGN KEY (user_id) REFERENCES users(id),
+    FOREIGN KEY (word_id) REFERENCES words(id)
+);
+
+INSERT INTO word_progress_by_user
+    (user_id, word_id, ease_factor, interval_days, repetitions, lapses, due_at, last_reviewed_at)
+SELECT 1, word_id, ease_factor, interval_days, repetitions, lapses, due_at, last_reviewed_at
+FROM word_progress;
+
+DROP TABLE word_progress;
+ALTER TABLE word_progress_by_user RENAME TO word_progress;
+CREATE INDEX IF NOT EXISTS idx_word_progress_due_at ON word_progress(user_id, due_at);
@@ -0,0 +1,4 @@
+DROP INDEX IF EXISTS idx_api_keys_user;
+DROP TABLE IF EXISTS api_keys;
+ALTER TABLE users DROP COLUMN password_hash;
+ALTER TABLE users DROP COLUMN role;
@@ -0,0 +1,106 @@
+-- Back to foreign keys without ON DELETE behavior. Removed orphans and merged
+-- groups are not restored.
+
+CREATE TABLE api_keys_old (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    user_id INTEGER NOT NULL,
+    name TEXT NOT NULL,
+    key_hash TEXT NOT NULL UNIQUE,
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    last_used_at DATETIME,
+    FOREIGN KEY (user_id) REFERENCES users(id)
+);
+INSERT INTO api_keys_old (id, user_id, name, key_hash, created_at, last_used_at)
+SELECT id, user_id, name, key_hash, created_at, last_used_at FROM api_keys;
+DELETE FROM sqlite_sequence WHERE name = 'api_keys_old';
+INSERT INTO sqlite_sequence (name, seq)
+SELECT 'api_keys_old', seq FROM sqlite_sequence WHERE name = 'api_keys';
+DROP TABLE api_keys;
+ALTER TABLE api_keys_old RENAME TO api_keys;
+CREATE INDEX idx_api_keys_user ON api_keys(user_id);
+
+CREATE TABLE word_progress_old (
+    user_id INTEGER NOT NULL,
+    word_id INTEGER NOT NULL,
+    ease_factor REAL NOT NULL DEFAULT 2.5,
+    interval_days INTEGER NOT NULL DEFAULT 0,
+    repetitions INTEGER NOT NULL DEFAULT 0,
+    lapses INTEGER NOT NULL DEFAULT 0,
+    due_at DATETIME NOT NULL,
+    last_reviewed_at DATETIME,
+    PRIMARY KEY (user_id, word_id),
+    FOREIGN KEY (user_id) REFERENCES users(id),
+    FOREIGN KEY (word_id) REFERENCES words(id)
+);
+INSERT INTO word_progress_old
+    (user_id, word_id, ease_factor, interval_days, repetitions, lapses, due_at, last_reviewed_at)
+SELECT user_id, word_id, ease_factor, interval_days, repetitions, lapses, due_at, last_reviewed_at
+FROM word_progress;
+DROP TABLE word_progress;
+ALTER TABLE word_progress_old RENAME TO word_progress;
+CREATE INDEX idx_word_progress_due_at ON word_progress(user_id, due_at);
+
+CREATE TABLE word_review_items_old (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    word_id INTEGER NOT NULL,
+    study_session_id INTEGER NOT NULL,
+    correct BOOLEAN NOT NULL,
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    grade TEXT NOT NULL DEFAULT 'good',
+    response_time_ms INTEGER,
+    answer TEXT,
+    direction TEXT,
+    statement_id TEXT,
+    user_id INTEGER NOT NULL DEFAULT 1,
+    FOREIGN KEY (word_id) REFERENCES words(id),
+    FOREIGN KEY (study_session_id) REFERENCES study_sessions(id)
+);
+INSERT INTO word_review_items_old
+    (id, word_id, study_session_id, correct, created_at, grade, response_time_ms, answer, direction, statement_id, user_id)
+SELECT id, word_id, study_session_id, correct, created_at, grade, response_time_ms, answer, direction, statement_id, user_id
+FROM word_review_items;
+DELETE FROM sqlite_sequence WHERE name = 'word_review_items_old';
+INSERT INTO sqlite_sequence (name, seq)
+SELECT 'word_review_items_old', seq FROM sqlite_sequence WHERE name = 'word_review_items';
+DROP TABLE word_review_items;
+ALTER TABLE word_review_items_old RENAME TO word_review_items;
+CREATE UNIQUE INDEX idx_word_review_items_statement_id ON word_review_items(statement_id);
+CREATE INDEX idx_word_review_items_user ON word_review_items(user_id, word_id);
+
+CREATE TABLE study_sessions_old (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    group_id INTEGER NOT NULL,
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    study_activity_id INTEGER,
+    registration TEXT,
+    user_id INTEGER NOT NULL DEFAULT 1,
+    FOREIGN KEY (group_id) REFERENCES groups(id),
+    FOREIGN KEY (study_activity_id) REFERENCES study_activities(id)
+);
+INSERT INTO study_sessions_old (id, group_id, created_at, study_activity_id, registration, user_id)
+SELECT id, group_id, created_at, study_activity_id, registration, user_id FROM study_sessions;
+DELETE FROM sqlite_sequence WHERE name = 'study_sessions_old';
+INSERT INTO sqlite_sequence (name, seq)
+SELECT 'study_sessions_old', seq FROM sqlite_sequence WHERE name = 'study_sessions';
+DROP TABLE study_sessions;
+ALTER TABLE study_sessions_old RENAME TO study_sessions;
+CREATE UNIQUE INDEX idx_study_sessions_registration ON study_sessions(registration);
+CREATE INDEX idx_study_sessions_user ON study_sessions(user_id, created_at);
+
+CREATE TABLE words_groups_old (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    word_id INTEGER NOT NULL,
+    group_id INTEGER NOT NULL,
+    FOREIGN KEY (word_id) REFERENCES words(id),
+    FOREIGN KEY (group_id) REFERENCES groups(id)
+);
+INSERT INTO words_groups_old (id, word_id, group_id)
+SELECT id, word_id, group_id FROM words_groups;
+DELETE FROM sqlite_sequence WHERE name = 'words_groups_old';
+INSERT INTO sqlite_sequence (name, seq)
+SELECT 'words_groups_old', seq FROM sqlite_sequence WHERE name = 'words_groups';
+DROP TABLE words_groups;
+ALTER TABLE words_groups_old RENAME TO words_groups;
+CREATE UNIQUE INDEX idx_words_groups_word_group ON words_groups(word_id, group_id);
+
+DROP INDEX IF EXISTS idx_groups_name;
@@ -0,0 +1,156 @@
+-- Enforce the relationships between tables. The server now opens the database
+-- with foreign keys on, so rows that point at missing rows are removed first,
+-- groups sharing a name are merged into the oldest one, and every table with
+-- a foreign key is rebuilt to say what happens when the row it points at is
+-- deleted:
+--   - memberships, reviews, progress and API keys go with their word, group,
+--     session or user (CASCADE)
+--   - groups and study activities with study sessions cannot be deleted, so
+--     history stays attributable (RESTRICT)
+
+DELETE FROM study_sessions
+WHERE group_id NOT IN (SELECT id FROM groups)
+   OR user_id NOT IN (SELECT id FROM users);
+UPDATE study_sessions SET study_activity_id = NULL
+WHERE study_activity_id NOT IN (SELECT id FROM study_activities);
+DELETE FROM word_review_items
+WHERE word_id NOT IN (SELECT id FROM words)
+   OR study_session_id NOT IN (SELECT id FROM study_sessions)
+   OR user_id NOT IN (SELECT id FROM users);
+DELETE FROM words_groups
+WHERE word_id NOT IN (SELECT id FROM words)
+   OR group_id NOT IN (SELECT id FROM groups);
+DELETE FROM word_progress
+WHERE word_id NOT IN (SELECT id FROM words)
+   OR user_id NOT IN (SELECT id FROM users);
+DELETE FROM api_keys WHERE user_id NOT IN (SELECT id FROM users);
+
+-- Group names are unique
+CREATE TEMP TABLE group_merges AS
+SELECT g.id AS old_id, (SELECT MIN(id) FROM groups WHERE name = g.name) AS new_id
+FROM groups g
+WHERE g.id <> (SELECT MIN(id) FROM groups WHERE name = g.name);
+
+UPDATE OR IGNORE words_groups
+SET group_id = (SELECT new_id FROM group_merges WHERE old_id = group_id)
+WHERE group_id IN (SELECT old_id FROM group_merges);
+DELETE FROM words_groups WHERE group_id IN (SELECT old_id FROM group_merges);
+UPDATE study_sessions
+SET group_id = (SELECT new_id FROM group_merges WHERE old_id = group_id)
+WHERE group_id IN (SELECT old_id FROM group_merges);
+DELETE FROM groups WHERE id IN (SELECT old_id FROM group_merges);
+DROP TABLE group_merges;
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_groups_name ON groups(name);
+
+-- Rebuilt tables keep their AUTOINCREMENT position so ids are never reused
+
+CREATE TABLE words_groups_new (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    word_id INTEGER NOT NULL,
+    group_id INTEGER NOT NULL,
+    FOREIGN KEY (word_id) REFERENCES words(id) ON DELETE CASCADE,
+    FOREIGN KEY (group_id) REFERENCES groups(id) ON DELETE CASCADE
+);
+INSERT INTO words_groups_new (id, word_id, group_id)
+SELECT id, word_id, group_id FROM words_groups;
+DELETE FROM sqlite_sequence WHERE name = 'words_groups_new';
+INSERT INTO sqlite_sequence (name, seq)
+SELECT 'words_groups_new', seq FROM sqlite_sequence WHERE name = 'words_groups';
+DROP TABLE words_groups;
+ALTER TABLE words_groups_new RENAME TO words_groups;
+CREATE UNIQUE INDEX idx_words_groups_word_group ON words_groups(word_id, group_id);
+CREATE INDEX idx_words_groups_group ON words_groups(group_id);
+
+CREATE TABLE study_sessions_new (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    user_id INTEGER NOT NULL,
+    group_id INTEGER NOT NULL,
+    study_activity_id INTEGER,
+    registration TEXT,
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE,
+    FOREIGN KEY (group_id) REFERENCES groups(id) ON DELETE RESTRICT,
+    FOREIGN KEY (study_activity_id) REFERENCES study_activities(id) ON DELETE RESTRICT
+);
+INSERT INTO study_sessions_new (id, user_id, group_id, study_activity_id, registration, created_at)
+SELECT id, user_id, group_id, study_activity_id, registration, created_at FROM study_sessions;
+DELETE FROM sqlite_sequence WHERE name = 'study_sessions_new';
+INSERT INTO sqlite_sequence (name, seq)
+SELECT 'study_sessions_new', seq FROM sqlite_sequence WHERE name = 'study_sessions';
+DROP TABLE study_sessions;
+ALTER TABLE study_sessions_new RENAME TO study_sessions;
+CREATE UNIQUE INDEX idx_study_sessions_registration ON study_sessions(registration);
+CREATE INDEX idx_study_sessions_user ON study_sessions(user_id, created_at);
+CREATE INDEX idx_study_sessions_group ON study_sessions(group_id);
+CREATE INDEX idx_study_sessions_activity ON study_sessions(study_activity_id);
+
+CREATE TABLE word_review_items_new (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    user_id INTEGER NOT NULL,
+    word_id INTEGER NOT NULL,
+    study_session_id INTEGER NOT NULL,
+    correct BOOLEAN NOT NULL,
+    grade TEXT NOT NULL DEFAULT 'good',
+    response_time_ms INTEGER,
+    answer TEXT,
+    direction TEXT,
+    statement_id TEXT,
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE,
+    FOREIGN KEY (word_id) REFERENCES words(id) ON DELETE CASCADE,
+    FOREIGN KEY (study_session_id) REFERENCES study_sessions(id) ON DELETE CASCADE
+);
+INSERT INTO word_review_items_new
+    (id, user_id, word_id, study_session_id, correct, grade, response_time_ms, answer, direction, statement_id, created_at)
+SELECT id, user_id, word_id, study_session_id, correct, grade, response_time_ms, answer, direction, statement_id, created_at
+FROM word_review_items;
+DELETE FROM sqlite_sequence WHERE name = 'word_review_items_new';
+INSERT INTO sqlite_sequence (name, seq)
+SELECT 'word_review_items_new', seq FROM sqlite_sequence WHERE name = 'word_review_items';
+DROP TABLE word_review_items;
+ALTER TABLE word_review_items_new RENAME TO word_review_items;
+CREATE UNIQUE INDEX idx_word_review_items_statement_id ON word_review_items(statement_id);
+CREATE INDEX idx_word_review_items_user ON word_review_items(user_id, word_id);
+CREATE INDEX idx_word_review_items_word ON word_review_items(word_id);
+CREATE INDEX idx_word_review_items_session ON word_review_items(study_session_id);
+
+CREATE TABLE word_progress_new (
+    user_id INTEGER NOT NULL,
+    word_id INTEGER NOT NULL,
+    ease_factor REAL NOT NULL DEFAULT 2.5,
+    interval_days INTEGER NOT NULL DEFAULT 0,
+    repetitions INTEGER NOT NULL DEFAULT 0,
+    lapses INTEGER NOT NULL DEFAULT 0,
+    due_at DATETIME NOT NULL,
+    last_reviewed_at DATETIME,
+    PRIMARY KEY (user_id, word_id),
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE,
+    FOREIGN KEY (word_id) REFERENCES words(id) ON DELETE CASCADE
+);
+INSERT INTO word_progress_new
+    (user_id, word_id, ease_factor, interval_days, repetitions, lapses, due_at, last_reviewed_at)
+SELECT user_id, word_id, ease_factor, interval_days, repetitions, lapses, due_at, last_reviewed_at
+FROM word_progress;
+DROP TABLE word_progress;
+ALTER TABLE word_progress_new RENAME TO word_progress;
+CREATE INDEX idx_word_progress_due_at ON word_progress(user_id, due_at);
+CREATE INDEX idx_word_progress_word ON word_progress(word_id);
+
+CREATE TABLE api_keys_new (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    user_id INTEGER NOT NULL,
+    name TEXT NOT NULL,
+    key_hash TEXT NOT NULL UNIQUE,
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    last_used_at DATETIME,
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+INSERT INTO api_keys_new (id, user_id, name, key_hash, created_at, last_used_at)
+SELECT id, user_id, name, key_hash, created_at, last_used_at FROM api_keys;
+DELETE FROM sqlite_sequence WHERE name = 'api_keys_new';
+INSERT INTO sqlite_sequence (name, seq)
+SELECT 'api_keys_new', seq FROM sqlite_sequence WHERE name = 'api_keys';
+DROP TABLE api_keys;
+ALTER TABLE api_keys_new RENAME TO api_keys;
+CREATE INDEX idx_api_keys_user ON api_keys(user_id);
@@ -0,0 +1,15 @@
+-- Study sessions are active until they are ended, or abandoned by the server
+-- after going idle. Sessions recorded before this migration are taken to
+-- have been completed when their last review was made.
+
+ALTER TABLE study_sessions ADD COLUMN ended_at DATETIME;
+ALTER TABLE study_sessions ADD COLUMN status TEXT NOT NULL DEFAULT 'active'
+    CHECK (status IN ('active', 'completed', 'abandoned'));
+
+UPDATE study_sessions SET
+    status = 'completed',
+    ended_at = COALESCE(
+        (SELECT MAX(datetime(created_at)) FROM word_review_items WHERE study_session_id = study_sessions.id),
+        datetime(created_at));
+
+CREATE INDEX IF NOT EXISTS idx_study_sessions_status ON study_sessions(status);
@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS idx_word_review_items_user_created;
+DROP INDEX IF EXISTS idx_study_sessions_user_created;
@@ -0,0 +1,9 @@
+-- Cursor listings of study sessions and reviews are ordered by
+-- datetime(created_at) and id, since created_at holds times written in more
+-- than one format. Index that expression per user so a page is read straight
+-- from the index instead of sorting every row.
+
+CREATE INDEX IF NOT EXISTS idx_study_sessions_user_created
+    ON study_sessions(user_id, datetime(created_at), id);
+CREATE INDEX IF NOT EXISTS idx_word_review_items_user_created
+    ON word_review_items(user_id, datetime(created_at), id);
@@ -0,0 +1,4 @@
+-- When a password is changed or removed, session tokens issued before then
+-- stop working.
+
+ALTER TABLE users ADD COLUMN password_changed_at DATETIME;
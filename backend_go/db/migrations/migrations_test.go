@@ -0,0 +1,250 @@
+package migrations
+
+import (
+	"database/sql"
+	"fmt"
+	"io/fs"
+	"testing"
+	"testing/fstest"
+
+	_ "github.com/mattn/go-sqlite3"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func openDB(t *testing.T) *sql.DB {
+	// foreign keys on, as the server opens its database
+	db, err := sql.Open("sqlite3", ":memory:?_foreign_keys=on")
+	require.NoError(t, err)
+	db.SetMaxOpenConns(1)
+	t.Cleanup(func() { db.Close() })
+	return db
+}
+
+func TestEmbeddedMigrationsRoundTrip(t *testing.T) {
+	db := openDB(t)
+	m, err := New(db, FS)
+	require.NoError(t, err)
+
+	assert.ErrorIs(t, m.Check(), ErrPending)
+
+	applied, err := m.Up()
+	require.NoError(t, err)
+	assert.Len(t, applied, len(m.migrations))
+	require.NoError(t, m.Check())
+
+	applied, err = m.Up()
+	require.NoError(t, err)
+	assert.Empty(t, applied, "up is idempotent")
+
+	for _, migration := range m.migrations {
+		assert.NotEmpty(t, migration.Down, "%d_%s has a down file", migration.Version, migration.Name)
+	}
+
+	reverted, err := m.Rollback(len(m.migrations))
+	require.NoError(t, err)
+	assert.Len(t, reverted, len(m.migrations))
+
+	var tables int
+	require.NoError(t, db.QueryRow(`
+		SELECT COUNT(*) FROM sqlite_master
+		WHERE type = 'table' AND name NOT IN ('schema_migrations', 'sqlite_sequence')`).Scan(&tables))
+	assert.Zero(t, tables)
+
+	_, err = m.Up()
+	require.NoError(t, err)
+	require.NoError(t, m.Check())
+}
+
+func TestRollbackSteps(t *testing.T) {
+	db := openDB(t)
+	m, err := New(db, fstest.MapFS{
+		"0001_a.sql":      {Data: []byte("CREATE TABLE a (id INTEGER)")},
+		"0001_a.down.sql": {Data: []byte("DROP TABLE a")},
+		"0002_b.sql":      {Data: []byte("CREATE TABLE b (id INTEGER)")},
+		"0002_b.down.sql": {Data: []byte("DROP TABLE b")},
+	})
+	require.NoError(t, err)
+	_, err = m.Up()
+	require.NoError(t, err)
+
+	reverted, err := m.Rollback(1)
+	require.NoError(t, err)
+	require.Len(t, reverted, 1)
+	assert.Equal(t, 2, reverted[0].Version)
+
+	statuses, err := m.Status()
+	require.NoError(t, err)
+	require.Len(t, statuses, 2)
+	assert.True(t, statuses[0].Applied)
+	assert.NotNil(t, statuses[0].AppliedAt)
+	assert.False(t, statuses[1].Applied)
+	assert.ErrorIs(t, m.Check(), ErrPending)
+}
+
+func TestFailedMigrationIsNotRecorded(t *testing.T) {
+	db := openDB(t)
+	m, err := New(db, fstest.MapFS{
+		"0001_a.sql": {Data: []byte("CREATE TABLE a (id INTEGER); INSERT INTO missing VALUES (1)")},
+	})
+	require.NoError(t, err)
+
+	_, err = m.Up()
+	require.Error(t, err)
+
+	var tables int
+	require.NoError(t, db.QueryRow("SELECT COUNT(*) FROM sqlite_master WHERE name = 'a'").Scan(&tables))
+	assert.Zero(t, tables, "partial migration was rolled back")
+
+	statuses, err := m.Status()
+	require.NoError(t, err)
+	assert.False(t, statuses[0].Applied)
+}
+
+func TestModifiedAndUnknownMigrations(t *testing.T) {
+	db := openDB(t)
+	m, err := New(db, fstest.MapFS{
+		"0001_a.sql": {Data: []byte("CREATE TABLE a (id INTEGER)")},
+		"0002_b.sql": {Data: []byte("CREATE TABLE b (id INTEGER)")},
+	})
+	require.NoError(t, err)
+	_, err = m.Up()
+	require.NoError(t, err)
+
+	edited, err := New(db, fstest.MapFS{
+		"0001_a.sql": {Data: []byte("CREATE TABLE a (id INTEGER, name TEXT)")},
+		"0002_b.sql": {Data: []byte("CREATE TABLE b (id INTEGER)")},
+	})
+	require.NoError(t, err)
+	assert.ErrorIs(t, edited.Check(), ErrChecksumMismatch)
+	_, err = edited.Up()
+	assert.ErrorIs(t, err, ErrChecksumMismatch)
+	statuses, err := edited.Status()
+	require.NoError(t, err)
+	assert.True(t, statuses[0].Modified)
+
+	older, err := New(db, fstest.MapFS{
+		"0001_a.sql": {Data: []byte("CREATE TABLE a (id INTEGER)")},
+	})
+	require.NoError(t, err)
+	assert.ErrorIs(t, older.Check(), ErrUnknownVersion)
+
+	_, err = older.Rollback(1)
+	assert.ErrorIs(t, err, ErrUnknownVersion)
+}
+
+func TestRollbackWithoutDownFile(t *testing.T) {
+	db := openDB(t)
+	m, err := New(db, fstest.MapFS{
+		"0001_a.sql": {Data: []byte("CREATE TABLE a (id INTEGER)")},
+	})
+	require.NoError(t, err)
+	_, err = m.Up()
+	require.NoError(t, err)
+
+	_, err = m.Rollback(1)
+	assert.ErrorIs(t, err, ErrNoDown)
+}
+
+func TestMigrationBreakingForeignKeys(t *testing.T) {
+	db := openDB(t)
+	m, err := New(db, fstest.MapFS{
+		"0001_a.sql": {Data: []byte(`
+			CREATE TABLE parent (id INTEGER PRIMARY KEY);
+			CREATE TABLE child (parent_id INTEGER REFERENCES parent(id));
+			INSERT INTO parent VALUES (1);
+			INSERT INTO child VALUES (1)`)},
+		"0002_b.sql": {Data: []byte("DELETE FROM parent")},
+	})
+	require.NoError(t, err)
+
+	applied, err := m.Up()
+	assert.ErrorIs(t, err, ErrForeignKeys)
+	assert.Len(t, applied, 1)
+
+	var parents int
+	require.NoError(t, db.QueryRow("SELECT COUNT(*) FROM parent").Scan(&parents))
+	assert.Equal(t, 1, parents, "the migration was rolled back")
+
+	var foreignKeys bool
+	require.NoError(t, db.QueryRow("PRAGMA foreign_keys").Scan(&foreignKeys))
+	assert.True(t, foreignKeys, "foreign keys are switched back on")
+}
+
+// migrationsUpTo is the embedded migrations up to and including version
+func migrationsUpTo(t *testing.T, version int) fstest.MapFS {
+	migrations, err := Load(FS)
+	require.NoError(t, err)
+	fsys := fstest.MapFS{}
+	for _, m := range migrations {
+		if m.Version > version {
+			continue
+		}
+		for _, suffix := range []string{".sql", ".down.sql"} {
+			name := fmt.Sprintf("%04d_%s%s", m.Version, m.Name, suffix)
+			data, err := fs.ReadFile(FS, name)
+			require.NoError(t, err)
+			fsys[name] = &fstest.MapFile{Data: data}
+		}
+	}
+	return fsys
+}
+
+func TestReferentialIntegrityMigration(t *testing.T) {
+	db := openDB(t)
+	before, err := New(db, migrationsUpTo(t, 8))
+	require.NoError(t, err)
+	_, err = before.Up()
+	require.NoError(t, err)
+
+	// Data written while foreign keys were not enforced
+	_, err = db.Exec(`
+		PRAGMA foreign_keys = OFF;
+		INSERT INTO words (id, japanese, romaji, english) VALUES (1, '猫', 'neko', 'cat'), (2, '犬', 'inu', 'dog');
+		INSERT INTO groups (id, name) VALUES (1, 'Animals'), (2, 'Animals'), (3, 'Colors');
+		INSERT INTO words_groups (word_id, group_id) VALUES (1, 1), (1, 2), (2, 2), (99, 3);
+		INSERT INTO study_activities (id, name, launch_url) VALUES (1, 'Cards', 'http://localhost');
+		INSERT INTO study_sessions (id, group_id, study_activity_id) VALUES (1, 2, 1), (2, 42, 1), (3, 3, 7);
+		INSERT INTO word_review_items (word_id, study_session_id, correct) VALUES (1, 1, 1), (1, 2, 1), (99, 1, 0);
+		PRAGMA foreign_keys = ON`)
+	require.NoError(t, err)
+
+	m, err := New(db, FS)
+	require.NoError(t, err)
+	_, err = m.Up()
+	require.NoError(t, err)
+
+	count := func(query string) int {
+		var n int
+		require.NoError(t, db.QueryRow(query).Scan(&n))
+		return n
+	}
+	assert.Equal(t, 2, count("SELECT COUNT(*) FROM groups"), "groups with the same name are merged")
+	assert.Equal(t, 2, count("SELECT COUNT(*) FROM words_groups WHERE group_id = 1"))
+	assert.Equal(t, 0, count("SELECT COUNT(*) FROM words_groups WHERE word_id = 99"))
+	assert.Equal(t, 1, count("SELECT group_id FROM study_sessions WHERE id = 1"))
+	assert.Equal(t, 2, count("SELECT COUNT(*) FROM study_sessions"), "sessions of missing groups are removed")
+	assert.Equal(t, 0, count("SELECT COUNT(*) FROM study_sessions WHERE study_activity_id = 7"))
+	assert.Equal(t, 1, count("SELECT COUNT(*) FROM word_review_items"))
+	assert.Equal(t, 0, count("SELECT COUNT(*) FROM pragma_foreign_key_check"))
+
+	_, err = db.Exec("INSERT INTO groups (name) VALUES ('Animals')")
+	assert.ErrorContains(t, err, "UNIQUE constraint failed: groups.name")
+	_, err = db.Exec("INSERT INTO study_sessions (user_id, group_id) VALUES (1, 99)")
+	assert.ErrorContains(t, err, "FOREIGN KEY constraint failed")
+	_, err = db.Exec("INSERT INTO study_sessions (group_id) VALUES (1)")
+	assert.ErrorContains(t, err, "NOT NULL constraint failed: study_sessions.user_id", "sessions name their user")
+	_, err = db.Exec("INSERT INTO word_review_items (word_id, study_session_id, correct) VALUES (1, 1, 1)")
+	assert.ErrorContains(t, err, "NOT NULL constraint failed: word_review_items.user_id", "reviews name their user")
+	_, err = db.Exec("DELETE FROM groups WHERE id = 1")
+	assert.ErrorContains(t, err, "FOREIGN KEY constraint failed", "groups with sessions are kept")
+
+	_, err = db.Exec("DELETE FROM words WHERE id = 1")
+	require.NoError(t, err)
+	assert.Equal(t, 0, count("SELECT COUNT(*) FROM word_review_items"), "reviews go with their word")
+	assert.Equal(t, 1, count("SELECT COUNT(*) FROM words_groups"))
+
+	var seq int
+	require.NoError(t, db.QueryRow("SELECT seq FROM sqlite_sequence WHERE name = 'study_sessions'").Scan(&seq))
+	assert.Equal(t, 3, seq, "session ids are not reused")
+}
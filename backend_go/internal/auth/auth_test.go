@@ -0,0 +1,62 @@
+package auth
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTokens(t *testing.T) {
+	now := time.Date(2025, 1, 4, 9, 0, 0, 0, time.UTC)
+	tokens := NewTokens([]byte("secret"), time.Hour)
+	tokens.now = func() time.Time { return now }
+
+	token, expires, err := tokens.Issue(42)
+	require.NoError(t, err)
+	assert.Equal(t, now.Add(time.Hour), expires)
+
+	claims, err := tokens.Verify(token)
+	require.NoError(t, err)
+	id, err := claims.UserID()
+	require.NoError(t, err)
+	assert.Equal(t, int64(42), id)
+
+	parts := strings.Split(token, ".")
+	forged, _, err := NewTokens([]byte("other"), time.Hour).Issue(1)
+	require.NoError(t, err)
+	for name, bad := range map[string]string{
+		"empty":               "",
+		"two parts":           parts[0] + "." + parts[1],
+		"bad signature":       parts[0] + "." + parts[1] + ".AAAA",
+		"other secret":        forged,
+		"swapped payload":     parts[0] + "." + strings.Split(forged, ".")[1] + "." + parts[2],
+		"unsigned (alg none)": "eyJhbGciOiJub25lIn0." + parts[1] + ".",
+	} {
+		_, err := tokens.Verify(bad)
+		assert.ErrorIs(t, err, ErrInvalidToken, name)
+	}
+
+	now = now.Add(time.Hour)
+	_, err = tokens.Verify(token)
+	assert.ErrorIs(t, err, ErrExpiredToken)
+}
+
+func TestAPIKeys(t *testing.T) {
+	key, hash := NewAPIKey()
+	other, _ := NewAPIKey()
+	assert.True(t, strings.HasPrefix(key, "lp_"))
+	assert.NotEqual(t, key, other)
+	assert.Equal(t, hash, HashAPIKey(key))
+	assert.NotContains(t, hash, key)
+}
+
+func TestPasswords(t *testing.T) {
+	hash, err := HashPassword("correct horse")
+	require.NoError(t, err)
+	assert.True(t, CheckPassword(hash, "correct horse"))
+	assert.False(t, CheckPassword(hash, "wrong horse"))
+	assert.False(t, CheckPassword("", ""), "users without a password cannot sign in")
+}
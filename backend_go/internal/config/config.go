@@ -0,0 +1,468 @@
+// Package config loads the server's settings. Each setting can come from a
+// YAML or TOML file, an environment variable or a command-line flag; later
+// sources win, so flags override the environment, which overrides the file,
+// which overrides the defaults.
+//
+// A setting named read_timeout is read_timeout in the file,
+// LANG_PORTAL_READ_TIMEOUT in the environment and --read-timeout on the
+// command line.
+package config
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pelletier/go-toml/v2"
+	"gopkg.in/yaml.v3"
+)
+
+// EnvPrefix starts the name of every environment variable the server reads
+const EnvPrefix = "LANG_PORTAL_"
+
+// ConfigEnv names the config file when --config is not given
+const ConfigEnv = EnvPrefix + "CONFIG"
+
+type Config struct {
+	// DBPath is the SQLite database file
+	DBPath string
+	// Addr is the host:port the server listens on
+	Addr string
+	// GinMode is debug, release or test
+	GinMode string
+	// LogLevel is debug, info, warn or error
+	LogLevel string
+	// CORSOrigins are the browser origins allowed to call the API; "*"
+	// allows any
+	CORSOrigins []string
+	// PerPage is the page size of paginated listings and MaxPerPage the
+	// largest page size a request may ask for
+	PerPage    int
+	MaxPerPage int
+	// Timeouts of the HTTP server; zero means none
+	ReadHeaderTimeout time.Duration
+	ReadTimeout       time.Duration
+	WriteTimeout      time.Duration
+	IdleTimeout       time.Duration
+	// ShutdownTimeout is how long requests in flight may take to finish
+	// once the server is asked to stop
+	ShutdownTimeout time.Duration
+	// QueryTimeout bounds each database operation; zero means only the
+	// request's own deadline applies
+	QueryTimeout time.Duration
+	// TLSCertFile and TLSKeyFile are PEM files to serve HTTPS with; when
+	// both are empty the server speaks plain HTTP
+	TLSCertFile string
+	TLSKeyFile  string
+	// TokenSecret signs session tokens; when empty a random secret is used
+	// and tokens do not survive a restart
+	TokenSecret string
+	TokenTTL    time.Duration
+	// SessionIdleTimeout is how long a study session may go without a
+	// review before it is abandoned; zero means never
+	SessionIdleTimeout time.Duration
+	// CrossGroupReviews lets study sessions review words outside their
+	// group, for drills that mix groups
+	CrossGroupReviews bool
+	// MaxImportMB is the largest vocabulary file upload, in megabytes
+	MaxImportMB int
+	// PublicURL is the URL clients reach the server at, which xAPI activity
+	// IRIs are built on. When empty it is localhost on the port of Addr.
+	PublicURL string
+}
+
+// Default returns the settings used when nothing else is given
+func Default() *Config {
+	return &Config{
+		DBPath:             "words.db",
+		Addr:               ":8080",
+		GinMode:            "debug",
+		LogLevel:           "info",
+		PerPage:            100,
+		MaxPerPage:         500,
+		ReadHeaderTimeout:  5 * time.Second,
+		ReadTimeout:        15 * time.Second,
+		WriteTimeout:       60 * time.Second,
+		IdleTimeout:        2 * time.Minute,
+		ShutdownTimeout:    30 * time.Second,
+		QueryTimeout:       10 * time.Second,
+		TokenTTL:           12 * time.Hour,
+		SessionIdleTimeout: 30 * time.Minute,
+		MaxImportMB:        32,
+	}
+}
+
+// setting is one configurable value, parsed from its string form
+type setting struct {
+	name  string
+	usage string
+	set   func(c *Config, value string) error
+}
+
+var settings = []setting{
+	{"db_path", "SQLite database file", func(c *Config, v string) error {
+		c.DBPath = v
+		return nil
+	}},
+	{"addr", "address to listen on, host:port", func(c *Config, v string) error {
+		c.Addr = v
+		return nil
+	}},
+	{"gin_mode", "debug, release or test", func(c *Config, v string) error {
+		c.GinMode = v
+		return nil
+	}},
+	{"log_level", "debug, info, warn or error", func(c *Config, v string) error {
+		c.LogLevel = strings.ToLower(v)
+		return nil
+	}},
+	{"cors_origins", `comma-separated browser origins allowed to call the API, or "*"`, func(c *Config, v string) error {
+		c.CORSOrigins = splitList(v)
+		return nil
+	}},
+	{"per_page", "page size of paginated listings", intSetter(func(c *Config) *int { return &c.PerPage })},
+	{"max_per_page", "largest page size a request may ask for", intSetter(func(c *Config) *int { return &c.MaxPerPage })},
+	{"read_header_timeout", "time allowed to read request headers, 0 for none", durationSetter(func(c *Config) *time.Duration { return &c.ReadHeaderTimeout })},
+	{"read_timeout", "time allowed to read a request, 0 for none", durationSetter(func(c *Config) *time.Duration { return &c.ReadTimeout })},
+	{"write_timeout", "time allowed to write a response, 0 for none", durationSetter(func(c *Config) *time.Duration { return &c.WriteTimeout })},
+	{"idle_timeout", "how long idle keep-alive connections stay open, 0 for none", durationSetter(func(c *Config) *time.Duration { return &c.IdleTimeout })},
+	{"shutdown_timeout", "time requests in flight may take to finish when stopping", durationSetter(func(c *Config) *time.Duration { return &c.ShutdownTimeout })},
+	{"query_timeout", "time a database operation may take, 0 for none", durationSetter(func(c *Config) *time.Duration { return &c.QueryTimeout })},
+	{"tls_cert_file", "PEM certificate to serve HTTPS with", func(c *Config, v string) error {
+		c.TLSCertFile = v
+		return nil
+	}},
+	{"tls_key_file", "PEM private key of tls_cert_file", func(c *Config, v string) error {
+		c.TLSKeyFile = v
+		return nil
+	}},
+	{"token_secret", "key session tokens are signed with", func(c *Config, v string) error {
+		c.TokenSecret = v
+		return nil
+	}},
+	{"token_ttl", "how long session tokens stay valid", durationSetter(func(c *Config) *time.Duration { return &c.TokenTTL })},
+	{"cross_group_reviews", "let study sessions review words outside their group", boolSetter(func(c *Config) *bool { return &c.CrossGroupReviews })},
+	{"session_idle_timeout", "time a study session may go without a review before it is abandoned, 0 for never", durationSetter(func(c *Config) *time.Duration { return &c.SessionIdleTimeout })},
+	{"max_import_mb", "largest vocabulary file upload, in megabytes", intSetter(func(c *Config) *int { return &c.MaxImportMB })},
+	{"public_url", "URL clients reach the server at, e.g. https://portal.example.com; xAPI activity IRIs are built on it", func(c *Config, v string) error {
+		c.PublicURL = strings.TrimRight(strings.TrimSpace(v), "/")
+		return nil
+	}},
+}
+
+func intSetter(field func(c *Config) *int) func(c *Config, v string) error {
+	return func(c *Config, v string) error {
+		n, err := strconv.Atoi(strings.TrimSpace(v))
+		if err != nil {
+			return fmt.Errorf("%q is not a whole number", v)
+		}
+		*field(c) = n
+		return nil
+	}
+}
+
+func boolSetter(field func(c *Config) *bool) func(c *Config, v string) error {
+	return func(c *Config, v string) error {
+		b, err := strconv.ParseBool(strings.TrimSpace(v))
+		if err != nil {
+			return fmt.Errorf("%q is not true or false", v)
+		}
+		*field(c) = b
+		return nil
+	}
+}
+
+func durationSetter(field func(c *Config) *time.Duration) func(c *Config, v string) error {
+	return func(c *Config, v string) error {
+		v = strings.TrimSpace(v)
+		if v == "0" {
+			*field(c) = 0
+			return nil
+		}
+		d, err := time.ParseDuration(v)
+		if err != nil {
+			return fmt.Errorf("%q is not a duration such as 30s or 5m", v)
+		}
+		*field(c) = d
+		return nil
+	}
+}
+
+func splitList(v string) []string {
+	var list []string
+	for _, item := range strings.Split(v, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			list = append(list, item)
+		}
+	}
+	return list
+}
+
+func envName(name string) string {
+	return EnvPrefix + strings.ToUpper(name)
+}
+
+func flagName(name string) string {
+	return strings.ReplaceAll(name, "_", "-")
+}
+
+// Load reads the settings from the config file, the environment and the
+// command-line flags in args, and returns them with the arguments left after
+// the flags. The config file is the --config flag, or else the file named by
+// LANG_PORTAL_CONFIG; without either only the environment and flags are used.
+func Load(args []string, getenv func(string) string) (*Config, []string, error) {
+	flags := flag.NewFlagSet("server", flag.ContinueOnError)
+	flags.SetOutput(io.Discard)
+	configPath := flags.String("config", getenv(ConfigEnv), "YAML or TOML config file")
+
+	type flagValue struct {
+		setting setting
+		value   string
+	}
+	var flagValues []flagValue
+	for _, s := range settings {
+		s := s
+		flags.Func(flagName(s.name), s.usage, func(v string) error {
+			flagValues = append(flagValues, flagValue{s, v})
+			return nil
+		})
+	}
+	if err := flags.Parse(args); err != nil {
+		return nil, nil, fmt.Errorf("config: %w", err)
+	}
+
+	cfg := Default()
+	var errs []error
+	if *configPath != "" {
+		if err := cfg.loadFile(*configPath); err != nil {
+			return nil, nil, err
+		}
+	}
+	for _, s := range settings {
+		if v := getenv(envName(s.name)); v != "" {
+			if err := s.set(cfg, v); err != nil {
+				errs = append(errs, fmt.Errorf("config: %s: %w", envName(s.name), err))
+			}
+		}
+	}
+	for _, fv := range flagValues {
+		if err := fv.setting.set(cfg, fv.value); err != nil {
+			errs = append(errs, fmt.Errorf("config: --%s: %w", flagName(fv.setting.name), err))
+		}
+	}
+	if len(errs) > 0 {
+		return nil, nil, errors.Join(errs...)
+	}
+
+	if err := cfg.Validate(); err != nil {
+		return nil, nil, err
+	}
+	return cfg, flags.Args(), nil
+}
+
+// Usage writes the flags and environment variables Load understands
+func Usage(w io.Writer) {
+	fmt.Fprintf(w, "  --config file\n\tYAML or TOML config file (env %s)\n", ConfigEnv)
+	for _, s := range settings {
+		fmt.Fprintf(w, "  --%s value\n\t%s (env %s, file key %s)\n", flagName(s.name), s.usage, envName(s.name), s.name)
+	}
+}
+
+// loadFile applies the settings in a YAML (.yaml, .yml) or TOML (.toml) file
+func (c *Config) loadFile(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("config: %w", err)
+	}
+
+	values := map[string]interface{}{}
+	switch ext := strings.ToLower(filepath.Ext(path)); ext {
+	case ".yaml", ".yml":
+		err = yaml.Unmarshal(data, &values)
+	case ".toml":
+		err = toml.Unmarshal(data, &values)
+	default:
+		return fmt.Errorf("config: %s: unsupported file type %q (use .yaml, .yml or .toml)", path, ext)
+	}
+	if err != nil {
+		return fmt.Errorf("config: %s: %w", path, err)
+	}
+
+	known := map[string]setting{}
+	for _, s := range settings {
+		known[s.name] = s
+	}
+	names := make([]string, 0, len(values))
+	for name := range values {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var errs []error
+	for _, name := range names {
+		s, ok := known[name]
+		if !ok {
+			errs = append(errs, fmt.Errorf("config: %s: unknown setting %q", path, name))
+			continue
+		}
+		value, err := fileValue(values[name])
+		if err == nil {
+			err = s.set(c, value)
+		}
+		if err != nil {
+			errs = append(errs, fmt.Errorf("config: %s: %s: %w", path, name, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// fileValue turns a value from a config file into the string form settings
+// are parsed from; lists become comma-separated
+func fileValue(v interface{}) (string, error) {
+	switch v := v.(type) {
+	case string:
+		return v, nil
+	case int, int64, uint64, float64, bool:
+		return fmt.Sprint(v), nil
+	case []interface{}:
+		items := make([]string, len(v))
+		for i, item := range v {
+			s, ok := item.(string)
+			if !ok {
+				return "", errors.New("list items must be strings")
+			}
+			items[i] = s
+		}
+		return strings.Join(items, ","), nil
+	}
+	return "", fmt.Errorf("unsupported value %v", v)
+}
+
+// Validate checks that every setting makes sense, reporting all problems at
+// once
+func (c *Config) Validate() error {
+	var errs []error
+	invalid := func(name, format string, args ...interface{}) {
+		errs = append(errs, fmt.Errorf("config: %s: "+format, append([]interface{}{name}, args...)...))
+	}
+
+	if strings.TrimSpace(c.DBPath) == "" {
+		invalid("db_path", "is required")
+	}
+	if _, port, err := net.SplitHostPort(c.Addr); err != nil {
+		invalid("addr", "%q is not host:port", c.Addr)
+	} else if n, err := strconv.Atoi(port); err != nil || n < 0 || n > 65535 {
+		invalid("addr", "%q has an invalid port", c.Addr)
+	}
+	switch c.GinMode {
+	case "debug", "release", "test":
+	default:
+		invalid("gin_mode", "%q must be debug, release or test", c.GinMode)
+	}
+	if _, err := c.SlogLevel(); err != nil {
+		invalid("log_level", "%q must be debug, info, warn or error", c.LogLevel)
+	}
+	for _, origin := range c.CORSOrigins {
+		if origin == "*" {
+			continue
+		}
+		u, err := url.Parse(origin)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" || (u.Path != "" && u.Path != "/") {
+			invalid("cors_origins", "%q must be \"*\" or an origin such as https://example.com", origin)
+		}
+	}
+	if c.PerPage < 1 {
+		invalid("per_page", "must be at least 1")
+	}
+	if c.MaxPerPage < c.PerPage {
+		invalid("max_per_page", "must be at least per_page (%d)", c.PerPage)
+	}
+	for name, d := range map[string]time.Duration{
+		"read_header_timeout":  c.ReadHeaderTimeout,
+		"read_timeout":         c.ReadTimeout,
+		"write_timeout":        c.WriteTimeout,
+		"idle_timeout":         c.IdleTimeout,
+		"query_timeout":        c.QueryTimeout,
+		"session_idle_timeout": c.SessionIdleTimeout,
+	} {
+		if d < 0 {
+			invalid(name, "must not be negative")
+		}
+	}
+	if c.ShutdownTimeout <= 0 {
+		invalid("shutdown_timeout", "must be positive")
+	}
+	switch {
+	case c.TLSCertFile == "" && c.TLSKeyFile != "":
+		invalid("tls_cert_file", "is required with tls_key_file")
+	case c.TLSCertFile != "" && c.TLSKeyFile == "":
+		invalid("tls_key_file", "is required with tls_cert_file")
+	}
+	for name, path := range map[string]string{"tls_cert_file": c.TLSCertFile, "tls_key_file": c.TLSKeyFile} {
+		if path == "" {
+			continue
+		}
+		if _, err := os.Stat(path); err != nil {
+			invalid(name, "%v", err)
+		}
+	}
+	if c.TokenTTL <= 0 {
+		invalid("token_ttl", "must be positive")
+	}
+	if c.MaxImportMB < 1 {
+		invalid("max_import_mb", "must be at least 1")
+	}
+	if c.PublicURL != "" {
+		u, err := url.Parse(c.PublicURL)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" || u.RawQuery != "" || u.Fragment != "" {
+			invalid("public_url", "%q must be an http or https URL such as https://portal.example.com", c.PublicURL)
+		}
+	}
+
+	sort.Slice(errs, func(i, j int) bool { return errs[i].Error() < errs[j].Error() })
+	return errors.Join(errs...)
+}
+
+// APIBase is the public URL of the API, the base of xAPI activity IRIs
+func (c *Config) APIBase() string {
+	public := c.PublicURL
+	if public == "" {
+		scheme := "http"
+		if c.TLS() {
+			scheme = "https"
+		}
+		_, port, _ := net.SplitHostPort(c.Addr)
+		public = scheme + "://" + net.JoinHostPort("localhost", port)
+	}
+	return public + "/api"
+}
+
+// TLS reports whether the server should serve HTTPS
+func (c *Config) TLS() bool {
+	return c.TLSCertFile != ""
+}
+
+// SlogLevel returns the log level as a slog level
+func (c *Config) SlogLevel() (slog.Level, error) {
+	switch c.LogLevel {
+	case "debug":
+		return slog.LevelDebug, nil
+	case "info":
+		return slog.LevelInfo, nil
+	case "warn":
+		return slog.LevelWarn, nil
+	case "error":
+		return slog.LevelError, nil
+	}
+	return 0, fmt.Errorf("unknown log level %q", c.LogLevel)
+}
@@ -0,0 +1,167 @@
+package config
+
+import (
+	"flag"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func env(vars map[string]string) func(string) string {
+	return func(name string) string { return vars[name] }
+}
+
+func writeFile(t *testing.T, name, content string) string {
+	path := filepath.Join(t.TempDir(), name)
+	require.NoError(t, os.WriteFile(path, []byte(content), 0o644))
+	return path
+}
+
+func TestLoadDefaults(t *testing.T) {
+	cfg, args, err := Load([]string{"migrate", "status"}, env(nil))
+	require.NoError(t, err)
+	assert.Equal(t, Default(), cfg)
+	assert.Equal(t, []string{"migrate", "status"}, args)
+	assert.Equal(t, "http://localhost:8080/api", cfg.APIBase())
+}
+
+func TestLoadPrecedence(t *testing.T) {
+	path := writeFile(t, "portal.yaml", `
+db_path: file.db
+addr: ":9000"
+log_level: warn
+cors_origins:
+  - https://apps.example.com
+  - http://localhost:5173
+per_page: 50
+read_timeout: 5s
+`)
+	vars := map[string]string{
+		ConfigEnv:                 path,
+		"LANG_PORTAL_ADDR":        ":9100",
+		"LANG_PORTAL_PER_PAGE":    "25",
+		"LANG_PORTAL_TOKEN_TTL":   "1h",
+		"LANG_PORTAL_UNRELATED_X": "ignored",
+	}
+
+	cfg, args, err := Load([]string{"--addr", "127.0.0.1:9200", "--gin-mode=release", "seed", "--dry-run"}, env(vars))
+	require.NoError(t, err)
+	assert.Equal(t, "file.db", cfg.DBPath, "from the file")
+	assert.Equal(t, "127.0.0.1:9200", cfg.Addr, "flags beat the environment")
+	assert.Equal(t, 25, cfg.PerPage, "the environment beats the file")
+	assert.Equal(t, "warn", cfg.LogLevel)
+	assert.Equal(t, "release", cfg.GinMode)
+	assert.Equal(t, []string{"https://apps.example.com", "http://localhost:5173"}, cfg.CORSOrigins)
+	assert.Equal(t, 5*time.Second, cfg.ReadTimeout)
+	assert.Equal(t, time.Hour, cfg.TokenTTL)
+	assert.Equal(t, 60*time.Second, cfg.WriteTimeout, "unset values keep their default")
+	assert.Equal(t, 32, cfg.MaxImportMB)
+	assert.Equal(t, []string{"seed", "--dry-run"}, args)
+}
+
+func TestLoadTOML(t *testing.T) {
+	path := writeFile(t, "portal.toml", `
+db_path = "toml.db"
+cors_origins = ["*"]
+max_per_page = 1000
+idle_timeout = "0"
+query_timeout = "250ms"
+session_idle_timeout = "0"
+cross_group_reviews = true
+public_url = "https://portal.example.com/"
+`)
+	cfg, _, err := Load([]string{"--config", path}, env(nil))
+	require.NoError(t, err)
+	assert.Equal(t, "toml.db", cfg.DBPath)
+	assert.Equal(t, []string{"*"}, cfg.CORSOrigins)
+	assert.Equal(t, 1000, cfg.MaxPerPage)
+	assert.Equal(t, time.Duration(0), cfg.IdleTimeout)
+	assert.Equal(t, 250*time.Millisecond, cfg.QueryTimeout)
+	assert.Equal(t, time.Duration(0), cfg.SessionIdleTimeout)
+	assert.True(t, cfg.CrossGroupReviews)
+	assert.Equal(t, "https://portal.example.com/api", cfg.APIBase())
+}
+
+func TestLoadErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		args    []string
+		vars    map[string]string
+		file    string
+		content string
+		wantErr []string
+	}{
+		{
+			name:    "unknown flag",
+			args:    []string{"--port", "80"},
+			wantErr: []string{"flag provided but not defined: -port"},
+		},
+		{
+			name:    "bad values",
+			args:    []string{"--addr", "8080", "--per-page", "ten"},
+			vars:    map[string]string{"LANG_PORTAL_READ_TIMEOUT": "soon"},
+			wantErr: []string{`LANG_PORTAL_READ_TIMEOUT: "soon" is not a duration`, `--per-page: "ten" is not a whole number`},
+		},
+		{
+			name:    "bad switch",
+			vars:    map[string]string{"LANG_PORTAL_CROSS_GROUP_REVIEWS": "sometimes"},
+			wantErr: []string{`LANG_PORTAL_CROSS_GROUP_REVIEWS: "sometimes" is not true or false`},
+		},
+		{
+			name: "invalid settings",
+			args: []string{"--addr", "8080", "--gin-mode", "prod", "--log-level", "loud",
+				"--cors-origins", "apps.example.com", "--per-page", "600", "--token-ttl", "0",
+				"--session-idle-timeout", "-5m", "--public-url", "portal.example.com", "--max-import-mb", "0"},
+			wantErr: []string{
+				`addr: "8080" is not host:port`,
+				`gin_mode: "prod" must be debug, release or test`,
+				`log_level: "loud" must be debug, info, warn or error`,
+				`cors_origins: "apps.example.com" must be "*" or an origin`,
+				"max_per_page: must be at least per_page (600)",
+				"token_ttl: must be positive",
+				"session_idle_timeout: must not be negative",
+				`public_url: "portal.example.com" must be an http or https URL`,
+				"max_import_mb: must be at least 1",
+			},
+		},
+		{
+			name:    "unknown file setting",
+			file:    "portal.yml",
+			content: "database: words.db\nper_page: [1]\n",
+			wantErr: []string{`unknown setting "database"`, "per_page: list items must be strings"},
+		},
+		{
+			name:    "unsupported file type",
+			file:    "portal.json",
+			content: "{}",
+			wantErr: []string{`unsupported file type ".json"`},
+		},
+		{
+			name:    "missing file",
+			args:    []string{"--config", "does-not-exist.yaml"},
+			wantErr: []string{"does-not-exist.yaml"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			args := tt.args
+			if tt.file != "" {
+				args = append([]string{"--config", writeFile(t, tt.file, tt.content)}, args...)
+			}
+			_, _, err := Load(args, env(tt.vars))
+			require.Error(t, err)
+			for _, want := range tt.wantErr {
+				assert.Contains(t, err.Error(), want)
+			}
+		})
+	}
+}
+
+func TestLoadHelp(t *testing.T) {
+	_, _, err := Load([]string{"-h"}, env(nil))
+	assert.ErrorIs(t, err, flag.ErrHelp)
+}
@@ -0,0 +1,244 @@
+package exporter
+
+import (
+	"archive/zip"
+	"crypto/sha1"
+	"database/sql"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/gen-ai-bootcamp-2025/backend_go/internal/models"
+	_ "github.com/mattn/go-sqlite3"
+)
+
+// Anki decks use the legacy collection.anki2 schema (version 11), which every
+// Anki release can import. Notes use a "Japanese" note type whose field
+// names match the importer's default mapping, so an exported deck can be
+// imported again without a mapping.
+
+const ankiSchema = `
+	CREATE TABLE col (
+		id integer primary key, crt integer not null, mod integer not null,
+		scm integer not null, ver integer not null, dty integer not null,
+		usn integer not null, ls integer not null, conf text not null,
+		models text not null, decks text not null, dconf text not null, tags text not null
+	);
+	CREATE TABLE notes (
+		id integer primary key, guid text not null, mid integer not null,
+		mod integer not null, usn integer not null, tags text not null,
+		flds text not null, sfld integer not null, csum integer not null,
+		flags integer not null, data text not null
+	);
+	CREATE TABLE cards (
+		id integer primary key, nid integer not null, did integer not null,
+		ord integer not null, mod integer not null, usn integer not null,
+		type integer not null, queue integer not null, due integer not null,
+		ivl integer not null, factor integer not null, reps integer not null,
+		lapses integer not null, left integer not null, odue integer not null,
+		odid integer not null, flags integer not null, data text not null
+	);
+	CREATE TABLE revlog (
+		id integer primary key, cid integer not null, usn integer not null,
+		ease integer not null, ivl integer not null, lastIvl integer not null,
+		factor integer not null, time integer not null, type integer not null
+	);
+	CREATE TABLE graves (usn integer not null, oid integer not null, type integer not null);
+	CREATE INDEX ix_notes_usn ON notes (usn);
+	CREATE INDEX ix_cards_usn ON cards (usn);
+	CREATE INDEX ix_revlog_usn ON revlog (usn);
+	CREATE INDEX ix_cards_nid ON cards (nid);
+	CREATE INDEX ix_cards_sched ON cards (did, queue, due);
+	CREATE INDEX ix_revlog_cid ON revlog (cid);
+	CREATE INDEX ix_notes_csum ON notes (csum);`
+
+var ankiFields = []string{"Japanese", "Romaji", "English", "Parts"}
+
+type ankiWriter struct {
+	w       io.Writer
+	dir     string
+	db      *sql.DB
+	tx      *sql.Tx
+	modelID int64
+	deckID  int64
+	now     time.Time
+	count   int64
+}
+
+func newAnkiWriter(w io.Writer, deckName string) (*ankiWriter, error) {
+	dir, err := os.MkdirTemp("", "export-*")
+	if err != nil {
+		return nil, err
+	}
+	a := &ankiWriter{w: w, dir: dir, now: time.Now()}
+	a.modelID = a.now.UnixMilli()
+	a.deckID = a.modelID + 1
+
+	if err := a.init(deckName); err != nil {
+		a.cleanup()
+		return nil, err
+	}
+	return a, nil
+}
+
+func (a *ankiWriter) init(deckName string) error {
+	db, err := sql.Open("sqlite3", filepath.Join(a.dir, "collection.anki2"))
+	if err != nil {
+		return err
+	}
+	a.db = db
+	if _, err := db.Exec(ankiSchema); err != nil {
+		return err
+	}
+
+	noteTypes, decks, deckOptions, conf, err := a.collectionJSON(deckName)
+	if err != nil {
+		return err
+	}
+	_, err = db.Exec(`
+		INSERT INTO col VALUES (1, ?, ?, ?, 11, 0, 0, 0, ?, ?, ?, ?, '{}')`,
+		a.now.Unix(), a.now.UnixMilli(), a.now.UnixMilli(), conf, noteTypes, decks, deckOptions)
+	if err != nil {
+		return err
+	}
+
+	a.tx, err = db.Begin()
+	return err
+}
+
+// collectionJSON builds the JSON columns of col describing the note type and deck
+func (a *ankiWriter) collectionJSON(deckName string) (noteTypes, decks, deckOptions, conf string, err error) {
+	fields := make([]map[string]interface{}, len(ankiFields))
+	for i, name := range ankiFields {
+		fields[i] = map[string]interface{}{
+			"name": name, "ord": i, "sticky": false, "rtl": false,
+			"font": "Arial", "size": 20, "media": []interface{}{},
+		}
+	}
+	model := map[string]interface{}{
+		"id": a.modelID, "name": "Japanese", "type": 0, "mod": a.now.Unix(), "usn": -1,
+		"sortf": 0, "did": a.deckID, "flds": fields, "tags": []interface{}{}, "vers": []interface{}{},
+		"tmpls": []map[string]interface{}{{
+			"name": "Recognition", "ord": 0, "did": nil, "bqfmt": "", "bafmt": "",
+			"qfmt": "{{Japanese}}",
+			"afmt": "{{FrontSide}}<hr id=answer>{{English}}<br>{{Romaji}}",
+		}},
+		"css":       ".card { font-family: arial; font-size: 20px; text-align: center; }",
+		"latexPre":  "\\documentclass[12pt]{article}\n\\begin{document}\n",
+		"latexPost": "\\end{document}",
+		"req":       []interface{}{[]interface{}{0, "any", []int{0}}},
+	}
+	deck := func(id int64, name string) map[string]interface{} {
+		return map[string]interface{}{
+			"id": id, "name": name, "mod": a.now.Unix(), "usn": -1, "desc": "", "dyn": 0,
+			"conf": 1, "collapsed": false, "extendNew": 10, "extendRev": 50,
+			"newToday": []int{0, 0}, "revToday": []int{0, 0}, "lrnToday": []int{0, 0}, "timeToday": []int{0, 0},
+		}
+	}
+	options := map[string]interface{}{
+		"id": 1, "name": "Default", "mod": 0, "usn": 0, "maxTaken": 60, "autoplay": true,
+		"timer": 0, "replayq": true, "dyn": false,
+		"new":   map[string]interface{}{"delays": []int{1, 10}, "ints": []int{1, 4, 7}, "initialFactor": 2500, "order": 1, "perDay": 20, "bury": true},
+		"rev":   map[string]interface{}{"perDay": 100, "ease4": 1.3, "fuzz": 0.05, "maxIvl": 36500, "ivlFct": 1, "bury": true},
+		"lapse": map[string]interface{}{"delays": []int{10}, "mult": 0, "minInt": 1, "leechFails": 8, "leechAction": 0},
+	}
+	collection := map[string]interface{}{
+		"nextPos": 1, "estTimes": true, "activeDecks": []int64{1}, "sortType": "noteFld",
+		"timeLim": 0, "sortBackwards": false, "addToCur": true, "curDeck": 1, "newSpread": 0,
+		"dueCounts": true, "curModel": a.modelID, "collapseTime": 1200,
+	}
+
+	encode := func(v interface{}) string {
+		if err != nil {
+			return ""
+		}
+		var data []byte
+		data, err = json.Marshal(v)
+		return string(data)
+	}
+	noteTypes = encode(map[string]interface{}{jsonKey(a.modelID): model})
+	decks = encode(map[string]interface{}{"1": deck(1, "Default"), jsonKey(a.deckID): deck(a.deckID, deckName)})
+	deckOptions = encode(map[string]interface{}{"1": options})
+	conf = encode(collection)
+	return noteTypes, decks, deckOptions, conf, err
+}
+
+func jsonKey(id int64) string {
+	data, _ := json.Marshal(id)
+	return string(data)
+}
+
+func (a *ankiWriter) Write(word *models.Word) error {
+	a.count++
+	noteID := a.modelID*1000 + a.count
+	fields := []string{word.Japanese, word.Romaji, word.English, word.Parts.String}
+	for i, f := range fields {
+		fields[i] = strings.ReplaceAll(f, "\x1f", " ")
+	}
+
+	sum := sha1.Sum([]byte(word.Japanese))
+	csum := int64(binary.BigEndian.Uint32(sum[:4]))
+	guid := sha1.Sum([]byte(word.Japanese + "\x1f" + word.English))
+
+	_, err := a.tx.Exec(`
+		INSERT INTO notes VALUES (?, ?, ?, ?, -1, '', ?, ?, ?, 0, '')`,
+		noteID, hex.EncodeToString(guid[:5]), a.modelID, a.now.Unix(),
+		strings.Join(fields, "\x1f"), word.Japanese, csum)
+	if err != nil {
+		return err
+	}
+	_, err = a.tx.Exec(`
+		INSERT INTO cards VALUES (?, ?, ?, 0, ?, -1, 0, 0, ?, 0, 0, 0, 0, 0, 0, 0, 0, '')`,
+		noteID, noteID, a.deckID, a.now.Unix(), a.count)
+	return err
+}
+
+// Close writes the finished package to the output
+func (a *ankiWriter) Close() error {
+	defer a.cleanup()
+
+	if err := a.tx.Commit(); err != nil {
+		return err
+	}
+	if err := a.db.Close(); err != nil {
+		return err
+	}
+
+	archive := zip.NewWriter(a.w)
+	f, err := archive.Create("collection.anki2")
+	if err != nil {
+		return err
+	}
+	collection, err := os.Open(filepath.Join(a.dir, "collection.anki2"))
+	if err != nil {
+		return err
+	}
+	defer collection.Close()
+	if _, err := io.Copy(f, collection); err != nil {
+		return err
+	}
+
+	media, err := archive.Create("media")
+	if err != nil {
+		return err
+	}
+	if _, err := io.WriteString(media, "{}"); err != nil {
+		return err
+	}
+	return archive.Close()
+}
+
+func (a *ankiWriter) cleanup() {
+	if a.tx != nil {
+		a.tx.Rollback()
+	}
+	if a.db != nil {
+		a.db.Close()
+	}
+	os.RemoveAll(a.dir)
+}
@@ -0,0 +1,253 @@
+// Package exporter writes vocabulary and study history to files.
+//
+// Writers receive rows one at a time and write them straight through, so an
+// export never holds a whole group or history in memory. The one exception is
+// apkg, which is a zipped SQLite database: its rows go to a temporary file
+// that is zipped into the output on Close.
+//
+// The json word format is the seed file format read by the importer, and the
+// csv word format has the japanese, romaji, english and parts columns the
+// importer looks for, so both can be imported again as-is.
+package exporter
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"time"
+
+	"github.com/gen-ai-bootcamp-2025/backend_go/internal/models"
+)
+
+// WordFormats lists the formats words can be exported in
+var WordFormats = []string{"csv", "json", "apkg"}
+
+// ReviewFormats lists the formats study history can be exported in
+var ReviewFormats = []string{"csv", "json"}
+
+// IsFormat reports whether format is one of formats
+func IsFormat(formats []string, format string) bool {
+	for _, f := range formats {
+		if f == format {
+			return true
+		}
+	}
+	return false
+}
+
+// ContentType returns the MIME type of an export format
+func ContentType(format string) string {
+	switch format {
+	case "csv":
+		return "text/csv; charset=utf-8"
+	case "json":
+		return "application/json; charset=utf-8"
+	default:
+		return "application/octet-stream"
+	}
+}
+
+// WordWriter writes words in one format. Close must be called to finish the
+// output.
+type WordWriter interface {
+	Write(word *models.Word) error
+	Close() error
+}
+
+// NewWordWriter returns a writer for a group's words in format
+func NewWordWriter(format string, w io.Writer, groupName string) (WordWriter, error) {
+	switch format {
+	case "csv":
+		return &csvWordWriter{w: csv.NewWriter(w)}, nil
+	case "json":
+		return &jsonWordWriter{w: w, groupName: groupName}, nil
+	case "apkg":
+		return newAnkiWriter(w, groupName)
+	default:
+		return nil, fmt.Errorf("unsupported format %q", format)
+	}
+}
+
+var wordCSVHeader = []string{"japanese", "romaji", "english", "parts", "correct_count", "wrong_count", "last_reviewed_at"}
+
+type csvWordWriter struct {
+	w             *csv.Writer
+	headerWritten bool
+}
+
+func (c *csvWordWriter) writeHeader() error {
+	if c.headerWritten {
+		return nil
+	}
+	c.headerWritten = true
+	return c.w.Write(wordCSVHeader)
+}
+
+func (c *csvWordWriter) Write(word *models.Word) error {
+	if err := c.writeHeader(); err != nil {
+		return err
+	}
+	lastReviewedAt := ""
+	if word.LastReviewedAt != nil {
+		lastReviewedAt = word.LastReviewedAt.UTC().Format(time.RFC3339)
+	}
+	return c.w.Write([]string{
+		word.Japanese, word.Romaji, word.English, word.Parts.String,
+		strconv.Itoa(word.CorrectCount), strconv.Itoa(word.WrongCount), lastReviewedAt,
+	})
+}
+
+func (c *csvWordWriter) Close() error {
+	if err := c.writeHeader(); err != nil {
+		return err
+	}
+	c.w.Flush()
+	return c.w.Error()
+}
+
+// jsonWord is a word as it appears in a seed file
+type jsonWord struct {
+	Japanese string          `json:"japanese"`
+	Romaji   string          `json:"romaji"`
+	English  string          `json:"english"`
+	Parts    json.RawMessage `json:"parts,omitempty"`
+}
+
+type jsonWordWriter struct {
+	w         io.Writer
+	groupName string
+	count     int
+}
+
+func (j *jsonWordWriter) open() error {
+	if j.count > 0 {
+		_, err := io.WriteString(j.w, ",\n")
+		return err
+	}
+	group, err := json.Marshal(j.groupName)
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(j.w, "{\n  \"group\": {\"name\": %s},\n  \"words\": [\n", group)
+	return err
+}
+
+func (j *jsonWordWriter) Write(word *models.Word) error {
+	if err := j.open(); err != nil {
+		return err
+	}
+	out := jsonWord{Japanese: word.Japanese, Romaji: word.Romaji, English: word.English}
+	if word.Parts.Valid {
+		out.Parts = json.RawMessage(word.Parts.String)
+	}
+	data, err := json.Marshal(out)
+	if err != nil {
+		return err
+	}
+	j.count++
+	_, err = fmt.Fprintf(j.w, "    %s", data)
+	return err
+}
+
+func (j *jsonWordWriter) Close() error {
+	if j.count == 0 {
+		if err := j.open(); err != nil {
+			return err
+		}
+	}
+	_, err := io.WriteString(j.w, "\n  ]\n}\n")
+	return err
+}
+
+// ReviewWriter writes reviews in one format. Close must be called to finish
+// the output.
+type ReviewWriter interface {
+	Write(review *models.ReviewExport) error
+	Close() error
+}
+
+// NewReviewWriter returns a writer for study history in format
+func NewReviewWriter(format string, w io.Writer) (ReviewWriter, error) {
+	switch format {
+	case "csv":
+		return &csvReviewWriter{w: csv.NewWriter(w)}, nil
+	case "json":
+		return &jsonReviewWriter{w: w}, nil
+	default:
+		return nil, fmt.Errorf("unsupported format %q", format)
+	}
+}
+
+var reviewCSVHeader = []string{
+	"id", "user_id", "study_session_id", "group_id", "group_name", "study_activity_id", "activity_name",
+	"word_id", "japanese", "english", "grade", "correct", "response_time_ms", "answer", "direction", "created_at",
+}
+
+type csvReviewWriter struct {
+	w             *csv.Writer
+	headerWritten bool
+}
+
+func (c *csvReviewWriter) writeHeader() error {
+	if c.headerWritten {
+		return nil
+	}
+	c.headerWritten = true
+	return c.w.Write(reviewCSVHeader)
+}
+
+func (c *csvReviewWriter) Write(r *models.ReviewExport) error {
+	if err := c.writeHeader(); err != nil {
+		return err
+	}
+	responseTime := ""
+	if r.ResponseTimeMs != nil {
+		responseTime = strconv.FormatInt(*r.ResponseTimeMs, 10)
+	}
+	return c.w.Write([]string{
+		strconv.FormatInt(r.ID, 10), strconv.FormatInt(r.UserID, 10), strconv.FormatInt(r.StudySessionID, 10),
+		strconv.FormatInt(r.GroupID, 10), r.GroupName,
+		strconv.FormatInt(r.StudyActivityID, 10), r.ActivityName,
+		strconv.FormatInt(r.WordID, 10), r.Japanese, r.English,
+		string(r.Grade), strconv.FormatBool(r.Correct), responseTime, r.Answer, string(r.Direction),
+		r.CreatedAt.UTC().Format(time.RFC3339),
+	})
+}
+
+func (c *csvReviewWriter) Close() error {
+	if err := c.writeHeader(); err != nil {
+		return err
+	}
+	c.w.Flush()
+	return c.w.Error()
+}
+
+type jsonReviewWriter struct {
+	w     io.Writer
+	count int
+}
+
+func (j *jsonReviewWriter) Write(r *models.ReviewExport) error {
+	sep := ",\n  "
+	if j.count == 0 {
+		sep = "[\n  "
+	}
+	data, err := json.Marshal(r)
+	if err != nil {
+		return err
+	}
+	j.count++
+	_, err = fmt.Fprintf(j.w, "%s%s", sep, data)
+	return err
+}
+
+func (j *jsonReviewWriter) Close() error {
+	if j.count == 0 {
+		_, err := io.WriteString(j.w, "[]\n")
+		return err
+	}
+	_, err := io.WriteString(j.w, "\n]\n")
+	return err
+}
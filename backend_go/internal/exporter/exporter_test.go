@@ -0,0 +1,125 @@
+package exporter
+
+import (
+	"bytes"
+	"database/sql"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gen-ai-bootcamp-2025/backend_go/internal/importer"
+	"github.com/gen-ai-bootcamp-2025/backend_go/internal/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func testWords() []*models.Word {
+	return []*models.Word{
+		{Japanese: "猫", Romaji: "neko", English: "cat",
+			Parts: sql.NullString{String: `{"type":"noun"}`, Valid: true}},
+		{Japanese: "犬", Romaji: "inu", English: `dog, "hound"`},
+	}
+}
+
+func exportWords(t *testing.T, format string) []byte {
+	var buf bytes.Buffer
+	w, err := NewWordWriter(format, &buf, "Animals")
+	require.NoError(t, err)
+	for _, word := range testWords() {
+		require.NoError(t, w.Write(word))
+	}
+	require.NoError(t, w.Close())
+	return buf.Bytes()
+}
+
+// assertRoundTrip checks that an export imports back into the same words
+func assertRoundTrip(t *testing.T, set *importer.WordSet) {
+	require.Empty(t, set.Errors)
+	require.Len(t, set.Words, 2)
+	for i, want := range testWords() {
+		got := set.Words[i]
+		assert.Equal(t, want.Japanese, got.Japanese)
+		assert.Equal(t, want.Romaji, got.Romaji)
+		assert.Equal(t, want.English, got.English)
+		assert.Equal(t, want.Parts.Valid, got.Parts.Valid)
+	}
+	assert.Equal(t, "noun", set.Words[0].PartsMap["type"])
+}
+
+func TestJSONExportRoundTrips(t *testing.T) {
+	set, err := importer.ParseJSON("export.json", exportWords(t, "json"))
+	require.NoError(t, err)
+	assert.Equal(t, "Animals", set.Group)
+	assertRoundTrip(t, set)
+}
+
+func TestCSVExportRoundTrips(t *testing.T) {
+	data := exportWords(t, "csv")
+	set, err := importer.ParseCSV(bytes.NewReader(data), ',', nil)
+	require.NoError(t, err)
+	assertRoundTrip(t, set)
+}
+
+func TestAnkiExportRoundTrips(t *testing.T) {
+	data := exportWords(t, "apkg")
+	set, err := importer.ParseAnki(bytes.NewReader(data), int64(len(data)), nil)
+	require.NoError(t, err)
+	assertRoundTrip(t, set)
+}
+
+func TestEmptyExports(t *testing.T) {
+	for _, format := range WordFormats {
+		var buf bytes.Buffer
+		w, err := NewWordWriter(format, &buf, "Empty")
+		require.NoError(t, err)
+		require.NoError(t, w.Close(), format)
+		assert.NotEmpty(t, buf.Bytes(), format)
+	}
+
+	var buf bytes.Buffer
+	w, err := NewReviewWriter("json", &buf)
+	require.NoError(t, err)
+	require.NoError(t, w.Close())
+	assert.JSONEq(t, "[]", buf.String())
+}
+
+func TestReviewExport(t *testing.T) {
+	responseTime := int64(1500)
+	reviews := []*models.ReviewExport{
+		{ID: 1, UserID: 1, StudySessionID: 2, GroupID: 3, GroupName: "Animals", WordID: 4, Japanese: "猫", English: "cat",
+			Grade: models.GradeGood, Correct: true, ResponseTimeMs: &responseTime,
+			CreatedAt: time.Date(2025, 1, 4, 9, 0, 0, 0, time.UTC)},
+		{ID: 2, StudySessionID: 2, GroupID: 3, GroupName: "Animals", WordID: 5, Japanese: "犬", English: "dog",
+			Grade: models.GradeAgain, CreatedAt: time.Date(2025, 1, 4, 9, 1, 0, 0, time.UTC)},
+	}
+
+	var csvOut, jsonOut bytes.Buffer
+	csvWriter, err := NewReviewWriter("csv", &csvOut)
+	require.NoError(t, err)
+	jsonWriter, err := NewReviewWriter("json", &jsonOut)
+	require.NoError(t, err)
+	for _, r := range reviews {
+		require.NoError(t, csvWriter.Write(r))
+		require.NoError(t, jsonWriter.Write(r))
+	}
+	require.NoError(t, csvWriter.Close())
+	require.NoError(t, jsonWriter.Close())
+
+	lines := strings.Split(strings.TrimSpace(csvOut.String()), "\n")
+	require.Len(t, lines, 3)
+	assert.Equal(t, strings.Join(reviewCSVHeader, ","), lines[0])
+	assert.Equal(t, "1,1,2,3,Animals,0,,4,猫,cat,good,true,1500,,,2025-01-04T09:00:00Z", lines[1])
+
+	var decoded []models.ReviewExport
+	require.NoError(t, json.Unmarshal(jsonOut.Bytes(), &decoded))
+	require.Len(t, decoded, 2)
+	assert.Equal(t, models.GradeAgain, decoded[1].Grade)
+}
+
+func TestUnsupportedFormat(t *testing.T) {
+	_, err := NewWordWriter("xlsx", &bytes.Buffer{}, "")
+	assert.Error(t, err)
+	_, err = NewReviewWriter("apkg", &bytes.Buffer{})
+	assert.Error(t, err)
+}
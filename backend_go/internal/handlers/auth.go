@@ -0,0 +1,113 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gen-ai-bootcamp-2025/backend_go/internal/auth"
+	"github.com/gen-ai-bootcamp-2025/backend_go/internal/models"
+	"github.com/gen-ai-bootcamp-2025/backend_go/internal/service"
+	"github.com/gin-gonic/gin"
+)
+
+// APIKeyHeader carries the API key of a learning app. People send the token
+// from Login as "Authorization: Bearer <token>" instead.
+const APIKeyHeader = "X-API-Key"
+
+var errAuthenticationRequired = models.NewError(models.ErrUnauthorized, "authentication_required", "authentication required")
+
+// Authenticate is middleware that stores the user signed in with an API key
+// or a session token in the request context, where the service reads it.
+// Requests without valid credentials are rejected.
+func (h *Handler) Authenticate(c *gin.Context) {
+	user, err := h.authenticate(c)
+	if errors.Is(err, service.ErrUserNotFound) || errors.Is(err, auth.ErrInvalidToken) || errors.Is(err, auth.ErrExpiredToken) {
+		// a token for a user that has since been deleted is as good as none
+		err = models.NewError(models.ErrUnauthorized, "invalid_token", err.Error())
+	}
+	if err != nil {
+		if errors.Is(err, models.ErrUnauthorized) {
+			c.Header("WWW-Authenticate", `Bearer realm="lang-portal"`)
+		}
+		respondError(c, err)
+		return
+	}
+
+	c.Request = c.Request.WithContext(service.WithUser(c.Request.Context(), user))
+	c.Next()
+}
+
+func (h *Handler) authenticate(c *gin.Context) (*models.User, error) {
+	if key := c.GetHeader(APIKeyHeader); key != "" {
+		return h.svc.AuthenticateAPIKey(c.Request.Context(), key)
+	}
+
+	scheme, token, _ := strings.Cut(c.GetHeader("Authorization"), " ")
+	if !strings.EqualFold(scheme, "Bearer") || token == "" {
+		return nil, errAuthenticationRequired
+	}
+	claims, err := h.tokens.Verify(strings.TrimSpace(token))
+	if err != nil {
+		return nil, err
+	}
+	return h.svc.AuthenticateToken(c.Request.Context(), claims)
+}
+
+// Require is middleware that only lets through users whose role allows what
+// role may do. It must run after Authenticate.
+func Require(role models.Role) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		user := service.UserFromContext(c.Request.Context())
+		if user == nil {
+			respondError(c, service.ErrNoUser)
+			return
+		}
+		if !user.Role.Allows(role) {
+			respondError(c, models.NewError(models.ErrForbidden, "role_required", "requires the "+string(role)+" role"))
+			return
+		}
+		c.Next()
+	}
+}
+
+// Login exchanges a name and password for a session token
+func (h *Handler) Login(c *gin.Context) {
+	var req struct {
+		Name     string `json:"name" binding:"required"`
+		Password string `json:"password" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondError(c, invalidBody(err))
+		return
+	}
+
+	user, err := h.svc.Login(c.Request.Context(), req.Name, req.Password)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	token, expires, err := h.tokens.Issue(user.ID)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"token":      token,
+		"token_type": "Bearer",
+		"expires_at": expires.UTC().Format(time.RFC3339),
+		"user":       user,
+	})
+}
+
+// GetCurrentUser returns the signed-in user
+func (h *Handler) GetCurrentUser(c *gin.Context) {
+	user := service.UserFromContext(c.Request.Context())
+	if user == nil {
+		respondError(c, service.ErrNoUser)
+		return
+	}
+	c.JSON(http.StatusOK, user)
+}
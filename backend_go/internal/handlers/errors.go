@@ -0,0 +1,106 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/gen-ai-bootcamp-2025/backend_go/internal/models"
+	"github.com/gin-gonic/gin"
+)
+
+// ErrorResponse is the body of every error response. Code is stable and meant
+// for programs, Message is meant for people, and Fields lists the invalid
+// fields of a request that failed validation:
+//
+//	{"code": "word_not_found", "message": "word not found"}
+//	{"code": "validation_failed", "message": "validation failed: japanese: is required",
+//	 "fields": [{"field": "japanese", "message": "is required"}]}
+type ErrorResponse struct {
+	Code    string                  `json:"code"`
+	Message string                  `json:"message"`
+	Fields  models.ValidationErrors `json:"fields,omitempty"`
+}
+
+// errorKinds are the status and fallback code of each kind of error
+var errorKinds = []struct {
+	kind   error
+	status int
+	code   string
+}{
+	{models.ErrNotFound, http.StatusNotFound, "not_found"},
+	{models.ErrConflict, http.StatusConflict, "conflict"},
+	{models.ErrValidation, http.StatusBadRequest, "validation_failed"},
+	{models.ErrUnauthorized, http.StatusUnauthorized, "unauthorized"},
+	{models.ErrForbidden, http.StatusForbidden, "forbidden"},
+}
+
+// Errors is middleware that answers a request whose handler recorded an
+// error with respondError. It must run before the handlers it covers.
+func Errors() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Next()
+
+		last := c.Errors.Last()
+		if last == nil || c.Writer.Written() {
+			return
+		}
+		status, body := errorResponse(last.Err)
+		c.JSON(status, body)
+	}
+}
+
+// NoRoute answers requests for paths the API does not serve
+func NoRoute(c *gin.Context) {
+	respondError(c, models.NewError(models.ErrNotFound, "no_route", "no such endpoint"))
+}
+
+// respondError records err for Errors to answer the request with, and stops
+// the handlers after this one
+func respondError(c *gin.Context, err error) {
+	c.Error(err)
+	c.Abort()
+}
+
+// errorResponse maps an error onto the status and body it is answered with.
+// Errors of no known kind are answered with 500 and a generic message, so
+// database details do not reach clients; Logger logs them in full.
+func errorResponse(err error) (int, ErrorResponse) {
+	if errors.Is(err, context.DeadlineExceeded) || errors.Is(err, context.Canceled) {
+		// the query timed out, or the client went away and nobody reads this
+		return http.StatusServiceUnavailable, ErrorResponse{Code: "timeout", Message: "request timed out"}
+	}
+	var tooLarge *http.MaxBytesError
+	if errors.As(err, &tooLarge) {
+		return http.StatusRequestEntityTooLarge, ErrorResponse{
+			Code:    "body_too_large",
+			Message: fmt.Sprintf("request body is larger than %d bytes", tooLarge.Limit),
+		}
+	}
+
+	for _, k := range errorKinds {
+		if !errors.Is(err, k.kind) {
+			continue
+		}
+		body := ErrorResponse{Code: k.code, Message: err.Error()}
+		var typed *models.Error
+		if errors.As(err, &typed) {
+			body.Code = typed.Code
+		}
+		errors.As(err, &body.Fields)
+		return k.status, body
+	}
+	return http.StatusInternalServerError, ErrorResponse{Code: "internal", Message: "internal server error"}
+}
+
+// invalidField is the error for a single request field, path parameter or
+// query parameter that is not valid
+func invalidField(field, message string) error {
+	return models.ValidationErrors{{Field: field, Message: message}}
+}
+
+// invalidBody is the error for a request body that could not be decoded
+func invalidBody(err error) error {
+	return models.NewError(models.ErrValidation, "invalid_body", err.Error())
+}
@@ -1,30 +1,50 @@
 package handlers
 
 import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
 	"net/http"
 	"strconv"
+	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/gen-ai-bootcamp-2025/backend_go/internal/auth"
+	"github.com/gen-ai-bootcamp-2025/backend_go/internal/exporter"
+	"github.com/gen-ai-bootcamp-2025/backend_go/internal/importer"
+	"github.com/gen-ai-bootcamp-2025/backend_go/internal/models"
 	"github.com/gen-ai-bootcamp-2025/backend_go/internal/service"
 )
 
+// DefaultMaxImportSize is used until SetMaxImportSize is called
+const DefaultMaxImportSize = 32 << 20
+
 type Handler struct {
-	svc *service.Service
+	svc           *service.Service
+	tokens        *auth.Tokens
+	maxImportSize int64
+}
+
+func NewHandler(svc *service.Service, tokens *auth.Tokens) *Handler {
+	return &Handler{svc: svc, tokens: tokens, maxImportSize: DefaultMaxImportSize}
 }
 
-func NewHandler(svc *service.Service) *Handler {
-	return &Handler{svc: svc}
+// SetMaxImportSize sets the largest request body, in bytes, ImportWords
+// reads; larger uploads are refused with 413
+func (h *Handler) SetMaxImportSize(n int64) {
+	h.maxImportSize = n
 }
 
 // GetLastStudySession returns the most recent study session
 func (h *Handler) GetLastStudySession(c *gin.Context) {
-	session, err := h.svc.GetLastStudySession()
+	session, err := h.svc.GetLastStudySession(c.Request.Context())
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		respondError(c, err)
 		return
 	}
 	if session == nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "no study sessions found"})
+		respondError(c, service.ErrStudySessionNotFound)
 		return
 	}
 	c.JSON(http.StatusOK, session)
@@ -32,9 +52,9 @@ func (h *Handler) GetLastStudySession(c *gin.Context) {
 
 // GetStudyProgress returns study progress statistics
 func (h *Handler) GetStudyProgress(c *gin.Context) {
-	progress, err := h.svc.GetStudyProgress()
+	progress, err := h.svc.GetStudyProgress(c.Request.Context())
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		respondError(c, err)
 		return
 	}
 	c.JSON(http.StatusOK, progress)
@@ -42,28 +62,41 @@ func (h *Handler) GetStudyProgress(c *gin.Context) {
 
 // GetQuickStats returns quick overview statistics
 func (h *Handler) GetQuickStats(c *gin.Context) {
-	stats, err := h.svc.GetQuickStats()
+	stats, err := h.svc.GetQuickStats(c.Request.Context())
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		respondError(c, err)
 		return
 	}
 	c.JSON(http.StatusOK, stats)
 }
 
+// GetStudyActivities returns a paginated list of study activities
+func (h *Handler) GetStudyActivities(c *gin.Context) {
+	page, err := parsePage(c)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+	response, err := h.svc.GetStudyActivities(c.Request.Context(), page)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, response)
+}
+
 // GetStudyActivity returns a specific study activity
 func (h *Handler) GetStudyActivity(c *gin.Context) {
 	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+		respondError(c, invalidField("id", "must be an integer"))
 		return
 	}
 
-	// TODO: Implement getting study activity
-	activity := gin.H{
-		"id":           id,
-		"name":         "Vocabulary Quiz",
-		"thumbnail_url": "https://example.com/thumbnail.jpg",
-		"description":  "Practice your vocabulary with flashcards",
+	activity, err := h.svc.GetStudyActivity(c.Request.Context(), id)
+	if err != nil {
+		respondError(c, err)
+		return
 	}
 	c.JSON(http.StatusOK, activity)
 }
@@ -72,33 +105,115 @@ func (h *Handler) GetStudyActivity(c *gin.Context) {
 func (h *Handler) GetStudyActivitySessions(c *gin.Context) {
 	activityID, err := strconv.ParseInt(c.Param("id"), 10, 64)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid activity id"})
+		respondError(c, invalidField("id", "must be an integer"))
 		return
 	}
 
-	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
-	response, err := h.svc.GetStudySessionsByActivity(activityID, page)
+	page, err := parsePage(c)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+	response, err := h.svc.GetStudySessionsByActivity(c.Request.Context(), activityID, page)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		respondError(c, err)
 		return
 	}
 	c.JSON(http.StatusOK, response)
 }
 
-// CreateStudyActivity creates a new study activity
+// studyActivityRequest is the body accepted when creating or updating a study activity
+type studyActivityRequest struct {
+	Name         string `json:"name" binding:"required"`
+	Description  string `json:"description"`
+	ThumbnailURL string `json:"thumbnail_url"`
+	LaunchURL    string `json:"launch_url" binding:"required"`
+	Enabled      *bool  `json:"enabled"`
+}
+
+func (r *studyActivityRequest) toModel(id int64) *models.StudyActivity {
+	enabled := true
+	if r.Enabled != nil {
+		enabled = *r.Enabled
+	}
+	return &models.StudyActivity{
+		ID:           id,
+		Name:         r.Name,
+		Description:  r.Description,
+		ThumbnailURL: r.ThumbnailURL,
+		LaunchURL:    r.LaunchURL,
+		Enabled:      enabled,
+	}
+}
+
+// CreateStudyActivity adds a new study activity to the catalog
 func (h *Handler) CreateStudyActivity(c *gin.Context) {
+	var req studyActivityRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondError(c, invalidBody(err))
+		return
+	}
+
+	activity, err := h.svc.CreateStudyActivity(c.Request.Context(), req.toModel(0))
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+	c.JSON(http.StatusCreated, activity)
+}
+
+// UpdateStudyActivity replaces the fields of an existing study activity
+func (h *Handler) UpdateStudyActivity(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		respondError(c, invalidField("id", "must be an integer"))
+		return
+	}
+
+	var req studyActivityRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondError(c, invalidBody(err))
+		return
+	}
+
+	activity, err := h.svc.UpdateStudyActivity(c.Request.Context(), req.toModel(id))
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, activity)
+}
+
+// DeleteStudyActivity removes a study activity from the catalog
+func (h *Handler) DeleteStudyActivity(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		respondError(c, invalidField("id", "must be an integer"))
+		return
+	}
+
+	err = h.svc.DeleteStudyActivity(c.Request.Context(), id)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+// CreateStudySession starts a new study session for a group using a study activity
+func (h *Handler) CreateStudySession(c *gin.Context) {
 	var req struct {
 		GroupID         int64 `json:"group_id" binding:"required"`
 		StudyActivityID int64 `json:"study_activity_id" binding:"required"`
 	}
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		respondError(c, invalidBody(err))
 		return
 	}
 
-	session, err := h.svc.CreateStudySession(req.GroupID, req.StudyActivityID)
+	session, err := h.svc.CreateStudySession(c.Request.Context(), req.GroupID, req.StudyActivityID)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		respondError(c, err)
 		return
 	}
 
@@ -108,12 +223,43 @@ func (h *Handler) CreateStudyActivity(c *gin.Context) {
 	})
 }
 
-// GetWords returns a paginated list of words
+// GetWords returns a filtered, sorted and paginated list of words
 func (h *Handler) GetWords(c *gin.Context) {
-	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
-	response, err := h.svc.GetWords(page)
+	filter, err := parseWordFilter(c)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+	if v := c.Query("group_id"); v != "" {
+		if filter.GroupID, err = strconv.ParseInt(v, 10, 64); err != nil {
+			respondError(c, invalidField("group_id", "must be an integer"))
+			return
+		}
+	}
+
+	page, err := parsePage(c)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+	response, err := h.svc.GetWords(c.Request.Context(), filter, page)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		respondError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, response)
+}
+
+// SearchWords returns words matching the q query parameter, best matches first
+func (h *Handler) SearchWords(c *gin.Context) {
+	page, err := parsePage(c)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+	response, err := h.svc.SearchWords(c.Request.Context(), c.Query("q"), page)
+	if err != nil {
+		respondError(c, err)
 		return
 	}
 	c.JSON(http.StatusOK, response)
@@ -123,24 +269,146 @@ func (h *Handler) GetWords(c *gin.Context) {
 func (h *Handler) GetWord(c *gin.Context) {
 	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+		respondError(c, invalidField("id", "must be an integer"))
+		return
+	}
+
+	word, err := h.svc.GetWord(c.Request.Context(), id)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, word)
+}
+
+// wordRequest is the body accepted when creating or replacing a word.
+// Parts is kept raw so its structure can be validated by the service.
+type wordRequest struct {
+	Japanese string          `json:"japanese"`
+	Romaji   string          `json:"romaji"`
+	English  string          `json:"english"`
+	Parts    json.RawMessage `json:"parts"`
+}
+
+func (r *wordRequest) toModel(id int64) *models.Word {
+	word := &models.Word{
+		ID:       id,
+		Japanese: r.Japanese,
+		Romaji:   r.Romaji,
+		English:  r.English,
+	}
+	if len(r.Parts) > 0 {
+		word.Parts = sql.NullString{String: string(r.Parts), Valid: true}
+	}
+	return word
+}
+
+// CreateWord adds a new word to the vocabulary
+func (h *Handler) CreateWord(c *gin.Context) {
+	var req wordRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondError(c, invalidBody(err))
+		return
+	}
+
+	word, err := h.svc.CreateWord(c.Request.Context(), req.toModel(0))
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+	c.JSON(http.StatusCreated, word)
+}
+
+// UpdateWord replaces all fields of an existing word
+func (h *Handler) UpdateWord(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		respondError(c, invalidField("id", "must be an integer"))
+		return
+	}
+
+	var req wordRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondError(c, invalidBody(err))
+		return
+	}
+
+	word, err := h.svc.UpdateWord(c.Request.Context(), req.toModel(id))
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, word)
+}
+
+// PatchWord updates only the fields present in the request body.
+// Sending "parts": null clears the parts.
+func (h *Handler) PatchWord(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		respondError(c, invalidField("id", "must be an integer"))
+		return
+	}
+
+	var req struct {
+		Japanese *string         `json:"japanese"`
+		Romaji   *string         `json:"romaji"`
+		English  *string         `json:"english"`
+		Parts    json.RawMessage `json:"parts"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondError(c, invalidBody(err))
 		return
 	}
 
-	word, err := h.svc.GetWord(id)
+	patch := models.WordPatch{
+		Japanese: req.Japanese,
+		Romaji:   req.Romaji,
+		English:  req.English,
+	}
+	if len(req.Parts) > 0 {
+		parts := string(req.Parts)
+		patch.Parts = &parts
+	}
+
+	word, err := h.svc.PatchWord(c.Request.Context(), id, patch)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		respondError(c, err)
 		return
 	}
 	c.JSON(http.StatusOK, word)
 }
 
-// GetGroups returns a paginated list of groups
+// DeleteWord removes a word and its review history
+func (h *Handler) DeleteWord(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		respondError(c, invalidField("id", "must be an integer"))
+		return
+	}
+
+	if err := h.svc.DeleteWord(c.Request.Context(), id); err != nil {
+		respondError(c, err)
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+// GetGroups returns a sorted and paginated list of groups
 func (h *Handler) GetGroups(c *gin.Context) {
-	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
-	response, err := h.svc.GetGroups(page)
+	filter := models.GroupFilter{
+		SortBy: c.Query("sort_by"),
+		Order:  c.Query("order"),
+	}
+
+	page, err := parsePage(c)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		respondError(c, err)
+		return
+	}
+	response, err := h.svc.GetGroups(c.Request.Context(), filter, page)
+	if err != nil {
+		respondError(c, err)
 		return
 	}
 	c.JSON(http.StatusOK, response)
@@ -150,30 +418,190 @@ func (h *Handler) GetGroups(c *gin.Context) {
 func (h *Handler) GetGroup(c *gin.Context) {
 	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+		respondError(c, invalidField("id", "must be an integer"))
+		return
+	}
+
+	group, err := h.svc.GetGroup(c.Request.Context(), id)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, group)
+}
+
+// CreateGroup creates a new, empty group
+func (h *Handler) CreateGroup(c *gin.Context) {
+	var req struct {
+		Name string `json:"name"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondError(c, invalidBody(err))
+		return
+	}
+
+	group, err := h.svc.CreateGroup(c.Request.Context(), req.Name)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+	c.JSON(http.StatusCreated, group)
+}
+
+// RenameGroup changes the name of a group
+func (h *Handler) RenameGroup(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		respondError(c, invalidField("id", "must be an integer"))
 		return
 	}
 
-	group, err := h.svc.GetGroup(id)
+	var req struct {
+		Name string `json:"name"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondError(c, invalidBody(err))
+		return
+	}
+
+	group, err := h.svc.RenameGroup(c.Request.Context(), id, req.Name)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		respondError(c, err)
 		return
 	}
 	c.JSON(http.StatusOK, group)
 }
 
+// DeleteGroup removes a group and its memberships, keeping the words
+func (h *Handler) DeleteGroup(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		respondError(c, invalidField("id", "must be an integer"))
+		return
+	}
+
+	if err := h.svc.DeleteGroup(c.Request.Context(), id); err != nil {
+		respondError(c, err)
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+// groupWordsRequest is the body of bulk membership changes
+type groupWordsRequest struct {
+	WordIDs []int64 `json:"word_ids"`
+}
+
+// AddGroupWords adds several words to a group; words already in the group are skipped
+func (h *Handler) AddGroupWords(c *gin.Context) {
+	groupID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		respondError(c, invalidField("id", "must be an integer"))
+		return
+	}
+
+	var req groupWordsRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondError(c, invalidBody(err))
+		return
+	}
+
+	added, err := h.svc.AddWordsToGroup(c.Request.Context(), groupID, req.WordIDs)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"added": added})
+}
+
+// RemoveGroupWords removes several words from a group
+func (h *Handler) RemoveGroupWords(c *gin.Context) {
+	groupID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		respondError(c, invalidField("id", "must be an integer"))
+		return
+	}
+
+	var req groupWordsRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondError(c, invalidBody(err))
+		return
+	}
+
+	removed, err := h.svc.RemoveWordsFromGroup(c.Request.Context(), groupID, req.WordIDs)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"removed": removed})
+}
+
+// AddGroupWord adds a single word to a group
+func (h *Handler) AddGroupWord(c *gin.Context) {
+	groupID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		respondError(c, invalidField("id", "must be an integer"))
+		return
+	}
+
+	wordID, err := strconv.ParseInt(c.Param("word_id"), 10, 64)
+	if err != nil {
+		respondError(c, invalidField("word_id", "must be an integer"))
+		return
+	}
+
+	added, err := h.svc.AddWordsToGroup(c.Request.Context(), groupID, []int64{wordID})
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"added": added})
+}
+
+// RemoveGroupWord removes a single word from a group
+func (h *Handler) RemoveGroupWord(c *gin.Context) {
+	groupID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		respondError(c, invalidField("id", "must be an integer"))
+		return
+	}
+
+	wordID, err := strconv.ParseInt(c.Param("word_id"), 10, 64)
+	if err != nil {
+		respondError(c, invalidField("word_id", "must be an integer"))
+		return
+	}
+
+	removed, err := h.svc.RemoveWordsFromGroup(c.Request.Context(), groupID, []int64{wordID})
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"removed": removed})
+}
+
 // GetGroupWords returns words for a specific group
 func (h *Handler) GetGroupWords(c *gin.Context) {
 	groupID, err := strconv.ParseInt(c.Param("id"), 10, 64)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid group id"})
+		respondError(c, invalidField("id", "must be an integer"))
 		return
 	}
 
-	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
-	response, err := h.svc.GetWordsByGroup(groupID, page)
+	filter, err := parseWordFilter(c)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		respondError(c, err)
+		return
+	}
+
+	page, err := parsePage(c)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+	response, err := h.svc.GetWordsByGroup(c.Request.Context(), groupID, filter, page)
+	if err != nil {
+		respondError(c, err)
 		return
 	}
 	c.JSON(http.StatusOK, response)
@@ -181,60 +609,173 @@ func (h *Handler) GetGroupWords(c *gin.Context) {
 
 // GetGroupStudySessions returns study sessions for a specific group
 func (h *Handler) GetGroupStudySessions(c *gin.Context) {
-	// TODO: Implement
-	c.JSON(http.StatusOK, gin.H{
-		"items": []gin.H{},
-		"pagination": gin.H{
-			"current_page":   1,
-			"total_pages":    1,
-			"total_items":    0,
-			"items_per_page": 100,
-		},
-	})
+	groupID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		respondError(c, invalidField("id", "must be an integer"))
+		return
+	}
+
+	page, err := parsePage(c)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+	response, err := h.svc.GetStudySessionsByGroup(c.Request.Context(), groupID, page)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, response)
+}
+
+// GetGroupDueWords returns the words in a group that are due for spaced-repetition review
+func (h *Handler) GetGroupDueWords(c *gin.Context) {
+	groupID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		respondError(c, invalidField("id", "must be an integer"))
+		return
+	}
+
+	page, err := parsePage(c)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+	response, err := h.svc.GetDueWords(c.Request.Context(), groupID, page)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, response)
+}
+
+// GetReviewQueue returns all studied words that are due for spaced-repetition review
+func (h *Handler) GetReviewQueue(c *gin.Context) {
+	page, err := parsePage(c)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+	response, err := h.svc.GetReviewQueue(c.Request.Context(), page)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, response)
 }
 
-// GetStudySessions returns all study sessions
+// GetStudySessions returns a filtered, sorted and paginated list of study sessions
 func (h *Handler) GetStudySessions(c *gin.Context) {
-	// TODO: Implement
-	c.JSON(http.StatusOK, gin.H{
-		"items": []gin.H{},
-		"pagination": gin.H{
-			"current_page":   1,
-			"total_pages":    1,
-			"total_items":    0,
-			"items_per_page": 100,
-		},
-	})
+	filter, err := parseStudySessionFilter(c)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	filter.SortBy = c.Query("sort_by")
+	filter.Order = c.Query("order")
+
+	page, err := parsePage(c)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+	response, err := h.svc.GetStudySessions(c.Request.Context(), filter, page)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, response)
+}
+
+// GetReviews returns the review history newest first, or oldest first with
+// order=asc, a page at a time: each page's next_cursor asks for the next. It
+// accepts the same filters as GetStudySessions.
+func (h *Handler) GetReviews(c *gin.Context) {
+	filter, err := parseStudySessionFilter(c)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+	filter.Order = c.Query("order")
+
+	page, err := parsePage(c)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+	response, err := h.svc.GetReviews(c.Request.Context(), filter, page)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, response)
 }
 
 // GetStudySession returns a specific study session
 func (h *Handler) GetStudySession(c *gin.Context) {
 	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+		respondError(c, invalidField("id", "must be an integer"))
 		return
 	}
 
-	session, err := h.svc.GetStudySession(id)
+	session, err := h.svc.GetStudySession(c.Request.Context(), id)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		respondError(c, err)
 		return
 	}
 	c.JSON(http.StatusOK, session)
 }
 
+// EndStudySession completes an active study session and returns its summary
+func (h *Handler) EndStudySession(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		respondError(c, invalidField("id", "must be an integer"))
+		return
+	}
+
+	summary, err := h.svc.EndStudySession(c.Request.Context(), id)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, summary)
+}
+
+// GetStudySessionSummary returns how a study session went
+func (h *Handler) GetStudySessionSummary(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		respondError(c, invalidField("id", "must be an integer"))
+		return
+	}
+
+	summary, err := h.svc.GetStudySessionSummary(c.Request.Context(), id)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, summary)
+}
+
 // GetStudySessionWords returns words for a specific study session
 func (h *Handler) GetStudySessionWords(c *gin.Context) {
 	sessionID, err := strconv.ParseInt(c.Param("id"), 10, 64)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid session id"})
+		respondError(c, invalidField("id", "must be an integer"))
 		return
 	}
 
-	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
-	response, err := h.svc.GetWordsByStudySession(sessionID, page)
+	page, err := parsePage(c)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+	response, err := h.svc.GetWordsByStudySession(c.Request.Context(), sessionID, page)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		respondError(c, err)
 		return
 	}
 	c.JSON(http.StatusOK, response)
@@ -244,49 +785,213 @@ func (h *Handler) GetStudySessionWords(c *gin.Context) {
 func (h *Handler) ReviewWord(c *gin.Context) {
 	sessionID, err := strconv.ParseInt(c.Param("id"), 10, 64)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid session id"})
+		respondError(c, invalidField("id", "must be an integer"))
 		return
 	}
 
 	wordID, err := strconv.ParseInt(c.Param("word_id"), 10, 64)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid word id"})
+		respondError(c, invalidField("word_id", "must be an integer"))
 		return
 	}
 
+	// Correct is accepted for clients that predate graded reviews and maps
+	// onto "good" or "again" when no grade is given.
 	var req struct {
-		Correct bool `json:"correct" binding:"required"`
+		Grade          models.Grade     `json:"grade"`
+		Correct        *bool            `json:"correct"`
+		ResponseTimeMs *int64           `json:"response_time_ms" binding:"omitempty,min=0"`
+		Answer         string           `json:"answer"`
+		Direction      models.Direction `json:"direction"`
 	}
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		respondError(c, invalidBody(err))
 		return
 	}
 
-	review, err := h.svc.ReviewWord(wordID, sessionID, req.Correct)
+	if req.Grade == "" && req.Correct != nil {
+		req.Grade = models.GradeAgain
+		if *req.Correct {
+			req.Grade = models.GradeGood
+		}
+	}
+	if !req.Grade.Valid() {
+		respondError(c, invalidField("grade", "must be one of again, hard, good, easy"))
+		return
+	}
+	if req.Direction != "" && !req.Direction.Valid() {
+		respondError(c, invalidField("direction", "must be one of jp_en, en_jp, kana_kanji"))
+		return
+	}
+
+	review, err := h.svc.ReviewWord(c.Request.Context(), &models.WordReviewItem{
+		WordID:         wordID,
+		StudySessionID: sessionID,
+		Grade:          req.Grade,
+		ResponseTimeMs: req.ResponseTimeMs,
+		Answer:         req.Answer,
+		Direction:      req.Direction,
+	})
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		respondError(c, err)
 		return
 	}
 
 	c.JSON(http.StatusCreated, review)
 }
 
-// ResetHistory resets study history
+// ImportWords imports an uploaded CSV, TSV or Anki file (multipart field
+// "file") into a group and reports the rows that could not be imported
+func (h *Handler) ImportWords(c *gin.Context) {
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, h.maxImportSize)
+	header, err := c.FormFile("file")
+	var tooLarge *http.MaxBytesError
+	if errors.As(err, &tooLarge) {
+		respondError(c, err)
+		return
+	}
+	if err != nil {
+		respondError(c, invalidField("file", "is required"))
+		return
+	}
+
+	req := service.ImportRequest{
+		FileName:  header.Filename,
+		Size:      header.Size,
+		Format:    c.PostForm("format"),
+		GroupName: c.PostForm("group_name"),
+	}
+	if v := c.PostForm("group_id"); v != "" {
+		if req.GroupID, err = strconv.ParseInt(v, 10, 64); err != nil {
+			respondError(c, invalidField("group_id", "must be an integer"))
+			return
+		}
+	}
+	if v := c.PostForm("dry_run"); v != "" {
+		if req.DryRun, err = strconv.ParseBool(v); err != nil {
+			respondError(c, invalidField("dry_run", "must be true or false"))
+			return
+		}
+	}
+	if req.Mapping, err = importer.ParseMapping(c.PostForm("mapping")); err != nil {
+		respondError(c, invalidField("mapping", err.Error()))
+		return
+	}
+
+	file, err := header.Open()
+	if err != nil {
+		respondError(c, invalidField("file", err.Error()))
+		return
+	}
+	defer file.Close()
+	req.File = file
+
+	report, err := h.svc.ImportWords(c.Request.Context(), req)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, report)
+}
+
+// ExportGroupWords downloads a group's words as csv (default), json or apkg
+func (h *Handler) ExportGroupWords(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		respondError(c, invalidField("id", "must be an integer"))
+		return
+	}
+
+	group, err := h.svc.GetGroup(c.Request.Context(), id)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	format := c.DefaultQuery("format", "csv")
+	if err := service.ValidateExportFormat(exporter.WordFormats, format); err != nil {
+		respondError(c, err)
+		return
+	}
+
+	attachment(c, fmt.Sprintf("group-%d.%s", group.ID, format), format)
+	if err := h.svc.ExportGroupWords(c.Request.Context(), group, format, c.Writer); err != nil {
+		abortExport(c, err)
+	}
+}
+
+// ExportReviews downloads the review history as csv (default) or json. It
+// accepts the same filters as GetStudySessions.
+func (h *Handler) ExportReviews(c *gin.Context) {
+	filter, err := parseStudySessionFilter(c)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	format := c.DefaultQuery("format", "csv")
+	if err := service.ValidateExportFormat(exporter.ReviewFormats, format); err != nil {
+		respondError(c, err)
+		return
+	}
+
+	attachment(c, "study_history."+format, format)
+	if err := h.svc.ExportReviews(c.Request.Context(), filter, format, c.Writer); err != nil {
+		abortExport(c, err)
+	}
+}
+
+// attachment sets the headers of a file download. Downloads are streamed
+// for as long as the export takes, so the server's write timeout, meant for
+// ordinary responses, is lifted for them.
+func attachment(c *gin.Context, fileName, format string) {
+	// not every ResponseWriter supports deadlines, such as test recorders;
+	// those have no timeout to lift
+	_ = http.NewResponseController(c.Writer).SetWriteDeadline(time.Time{})
+	c.Header("Content-Type", exporter.ContentType(format))
+	c.Header("Content-Disposition", `attachment; filename="`+fileName+`"`)
+	c.Status(http.StatusOK)
+}
+
+// abortExport reports an export that failed. Once rows have been streamed the
+// status is already sent, so the error can only be logged.
+func abortExport(c *gin.Context, err error) {
+	if !c.Writer.Written() {
+		c.Writer.Header().Del("Content-Type")
+		c.Writer.Header().Del("Content-Disposition")
+	}
+	respondError(c, err)
+}
+
+// ResetHistory resets the study history of the user given by user_id, or of
+// every user without it
 func (h *Handler) ResetHistory(c *gin.Context) {
-	if err := h.svc.ResetHistory(); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	var userID int64
+	if v := c.Query("user_id"); v != "" {
+		var err error
+		if userID, err = strconv.ParseInt(v, 10, 64); err != nil || userID < 1 {
+			respondError(c, invalidField("user_id", "must be a positive integer"))
+			return
+		}
+	}
+	if err := h.svc.ResetHistory(c.Request.Context(), userID); err != nil {
+		respondError(c, err)
 		return
 	}
+	message := "Study history of every user has been reset"
+	if userID != 0 {
+		message = fmt.Sprintf("Study history of user %d has been reset", userID)
+	}
 	c.JSON(http.StatusOK, gin.H{
 		"success": true,
-		"message": "Study history has been reset",
+		"message": message,
 	})
 }
 
 // FullReset performs a complete system reset
 func (h *Handler) FullReset(c *gin.Context) {
-	if err := h.svc.FullReset(); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	if err := h.svc.FullReset(c.Request.Context()); err != nil {
+		respondError(c, err)
 		return
 	}
 	c.JSON(http.StatusOK, gin.H{
@@ -294,3 +999,112 @@ func (h *Handler) FullReset(c *gin.Context) {
 		"message": "System has been fully reset",
 	})
 }
+
+// parseStudySessionFilter reads the group_id, study_activity_id, from and to
+// query parameters
+func parseStudySessionFilter(c *gin.Context) (models.StudySessionFilter, error) {
+	var filter models.StudySessionFilter
+	var err error
+
+	if v := c.Query("group_id"); v != "" {
+		if filter.GroupID, err = strconv.ParseInt(v, 10, 64); err != nil {
+			return filter, invalidField("group_id", "must be an integer")
+		}
+	}
+	if v := c.Query("study_activity_id"); v != "" {
+		if filter.StudyActivityID, err = strconv.ParseInt(v, 10, 64); err != nil {
+			return filter, invalidField("study_activity_id", "must be an integer")
+		}
+	}
+	if filter.From, err = parseTimeQuery(c.Query("from"), false); err != nil {
+		return filter, invalidField("from", "must be an RFC 3339 timestamp or a YYYY-MM-DD date")
+	}
+	if filter.To, err = parseTimeQuery(c.Query("to"), true); err != nil {
+		return filter, invalidField("to", "must be an RFC 3339 timestamp or a YYYY-MM-DD date")
+	}
+	return filter, nil
+}
+
+// parseTimeQuery accepts either an RFC 3339 timestamp or a YYYY-MM-DD date.
+// A bare date used as an upper bound covers the whole day.
+func parseTimeQuery(value string, upperBound bool) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, nil
+	}
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, nil
+	}
+	t, err := time.Parse("2006-01-02", value)
+	if err != nil {
+		return time.Time{}, err
+	}
+	if upperBound {
+		t = t.AddDate(0, 0, 1)
+	}
+	return t, nil
+}
+
+// parsePage reads the page, per_page and cursor query parameters of a
+// listing. The service checks them against the listing and the page size
+// limit.
+func parsePage(c *gin.Context) (models.PageRequest, error) {
+	page := models.PageRequest{Cursor: c.Query("cursor")}
+	var errs models.ValidationErrors
+	for _, param := range []struct {
+		name   string
+		target *int
+	}{{"page", &page.Page}, {"per_page", &page.PerPage}} {
+		v := c.Query(param.name)
+		if v == "" {
+			continue
+		}
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 {
+			errs = append(errs, models.ValidationError{Field: param.name, Message: "must be a positive integer"})
+			continue
+		}
+		*param.target = n
+	}
+	if len(errs) > 0 {
+		return page, errs
+	}
+	return page, nil
+}
+
+// parseWordFilter reads the sorting and filtering query parameters shared by
+// word listings
+func parseWordFilter(c *gin.Context) (models.WordFilter, error) {
+	filter := models.WordFilter{
+		SortBy: c.Query("sort_by"),
+		Order:  c.Query("order"),
+	}
+	var errs models.ValidationErrors
+
+	for _, param := range []struct {
+		name   string
+		target **bool
+	}{{"has_parts", &filter.HasParts}, {"never_reviewed", &filter.NeverReviewed}} {
+		if v := c.Query(param.name); v != "" {
+			b, err := strconv.ParseBool(v)
+			if err != nil {
+				errs = append(errs, models.ValidationError{Field: param.name, Message: "must be true or false"})
+				continue
+			}
+			*param.target = &b
+		}
+	}
+
+	if v := c.Query("accuracy_below"); v != "" {
+		accuracy, err := strconv.ParseFloat(v, 64)
+		if err != nil || accuracy < 0 || accuracy > 100 {
+			errs = append(errs, models.ValidationError{Field: "accuracy_below", Message: "must be a percentage between 0 and 100"})
+		} else {
+			filter.AccuracyBelow = &accuracy
+		}
+	}
+
+	if len(errs) > 0 {
+		return filter, errs
+	}
+	return filter, nil
+}
@@ -1,25 +1,64 @@
 package handlers
 
 import (
+	"bytes"
+	"context"
+	"database/sql"
 	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"mime/multipart"
 	"net/http"
 	"net/http/httptest"
+	"strconv"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/gen-ai-bootcamp-2025/backend_go/internal/auth"
 	"github.com/gen-ai-bootcamp-2025/backend_go/internal/models"
 	"github.com/gen-ai-bootcamp-2025/backend_go/internal/service"
+	"github.com/gen-ai-bootcamp-2025/backend_go/internal/testdb"
 	"github.com/stretchr/testify/assert"
 )
 
 // MockDB implements the necessary database methods for testing
 type MockDB struct{}
 
-func (m *MockDB) GetWord(id int64) (*models.Word, error) {
-	return &models.Word{ID: id, Japanese: "テスト", Romaji: "tesuto", English: "test"}, nil
+func (m *MockDB) GetWord(ctx context.Context, userID, id int64) (*models.Word, error) {
+	if id == missingID {
+		return nil, nil
+	}
+	accuracy := 75.0
+	lastReviewedAt := time.Date(2025, 1, 4, 9, 0, 0, 0, time.UTC)
+	return &models.Word{
+		ID: id, Japanese: "テスト", Romaji: "tesuto", English: "test",
+		WordStats: models.WordStats{
+			CorrectCount:   3,
+			WrongCount:     1,
+			Accuracy:       &accuracy,
+			LastReviewedAt: &lastReviewedAt,
+		},
+	}, nil
+}
+
+func (m *MockDB) GetWords(ctx context.Context, filter models.WordFilter, page, perPage int) ([]*models.Word, *models.Pagination, error) {
+	words := []*models.Word{
+		{ID: 1, Japanese: "テスト", Romaji: "tesuto", English: "test"},
+	}
+	pagination := &models.Pagination{
+		CurrentPage:  page,
+		TotalPages:   1,
+		TotalItems:   1,
+		ItemsPerPage: perPage,
+	}
+	return words, pagination, nil
 }
 
-func (m *MockDB) GetWords(page, perPage int) ([]*models.Word, *models.Pagination, error) {
+func (m *MockDB) SearchWords(ctx context.Context, userID int64, q string, page, perPage int) ([]*models.Word, *models.Pagination, error) {
 	words := []*models.Word{
 		{ID: 1, Japanese: "テスト", Romaji: "tesuto", English: "test"},
 	}
@@ -32,11 +71,31 @@ func (m *MockDB) GetWords(page, perPage int) ([]*models.Word, *models.Pagination
 	return words, pagination, nil
 }
 
-func (m *MockDB) GetGroup(id int64) (*models.Group, error) {
+func (m *MockDB) CreateWord(ctx context.Context, word *models.Word) (*models.Word, error) {
+	created := *word
+	created.ID = 1
+	return &created, nil
+}
+
+func (m *MockDB) UpdateWord(ctx context.Context, word *models.Word) (*models.Word, error) {
+	return word, nil
+}
+
+func (m *MockDB) DeleteWord(ctx context.Context, id int64) error {
+	return nil
+}
+
+func (m *MockDB) GetGroup(ctx context.Context, id int64) (*models.Group, error) {
+	if id == missingID {
+		return nil, nil
+	}
 	return &models.Group{ID: id, Name: "Test Group", WordCount: 10}, nil
 }
 
-func (m *MockDB) GetGroups(page, perPage int) ([]*models.Group, *models.Pagination, error) {
+func (m *MockDB) GetGroups(ctx context.Context, filter models.GroupFilter, page, perPage int) ([]*models.Group, *models.Pagination, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, nil, err
+	}
 	groups := []*models.Group{
 		{ID: 1, Name: "Test Group", WordCount: 10},
 	}
@@ -49,15 +108,245 @@ func (m *MockDB) GetGroups(page, perPage int) ([]*models.Group, *models.Paginati
 	return groups, pagination, nil
 }
 
-func (m *MockDB) CreateStudySession(groupID, activityID int64) (*models.StudySession, error) {
-	return &models.StudySession{ID: 1, GroupID: groupID, StudyActivityID: activityID}, nil
+const (
+	// missingID is treated as a nonexistent row by MockDB
+	missingID = 999
+	// inUseActivityID is a study activity that MockDB reports as having sessions
+	inUseActivityID = 2
+	// knownRegistration is the xAPI registration of study session 1
+	knownRegistration = "8f3c2a1e-5b7d-4e6f-9a0b-1c2d3e4f5a6b"
+	// recordedStatementID is an xAPI statement MockDB has already recorded
+	recordedStatementID = "2d6e0f4a-1b3c-4d5e-8f7a-9b0c1d2e3f4a"
+	// otherUserID is a second user, who owns study session otherUsersSessionID
+	otherUserID         = 2
+	otherUsersSessionID = 3
+	// endedSessionID is a study session of user 1 that has been completed
+	endedSessionID = 4
+	// strayWordID is a word in no group, so not in any study session's group
+	strayWordID = 5
+	// takenUserName is the name of an existing user, who signs in with
+	// takenUserPassword
+	takenUserName     = "hana"
+	takenUserPassword = "correct horse"
+	// teacherID is a teacher; user 1 is an admin and every other user a learner
+	teacherID = 4
+	// takenGroupName is the name of an existing group
+	takenGroupName = "Basic Greetings"
+)
+
+// apiKeyUsers maps the API keys MockDB knows to the users they act for
+var apiKeyUsers = map[string]int64{
+	"admin-key":   1,
+	"learner-key": otherUserID,
+	"teacher-key": teacherID,
+}
+
+var takenUserPasswordHash, _ = auth.HashPassword(takenUserPassword)
+
+func (m *MockDB) GetUser(ctx context.Context, id int64) (*models.User, error) {
+	if id == missingID {
+		return nil, nil
+	}
+	user := &models.User{ID: id, Name: fmt.Sprintf("user%d", id), Role: models.RoleLearner}
+	switch id {
+	case 1:
+		user.Role = models.RoleAdmin
+	case otherUserID:
+		user.Name, user.PasswordHash = takenUserName, takenUserPasswordHash
+	case teacherID:
+		user.Role = models.RoleTeacher
+	}
+	return user, nil
+}
+
+func (m *MockDB) GetUserByName(ctx context.Context, name string) (*models.User, error) {
+	if name != takenUserName {
+		return nil, nil
+	}
+	return m.GetUser(ctx, otherUserID)
+}
+
+func (m *MockDB) UpdateUser(ctx context.Context, user *models.User) (*models.User, error) {
+	return user, nil
+}
+
+func (m *MockDB) GetUserByAPIKey(ctx context.Context, keyHash string) (*models.User, error) {
+	for key, id := range apiKeyUsers {
+		if auth.HashAPIKey(key) == keyHash {
+			return m.GetUser(ctx, id)
+		}
+	}
+	return nil, nil
+}
+
+func (m *MockDB) GetAPIKeys(ctx context.Context, userID int64) ([]*models.APIKey, error) {
+	return []*models.APIKey{{ID: 1, UserID: userID, Name: "flashcards"}}, nil
+}
+
+func (m *MockDB) CreateAPIKey(ctx context.Context, userID int64, name, keyHash string) (*models.APIKey, error) {
+	return &models.APIKey{ID: 2, UserID: userID, Name: name}, nil
+}
+
+func (m *MockDB) DeleteAPIKey(ctx context.Context, userID, id int64) error {
+	return nil
+}
+
+func (m *MockDB) GetUsers(ctx context.Context, page, perPage int) ([]*models.User, *models.Pagination, error) {
+	users := []*models.User{{ID: 1, Name: "default"}, {ID: otherUserID, Name: takenUserName}}
+	return users, &models.Pagination{CurrentPage: page, TotalPages: 1, TotalItems: len(users), ItemsPerPage: perPage}, nil
+}
+
+func (m *MockDB) CreateUser(ctx context.Context, name string, role models.Role) (*models.User, error) {
+	return &models.User{ID: 3, Name: name, Role: role}, nil
+}
+
+func (m *MockDB) CreateGroup(ctx context.Context, name string) (*models.Group, error) {
+	if name == takenGroupName {
+		return nil, fmt.Errorf("%w (groups.name)", models.ErrDuplicate)
+	}
+	return &models.Group{ID: 1, Name: name}, nil
+}
+
+func (m *MockDB) RenameGroup(ctx context.Context, id int64, name string) (*models.Group, error) {
+	return &models.Group{ID: id, Name: name}, nil
+}
+
+func (m *MockDB) DeleteGroup(ctx context.Context, id int64) error {
+	return nil
+}
+
+func (m *MockDB) CountStudySessionsByGroup(ctx context.Context, groupID int64) (int, error) {
+	return 0, nil
+}
+
+func (m *MockDB) AddWordsToGroup(ctx context.Context, groupID int64, wordIDs []int64) (int, error) {
+	return len(wordIDs), nil
+}
+
+func (m *MockDB) RemoveWordsFromGroup(ctx context.Context, groupID int64, wordIDs []int64) (int, error) {
+	return len(wordIDs), nil
+}
+
+func (m *MockDB) IsWordInGroup(ctx context.Context, groupID, wordID int64) (bool, error) {
+	return wordID != missingID && wordID != strayWordID, nil
+}
+
+func (m *MockDB) GetMissingWordIDs(ctx context.Context, wordIDs []int64) ([]int64, error) {
+	missing := []int64{}
+	for _, id := range wordIDs {
+		if id == missingID {
+			missing = append(missing, id)
+		}
+	}
+	return missing, nil
+}
+
+func (m *MockDB) GetStudyActivity(ctx context.Context, id int64) (*models.StudyActivity, error) {
+	if id == missingID {
+		return nil, nil
+	}
+	return &models.StudyActivity{
+		ID:        id,
+		Name:      "Flashcards",
+		LaunchURL: "http://localhost:8081",
+		Enabled:   true,
+	}, nil
+}
+
+func (m *MockDB) GetStudyActivities(ctx context.Context, page, perPage int) ([]*models.StudyActivity, *models.Pagination, error) {
+	activities := []*models.StudyActivity{
+		{ID: 1, Name: "Flashcards", LaunchURL: "http://localhost:8081", Enabled: true},
+	}
+	pagination := &models.Pagination{
+		CurrentPage:  page,
+		TotalPages:   1,
+		TotalItems:   1,
+		ItemsPerPage: perPage,
+	}
+	return activities, pagination, nil
+}
+
+func (m *MockDB) CreateStudyActivity(ctx context.Context, activity *models.StudyActivity) (*models.StudyActivity, error) {
+	created := *activity
+	created.ID = 1
+	return &created, nil
+}
+
+func (m *MockDB) UpdateStudyActivity(ctx context.Context, activity *models.StudyActivity) (*models.StudyActivity, error) {
+	return activity, nil
+}
+
+func (m *MockDB) DeleteStudyActivity(ctx context.Context, id int64) error {
+	return nil
+}
+
+func (m *MockDB) CountStudySessionsByActivity(ctx context.Context, activityID int64) (int, error) {
+	if activityID == inUseActivityID {
+		return 1, nil
+	}
+	return 0, nil
+}
+
+func (m *MockDB) CreateStudySession(ctx context.Context, userID, groupID, activityID int64) (*models.StudySession, error) {
+	if groupID == missingID {
+		// the foreign key on study_sessions.group_id
+		return nil, models.ErrMissingReference
+	}
+	return &models.StudySession{ID: 1, UserID: userID, GroupID: groupID, StudyActivityID: activityID, Status: models.StudySessionActive}, nil
+}
+
+func (m *MockDB) GetStudySession(ctx context.Context, id int64) (*models.StudySession, error) {
+	if id == missingID {
+		return nil, nil
+	}
+	userID := int64(models.DefaultUserID)
+	if id == otherUsersSessionID {
+		userID = otherUserID
+	}
+	status := models.StudySessionActive
+	if id == endedSessionID {
+		status = models.StudySessionCompleted
+	}
+	return &models.StudySession{ID: id, UserID: userID, GroupID: 1, GroupName: "Test Group", Status: status}, nil
+}
+
+func (m *MockDB) EndStudySession(ctx context.Context, id int64, endedAt time.Time) (bool, error) {
+	return id != missingID && id != endedSessionID, nil
+}
+
+func (m *MockDB) AbandonIdleStudySessions(ctx context.Context, idleSince time.Time) (int64, error) {
+	return 0, nil
+}
+
+// GetStudySessionSummary reports a session of ten minutes with four reviews
+// of three words, one of them studied before
+func (m *MockDB) GetStudySessionSummary(ctx context.Context, id int64) (*models.StudySessionSummary, error) {
+	session, err := m.GetStudySession(ctx, id)
+	if session == nil || err != nil {
+		return nil, err
+	}
+	session.CreatedAt = time.Date(2025, 1, 4, 9, 0, 0, 0, time.UTC)
+	endedAt := session.CreatedAt.Add(10 * time.Minute)
+	session.EndedAt = &endedAt
+	session.Status = models.StudySessionCompleted
+	session.ReviewItemCount = 4
+	return &models.StudySessionSummary{
+		StudySession:  *session,
+		WordsSeen:     3,
+		CorrectCount:  3,
+		NewWords:      2,
+		RepeatedWords: 1,
+	}, nil
 }
 
-func (m *MockDB) GetStudySession(id int64) (*models.StudySession, error) {
-	return &models.StudySession{ID: id, GroupID: 1, GroupName: "Test Group"}, nil
+func (m *MockDB) GetStudySessionByRegistration(ctx context.Context, registration string) (*models.StudySession, error) {
+	if registration != knownRegistration {
+		return nil, nil
+	}
+	return &models.StudySession{ID: 1, UserID: models.DefaultUserID, GroupID: 1, GroupName: "Test Group", Status: models.StudySessionActive}, nil
 }
 
-func (m *MockDB) GetLastStudySession() (*models.StudySession, error) {
+func (m *MockDB) GetLastStudySession(ctx context.Context, userID int64) (*models.StudySession, error) {
 	return &models.StudySession{
 		ID:       1,
 		GroupID:  1,
@@ -65,14 +354,27 @@ func (m *MockDB) GetLastStudySession() (*models.StudySession, error) {
 	}, nil
 }
 
-func (m *MockDB) GetStudyProgress() (*models.StudyProgress, error) {
+func (m *MockDB) GetStudyProgress(ctx context.Context, userID int64) (*models.StudyProgress, error) {
 	return &models.StudyProgress{
 		TotalWordsStudied:    10,
 		TotalAvailableWords: 100,
 	}, nil
 }
 
-func (m *MockDB) GetStudySessionsByActivity(activityID int64, page, perPage int) ([]*models.StudySession, *models.Pagination, error) {
+func (m *MockDB) GetStudySessions(ctx context.Context, filter models.StudySessionFilter, page, perPage int) ([]*models.StudySession, *models.Pagination, error) {
+	sessions := []*models.StudySession{
+		{ID: 1, GroupID: 1, GroupName: "Test Group", StudyActivityID: 1, ActivityName: "Flashcards", ReviewItemCount: 4},
+	}
+	pagination := &models.Pagination{
+		CurrentPage:  page,
+		TotalPages:   1,
+		TotalItems:   1,
+		ItemsPerPage: perPage,
+	}
+	return sessions, pagination, nil
+}
+
+func (m *MockDB) GetStudySessionsByActivity(ctx context.Context, userID, activityID int64, page, perPage int) ([]*models.StudySession, *models.Pagination, error) {
 	sessions := []*models.StudySession{
 		{ID: 1, GroupID: 1, GroupName: "Test Group"},
 	}
@@ -85,11 +387,114 @@ func (m *MockDB) GetStudySessionsByActivity(activityID int64, page, perPage int)
 	return sessions, pagination, nil
 }
 
-func (m *MockDB) CreateWordReview(wordID, sessionID int64, correct bool) (*models.WordReviewItem, error) {
-	return &models.WordReviewItem{ID: 1, WordID: wordID, StudySessionID: sessionID, Correct: correct}, nil
+func (m *MockDB) GetStudySessionsByGroup(ctx context.Context, userID, groupID int64, page, perPage int) ([]*models.StudySession, *models.Pagination, error) {
+	sessions := []*models.StudySession{
+		{ID: 1, GroupID: groupID, GroupName: "Test Group"},
+	}
+	pagination := &models.Pagination{
+		CurrentPage:  page,
+		TotalPages:   1,
+		TotalItems:   1,
+		ItemsPerPage: perPage,
+	}
+	return sessions, pagination, nil
+}
+
+// GetStudySessionsAfter serves sessions 3, 2 and 1, newest first
+func (m *MockDB) GetStudySessionsAfter(ctx context.Context, filter models.StudySessionFilter, after models.Cursor, limit int) ([]*models.StudySession, error) {
+	sessions := []*models.StudySession{}
+	for id := int64(3); id >= 1 && len(sessions) < limit; id-- {
+		if !after.IsZero() && id >= after.ID {
+			continue
+		}
+		sessions = append(sessions, &models.StudySession{
+			ID: id, UserID: filter.UserID, GroupID: 1, GroupName: "Test Group",
+			CreatedAt: time.Date(2025, 1, 4, 9, 0, 0, 0, time.UTC),
+		})
+	}
+	return sessions, nil
+}
+
+func (m *MockDB) RecordReview(ctx context.Context, review *models.WordReviewItem, schedule models.Scheduler) (*models.WordReviewItem, error) {
+	created := *review
+	created.ID = 1
+	created.Correct = review.Grade.Correct()
+	return &created, nil
+}
+
+// GetRecordedStatementIDs knows recordedStatementID as recorded
+func (m *MockDB) GetRecordedStatementIDs(ctx context.Context, userID int64, statementIDs []string) ([]string, error) {
+	recorded := []string{}
+	for _, id := range statementIDs {
+		if id == recordedStatementID {
+			recorded = append(recorded, id)
+		}
+	}
+	return recorded, nil
+}
+
+func (m *MockDB) RecordStatements(ctx context.Context, userID int64, records []*models.StatementRecord, schedule models.Scheduler) error {
+	return nil
+}
+
+// GetReviewStatements serves reviews 3, 2 and 1, newest first
+func (m *MockDB) GetReviewStatements(ctx context.Context, filter models.ReviewStatementFilter) ([]*models.ReviewExport, error) {
+	reviews := []*models.ReviewExport{}
+	for id := int64(3); id >= 1 && len(reviews) < filter.Limit; id-- {
+		if filter.AfterID != 0 && id >= filter.AfterID || filter.ReviewID != 0 && id != filter.ReviewID {
+			continue
+		}
+		review := &models.ReviewExport{
+			ID: id, StudySessionID: 1, GroupID: 1, GroupName: "Test Group",
+			StudyActivityID: 1, ActivityName: "Flashcards",
+			WordID: 1, Japanese: "猫", English: "cat", Grade: models.GradeGood, Correct: true,
+			CreatedAt: time.Date(2025, 1, 4, 9, 0, 0, 0, time.UTC),
+		}
+		if id == 3 {
+			review.StatementID = recordedStatementID
+			review.Registration = knownRegistration
+		}
+		if filter.StatementID != "" && review.StatementID != filter.StatementID {
+			continue
+		}
+		reviews = append(reviews, review)
+	}
+	return reviews, nil
+}
+
+// GetReviewsAfter serves reviews 3, 2 and 1, newest first
+func (m *MockDB) GetReviewsAfter(ctx context.Context, filter models.StudySessionFilter, after models.Cursor, limit int) ([]*models.ReviewExport, error) {
+	reviews := []*models.ReviewExport{}
+	for id := int64(3); id >= 1 && len(reviews) < limit; id-- {
+		if !after.IsZero() && id >= after.ID {
+			continue
+		}
+		reviews = append(reviews, &models.ReviewExport{
+			ID: id, UserID: filter.UserID, StudySessionID: 1, GroupID: 1, GroupName: "Test Group",
+			WordID: 1, Japanese: "猫", English: "cat", Grade: models.GradeGood, Correct: true,
+			CreatedAt: time.Date(2025, 1, 4, 9, 0, 0, 0, time.UTC),
+		})
+	}
+	return reviews, nil
+}
+
+func (m *MockDB) GetDueWords(ctx context.Context, userID, groupID int64, now time.Time, includeNew bool, page, perPage int) ([]*models.DueWord, *models.Pagination, error) {
+	words := []*models.DueWord{
+		{
+			Word:     &models.Word{ID: 1, Japanese: "テスト", Romaji: "tesuto", English: "test"},
+			Progress: &models.WordProgress{WordID: 1, EaseFactor: 2.5, IntervalDays: 1, Repetitions: 1, DueAt: now},
+		},
+	}
+	pagination := &models.Pagination{
+		CurrentPage:  page,
+		TotalPages:   1,
+		TotalItems:   1,
+		ItemsPerPage: perPage,
+	}
+	return words, pagination, nil
 }
 
-func (m *MockDB) GetQuickStats() (*models.QuickStats, error) {
+func (m *MockDB) GetQuickStats(ctx context.Context, userID int64) (*models.QuickStats, error) {
 	return &models.QuickStats{
 		SuccessRate:        0.75,
 		TotalStudySessions: 5,
@@ -98,15 +503,19 @@ func (m *MockDB) GetQuickStats() (*models.QuickStats, error) {
 	}, nil
 }
 
-func (m *MockDB) ResetHistory() error {
+func (m *MockDB) ImportWords(ctx context.Context, groupID int64, groupName string, words []*models.Word, dryRun bool) (*models.ImportResult, error) {
+	return &models.ImportResult{GroupID: groupID, Group: groupName, Added: len(words), DryRun: dryRun}, nil
+}
+
+func (m *MockDB) ResetHistory(ctx context.Context, userID int64) error {
 	return nil
 }
 
-func (m *MockDB) FullReset() error {
+func (m *MockDB) FullReset(ctx context.Context) error {
 	return nil
 }
 
-func (m *MockDB) GetWordsByGroup(groupID int64, page, perPage int) ([]*models.Word, *models.Pagination, error) {
+func (m *MockDB) GetWordsByGroup(ctx context.Context, groupID int64, filter models.WordFilter, page, perPage int) ([]*models.Word, *models.Pagination, error) {
 	words := []*models.Word{
 		{ID: 1, Japanese: "テスト", Romaji: "tesuto", English: "test"},
 	}
@@ -119,7 +528,30 @@ func (m *MockDB) GetWordsByGroup(groupID int64, page, perPage int) ([]*models.Wo
 	return words, pagination, nil
 }
 
-func (m *MockDB) GetWordsByStudySession(sessionID int64, page, perPage int) ([]*models.Word, *models.Pagination, error) {
+func (m *MockDB) EachGroupWord(ctx context.Context, userID, groupID int64, fn func(*models.Word) error) error {
+	words := []*models.Word{
+		{ID: 1, Japanese: "猫", Romaji: "neko", English: "cat",
+			Parts: sql.NullString{String: `{"type":"noun"}`, Valid: true}},
+		{ID: 2, Japanese: "犬", Romaji: "inu", English: "dog, \"hound\""},
+	}
+	for _, word := range words {
+		if err := fn(word); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (m *MockDB) EachReview(ctx context.Context, filter models.StudySessionFilter, fn func(*models.ReviewExport) error) error {
+	return fn(&models.ReviewExport{
+		ID: 1, StudySessionID: 1, GroupID: 1, GroupName: "Test Group",
+		WordID: 1, Japanese: "猫", English: "cat",
+		Grade: models.GradeGood, Correct: true,
+		CreatedAt: time.Date(2025, 1, 4, 9, 0, 0, 0, time.UTC),
+	})
+}
+
+func (m *MockDB) GetWordsByStudySession(ctx context.Context, userID, sessionID int64, page, perPage int) ([]*models.Word, *models.Pagination, error) {
 	words := []*models.Word{
 		{ID: 1, Japanese: "テスト", Romaji: "tesuto", English: "test"},
 	}
@@ -141,22 +573,77 @@ func setupTestRouter(t *testing.T) (*gin.Engine, *service.Service) {
 
 	// Initialize service with mock database
 	svc := service.NewService(mockDB)
+	svc.SetActivityBase("https://app.example.com/api")
 
 	// Setup router with handlers
 	router := gin.New()
-	handler := NewHandler(svc)
+	handler := NewHandler(svc, auth.NewTokens([]byte("test secret"), 0))
+	router.Use(Errors(), actAsTestUser(svc))
 
 	// Register routes
 	router.GET("/study-sessions/last", handler.GetLastStudySession)
 	router.GET("/study/progress", handler.GetStudyProgress)
 	router.GET("/stats/quick", handler.GetQuickStats)
 	router.GET("/words", handler.GetWords)
+	router.GET("/words/search", handler.SearchWords)
+	router.GET("/words/:id", handler.GetWord)
+	router.POST("/words", handler.CreateWord)
+	router.PATCH("/words/:id", handler.PatchWord)
+	router.DELETE("/words/:id", handler.DeleteWord)
 	router.GET("/groups", handler.GetGroups)
+	router.POST("/groups", handler.CreateGroup)
+	router.DELETE("/groups/:id", handler.DeleteGroup)
+	router.POST("/groups/:id/words", handler.AddGroupWords)
+	router.DELETE("/groups/:id/words", handler.RemoveGroupWords)
 	router.POST("/study/review", handler.ReviewWord)
+	router.POST("/study_sessions/:id/words/:word_id/review", handler.ReviewWord)
+	router.GET("/study_activities", handler.GetStudyActivities)
+	router.GET("/study_activities/:id", handler.GetStudyActivity)
+	router.POST("/study_activities", handler.CreateStudyActivity)
+	router.DELETE("/study_activities/:id", handler.DeleteStudyActivity)
+	router.GET("/groups/:id/study_sessions", handler.GetGroupStudySessions)
+	router.GET("/groups/:id/due_words", handler.GetGroupDueWords)
+	router.GET("/review_queue", handler.GetReviewQueue)
+	router.GET("/study_sessions", handler.GetStudySessions)
+	router.POST("/study_sessions", handler.CreateStudySession)
+	router.POST("/import", handler.ImportWords)
+	router.GET("/groups/:id/export", handler.ExportGroupWords)
+	router.GET("/study_sessions/export", handler.ExportReviews)
+	router.GET("/reviews", handler.GetReviews)
+	router.GET("/study_sessions/:id", handler.GetStudySession)
+	router.GET("/study_sessions/:id/summary", handler.GetStudySessionSummary)
+	router.POST("/study_sessions/:id/end", handler.EndStudySession)
+	router.GET("/users", handler.GetUsers)
+	router.GET("/users/:id", handler.GetUser)
+	router.POST("/users", handler.CreateUser)
+	router.PATCH("/users/:id", handler.UpdateUser)
+	router.GET("/xapi/statements", handler.GetStatements)
+	router.POST("/xapi/statements", handler.PostStatements)
+	router.POST("/reset_history", handler.ResetHistory)
 
 	return router, svc
 }
 
+// testUserHeader names the user a request to the test router acts for, in
+// place of real credentials. Requests without it act for user 1, an admin.
+const testUserHeader = "X-Test-User"
+
+func actAsTestUser(svc *service.Service) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id := int64(1)
+		if v := c.GetHeader(testUserHeader); v != "" {
+			id, _ = strconv.ParseInt(v, 10, 64)
+		}
+		user, err := svc.GetUser(c.Request.Context(), id)
+		if err != nil {
+			c.AbortWithStatus(http.StatusUnauthorized)
+			return
+		}
+		c.Request = c.Request.WithContext(service.WithUser(c.Request.Context(), user))
+		c.Next()
+	}
+}
+
 func TestGetLastStudySession(t *testing.T) {
 	router, _ := setupTestRouter(t)
 
@@ -250,3 +737,1517 @@ func TestReviewWordWithInvalidInput(t *testing.T) {
 
 	assert.Equal(t, http.StatusBadRequest, w.Code)
 }
+
+func TestGetStudyActivity(t *testing.T) {
+	router, _ := setupTestRouter(t)
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/study_activities/1", nil)
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var response models.StudyActivity
+	err := json.Unmarshal(w.Body.Bytes(), &response)
+	assert.NoError(t, err)
+	assert.Equal(t, "Flashcards", response.Name)
+	assert.Equal(t, "http://localhost:8081", response.LaunchURL)
+}
+
+func TestGetStudyActivityNotFound(t *testing.T) {
+	router, _ := setupTestRouter(t)
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/study_activities/999", nil)
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
+
+func TestCreateStudyActivity(t *testing.T) {
+	router, _ := setupTestRouter(t)
+
+	w := httptest.NewRecorder()
+	body := strings.NewReader(`{"name": "Typing Tutor", "launch_url": "http://localhost:8082"}`)
+	req, _ := http.NewRequest("POST", "/study_activities", body)
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusCreated, w.Code)
+
+	var response models.StudyActivity
+	err := json.Unmarshal(w.Body.Bytes(), &response)
+	assert.NoError(t, err)
+	assert.Equal(t, "Typing Tutor", response.Name)
+	assert.True(t, response.Enabled)
+}
+
+func TestDeleteStudyActivityInUse(t *testing.T) {
+	router, _ := setupTestRouter(t)
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("DELETE", "/study_activities/2", nil)
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusConflict, w.Code)
+}
+
+func TestCreateStudySessionUnknownActivity(t *testing.T) {
+	router, _ := setupTestRouter(t)
+
+	w := httptest.NewRecorder()
+	body := strings.NewReader(`{"group_id": 1, "study_activity_id": 999}`)
+	req, _ := http.NewRequest("POST", "/study_sessions", body)
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
+
+func TestCreateStudySessionUnknownGroup(t *testing.T) {
+	router, _ := setupTestRouter(t)
+
+	w := httptest.NewRecorder()
+	body := strings.NewReader(`{"group_id": 999, "study_activity_id": 1}`)
+	req, _ := http.NewRequest("POST", "/study_sessions", body)
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusNotFound, w.Code)
+	assert.JSONEq(t, `{"code": "group_not_found", "message": "group not found"}`, w.Body.String())
+}
+
+func TestGetStudySessions(t *testing.T) {
+	router, _ := setupTestRouter(t)
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/study_sessions?group_id=1&from=2025-01-01&to=2025-01-31&sort_by=review_count&order=asc", nil)
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var response map[string]interface{}
+	err := json.Unmarshal(w.Body.Bytes(), &response)
+	assert.NoError(t, err)
+
+	items, ok := response["items"].([]interface{})
+	assert.True(t, ok)
+	assert.Equal(t, 1, len(items))
+	assert.Equal(t, "Flashcards", items[0].(map[string]interface{})["activity_name"])
+}
+
+func TestGetStudySessionsWithInvalidSort(t *testing.T) {
+	router, _ := setupTestRouter(t)
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/study_sessions?sort_by=name", nil)
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
+
+func TestGetGroupStudySessions(t *testing.T) {
+	router, _ := setupTestRouter(t)
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/groups/3/study_sessions", nil)
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var response map[string]interface{}
+	err := json.Unmarshal(w.Body.Bytes(), &response)
+	assert.NoError(t, err)
+
+	items, ok := response["items"].([]interface{})
+	assert.True(t, ok)
+	assert.Equal(t, 1, len(items))
+	assert.Equal(t, float64(3), items[0].(map[string]interface{})["group_id"])
+}
+
+func TestGetGroupStudySessionsUnknownGroup(t *testing.T) {
+	router, _ := setupTestRouter(t)
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/groups/999/study_sessions", nil)
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
+
+func TestGetGroupDueWords(t *testing.T) {
+	router, _ := setupTestRouter(t)
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/groups/1/due_words", nil)
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var response map[string]interface{}
+	err := json.Unmarshal(w.Body.Bytes(), &response)
+	assert.NoError(t, err)
+
+	items, ok := response["items"].([]interface{})
+	assert.True(t, ok)
+	assert.Equal(t, 1, len(items))
+	item := items[0].(map[string]interface{})
+	assert.Equal(t, "テスト", item["japanese"])
+	assert.NotNil(t, item["progress"])
+}
+
+func TestGetGroupDueWordsUnknownGroup(t *testing.T) {
+	router, _ := setupTestRouter(t)
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/groups/999/due_words", nil)
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
+
+func TestGetReviewQueue(t *testing.T) {
+	router, _ := setupTestRouter(t)
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/review_queue", nil)
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+}
+
+func TestReviewWordWithGrade(t *testing.T) {
+	router, _ := setupTestRouter(t)
+
+	w := httptest.NewRecorder()
+	body := strings.NewReader(`{"grade": "hard", "response_time_ms": 1850, "answer": "hello", "direction": "jp_en"}`)
+	req, _ := http.NewRequest("POST", "/study_sessions/1/words/2/review", body)
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusCreated, w.Code)
+
+	var response models.WordReviewItem
+	err := json.Unmarshal(w.Body.Bytes(), &response)
+	assert.NoError(t, err)
+	assert.Equal(t, models.GradeHard, response.Grade)
+	assert.True(t, response.Correct)
+	assert.Equal(t, int64(1850), *response.ResponseTimeMs)
+	assert.Equal(t, "hello", response.Answer)
+	assert.Equal(t, models.DirectionJapaneseToEnglish, response.Direction)
+}
+
+func TestReviewWordIncorrectLegacyBody(t *testing.T) {
+	router, _ := setupTestRouter(t)
+
+	w := httptest.NewRecorder()
+	body := strings.NewReader(`{"correct": false}`)
+	req, _ := http.NewRequest("POST", "/study_sessions/1/words/2/review", body)
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusCreated, w.Code)
+
+	var response models.WordReviewItem
+	err := json.Unmarshal(w.Body.Bytes(), &response)
+	assert.NoError(t, err)
+	assert.Equal(t, models.GradeAgain, response.Grade)
+	assert.False(t, response.Correct)
+}
+
+func TestReviewWordWithInvalidGrade(t *testing.T) {
+	router, _ := setupTestRouter(t)
+
+	w := httptest.NewRecorder()
+	body := strings.NewReader(`{"grade": "perfect"}`)
+	req, _ := http.NewRequest("POST", "/study_sessions/1/words/2/review", body)
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
+
+func TestGetWordIncludesStats(t *testing.T) {
+	router, _ := setupTestRouter(t)
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/words/1", nil)
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var response map[string]interface{}
+	err := json.Unmarshal(w.Body.Bytes(), &response)
+	assert.NoError(t, err)
+	assert.Equal(t, 3.0, response["correct_count"])
+	assert.Equal(t, 1.0, response["wrong_count"])
+	assert.Equal(t, 75.0, response["accuracy"])
+	assert.Equal(t, "2025-01-04T09:00:00Z", response["last_reviewed_at"])
+}
+
+func TestGetWordsNeverReviewedStats(t *testing.T) {
+	router, _ := setupTestRouter(t)
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/words", nil)
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var response struct {
+		Items []map[string]interface{} `json:"items"`
+	}
+	err := json.Unmarshal(w.Body.Bytes(), &response)
+	assert.NoError(t, err)
+	assert.Len(t, response.Items, 1)
+	assert.Equal(t, 0.0, response.Items[0]["correct_count"])
+	assert.Contains(t, response.Items[0], "accuracy")
+	assert.Nil(t, response.Items[0]["accuracy"])
+	assert.Nil(t, response.Items[0]["last_reviewed_at"])
+}
+
+func TestGetWordNotFound(t *testing.T) {
+	router, _ := setupTestRouter(t)
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/words/999", nil)
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
+
+func TestCreateWord(t *testing.T) {
+	router, _ := setupTestRouter(t)
+
+	w := httptest.NewRecorder()
+	body := strings.NewReader(`{
+		"japanese": "新しい",
+		"romaji": "atarashii",
+		"english": "new",
+		"parts": {"type": "i-adjective", "components": [{"kanji": "新", "romaji": ["a", "ta", "ra"]}]}
+	}`)
+	req, _ := http.NewRequest("POST", "/words", body)
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusCreated, w.Code)
+
+	var response map[string]interface{}
+	err := json.Unmarshal(w.Body.Bytes(), &response)
+	assert.NoError(t, err)
+	assert.Equal(t, "新しい", response["japanese"])
+	parts, ok := response["parts"].(map[string]interface{})
+	assert.True(t, ok)
+	assert.Equal(t, "i-adjective", parts["type"])
+}
+
+func TestCreateWordWithInvalidFields(t *testing.T) {
+	router, _ := setupTestRouter(t)
+
+	w := httptest.NewRecorder()
+	body := strings.NewReader(`{"japanese": "hello", "romaji": "kon'nichiwa!", "english": "hello", "parts": [1, 2]}`)
+	req, _ := http.NewRequest("POST", "/words", body)
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	var response struct {
+		Fields []models.ValidationError `json:"fields"`
+	}
+	err := json.Unmarshal(w.Body.Bytes(), &response)
+	assert.NoError(t, err)
+
+	fields := []string{}
+	for _, f := range response.Fields {
+		fields = append(fields, f.Field)
+	}
+	assert.ElementsMatch(t, []string{"japanese", "romaji", "parts"}, fields)
+}
+
+func TestPatchWord(t *testing.T) {
+	router, _ := setupTestRouter(t)
+
+	w := httptest.NewRecorder()
+	body := strings.NewReader(`{"english": "exam"}`)
+	req, _ := http.NewRequest("PATCH", "/words/1", body)
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var response models.Word
+	err := json.Unmarshal(w.Body.Bytes(), &response)
+	assert.NoError(t, err)
+	assert.Equal(t, "exam", response.English)
+	assert.Equal(t, "テスト", response.Japanese)
+}
+
+func TestDeleteWordNotFound(t *testing.T) {
+	router, _ := setupTestRouter(t)
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("DELETE", "/words/999", nil)
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
+
+func TestCreateGroupRequiresName(t *testing.T) {
+	router, _ := setupTestRouter(t)
+
+	w := httptest.NewRecorder()
+	body := strings.NewReader(`{"name": "  "}`)
+	req, _ := http.NewRequest("POST", "/groups", body)
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
+
+func TestCreateGroup(t *testing.T) {
+	router, _ := setupTestRouter(t)
+
+	w := httptest.NewRecorder()
+	body := strings.NewReader(`{"name": "Lesson 3"}`)
+	req, _ := http.NewRequest("POST", "/groups", body)
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusCreated, w.Code)
+
+	var response models.Group
+	err := json.Unmarshal(w.Body.Bytes(), &response)
+	assert.NoError(t, err)
+	assert.Equal(t, "Lesson 3", response.Name)
+}
+
+func TestCreateGroupNameTaken(t *testing.T) {
+	router, _ := setupTestRouter(t)
+
+	w := httptest.NewRecorder()
+	body := strings.NewReader(`{"name": "Basic Greetings"}`)
+	req, _ := http.NewRequest("POST", "/groups", body)
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusConflict, w.Code)
+	assert.JSONEq(t, `{"code": "group_exists", "message": "a group with that name already exists"}`, w.Body.String())
+}
+
+func TestDeleteGroupNotFound(t *testing.T) {
+	router, _ := setupTestRouter(t)
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("DELETE", "/groups/999", nil)
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
+
+func TestAddGroupWords(t *testing.T) {
+	router, _ := setupTestRouter(t)
+
+	w := httptest.NewRecorder()
+	body := strings.NewReader(`{"word_ids": [1, 2, 3]}`)
+	req, _ := http.NewRequest("POST", "/groups/1/words", body)
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var response map[string]int
+	err := json.Unmarshal(w.Body.Bytes(), &response)
+	assert.NoError(t, err)
+	assert.Equal(t, 3, response["added"])
+}
+
+func TestAddGroupWordsUnknownWord(t *testing.T) {
+	router, _ := setupTestRouter(t)
+
+	w := httptest.NewRecorder()
+	body := strings.NewReader(`{"word_ids": [1, 999]}`)
+	req, _ := http.NewRequest("POST", "/groups/1/words", body)
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Contains(t, w.Body.String(), "unknown word ids: 999")
+}
+
+func TestGroupWordsEmpty(t *testing.T) {
+	router, _ := setupTestRouter(t)
+
+	for _, method := range []string{"POST", "DELETE"} {
+		t.Run(method, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			req, _ := http.NewRequest(method, "/groups/1/words", strings.NewReader(`{"word_ids": []}`))
+			router.ServeHTTP(w, req)
+
+			assert.Equal(t, http.StatusBadRequest, w.Code)
+			assert.Contains(t, w.Body.String(), "must not be empty")
+		})
+	}
+}
+
+func TestSearchWords(t *testing.T) {
+	router, _ := setupTestRouter(t)
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/words/search?q=tesuto", nil)
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var response map[string]interface{}
+	err := json.Unmarshal(w.Body.Bytes(), &response)
+	assert.NoError(t, err)
+
+	items, ok := response["items"].([]interface{})
+	assert.True(t, ok)
+	assert.Equal(t, 1, len(items))
+}
+
+func TestSearchWordsRequiresQuery(t *testing.T) {
+	router, _ := setupTestRouter(t)
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/words/search", nil)
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
+
+func TestGetWordsWithSortAndFilters(t *testing.T) {
+	router, _ := setupTestRouter(t)
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/words?sort_by=wrong_count&order=desc&has_parts=false&accuracy_below=50", nil)
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+}
+
+func TestGetWordsWithInvalidParams(t *testing.T) {
+	router, _ := setupTestRouter(t)
+
+	for _, query := range []string{"sort_by=parts", "order=up", "never_reviewed=maybe", "accuracy_below=150"} {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("GET", "/words?"+query, nil)
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusBadRequest, w.Code, query)
+	}
+
+	// errors are listed in a fixed order
+	for i := 0; i < 10; i++ {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("GET", "/words?never_reviewed=maybe&has_parts=maybe&accuracy_below=150", nil)
+		router.ServeHTTP(w, req)
+
+		var response ErrorResponse
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+		var fields []string
+		for _, f := range response.Fields {
+			fields = append(fields, f.Field)
+		}
+		assert.Equal(t, []string{"has_parts", "never_reviewed", "accuracy_below"}, fields)
+	}
+}
+
+func TestPaginationParams(t *testing.T) {
+	router, _ := setupTestRouter(t)
+
+	for query, field := range map[string]string{
+		"page=0":       "page",
+		"page=-5":      "page",
+		"page=two":     "page",
+		"per_page=0":   "per_page",
+		"per_page=501": "per_page",
+		"cursor=bogus": "cursor",
+		"page=2&cursor=" + models.CursorAfter(time.Now(), 1).String(): "cursor",
+	} {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("GET", "/study_sessions?"+query, nil)
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusBadRequest, w.Code, query)
+		var response ErrorResponse
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+		if assert.Len(t, response.Fields, 1, query) {
+			assert.Equal(t, field, response.Fields[0].Field, query)
+		}
+	}
+
+	// listings without cursors refuse them rather than ignore them
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/words?cursor="+models.CursorAfter(time.Now(), 1).String(), nil)
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest("GET", "/words?page=3&per_page=25", nil)
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+	var response models.PaginatedResponse
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+	assert.Equal(t, 3, response.Pagination.CurrentPage)
+	assert.Equal(t, 25, response.Pagination.ItemsPerPage)
+}
+
+func TestCursorPagination(t *testing.T) {
+	router, _ := setupTestRouter(t)
+
+	// the first page of study sessions is numbered, so start those after one
+	for path, cursor := range map[string]string{
+		"/study_sessions": models.CursorAfter(time.Now(), 4).String(),
+		"/reviews":        "",
+	} {
+		var ids []float64
+		for pages := 0; pages < 3; pages++ {
+			w := httptest.NewRecorder()
+			req, _ := http.NewRequest("GET", path+"?per_page=2&cursor="+cursor, nil)
+			router.ServeHTTP(w, req)
+			if !assert.Equal(t, http.StatusOK, w.Code, path) {
+				break
+			}
+
+			var response struct {
+				Items      []map[string]interface{} `json:"items"`
+				Pagination *models.Pagination       `json:"pagination"`
+				NextCursor string                   `json:"next_cursor"`
+			}
+			assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+			for _, item := range response.Items {
+				ids = append(ids, item["id"].(float64))
+			}
+			assert.Nil(t, response.Pagination, "a page read by cursor has no page numbers")
+			if cursor = response.NextCursor; cursor == "" {
+				break
+			}
+		}
+		assert.Equal(t, []float64{3, 2, 1}, ids, path)
+	}
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/study_sessions?sort_by=review_count&cursor="+models.CursorAfter(time.Now(), 1).String(), nil)
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest("GET", "/reviews?page=2", nil)
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
+
+func TestErrorResponse(t *testing.T) {
+	for _, tt := range []struct {
+		err    error
+		status int
+		code   string
+	}{
+		{service.ErrWordNotFound, http.StatusNotFound, "word_not_found"},
+		{service.ErrGroupInUse, http.StatusConflict, "group_in_use"},
+		{service.ErrStudyActivityDisabled, http.StatusBadRequest, "study_activity_disabled"},
+		{service.ErrInvalidCredentials, http.StatusUnauthorized, "invalid_credentials"},
+		{service.ErrForbidden, http.StatusForbidden, "forbidden"},
+		{models.ErrMissingReference, http.StatusNotFound, "missing_reference"},
+		{models.ErrReferenced, http.StatusConflict, "in_use"},
+		{fmt.Errorf("%w (users.name)", models.ErrDuplicate), http.StatusConflict, "duplicate"},
+		{fmt.Errorf("%w (users.role)", models.ErrInvalidValue), http.StatusBadRequest, "invalid_value"},
+		{fmt.Errorf("saving: %w", models.ErrNotFound), http.StatusNotFound, "not_found"},
+		{models.ValidationErrors{{Field: "name", Message: "is required"}}, http.StatusBadRequest, "validation_failed"},
+		{fmt.Errorf("querying: %w", context.DeadlineExceeded), http.StatusServiceUnavailable, "timeout"},
+		{errors.New("disk I/O error"), http.StatusInternalServerError, "internal"},
+	} {
+		status, body := errorResponse(tt.err)
+		assert.Equal(t, tt.status, status, tt.err.Error())
+		assert.Equal(t, tt.code, body.Code, tt.err.Error())
+	}
+
+	// the message of a known error reaches the client, that of an unknown one does not
+	_, body := errorResponse(fmt.Errorf("%w (users.name)", models.ErrDuplicate))
+	assert.Equal(t, "already exists (users.name)", body.Message)
+	_, body = errorResponse(errors.New("no such table: words"))
+	assert.Equal(t, "internal server error", body.Message)
+
+	_, body = errorResponse(models.ValidationErrors{{Field: "name", Message: "is required"}})
+	assert.Equal(t, models.ValidationErrors{{Field: "name", Message: "is required"}}, body.Fields)
+}
+
+func TestErrorsMiddleware(t *testing.T) {
+	router, _ := setupTestRouter(t)
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/words/abc", nil)
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.JSONEq(t, `{
+		"code": "validation_failed",
+		"message": "validation failed: id: must be an integer",
+		"fields": [{"field": "id", "message": "must be an integer"}]
+	}`, w.Body.String())
+
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest("POST", "/words", bytes.NewBufferString(`{"japanese": `))
+	req.Header.Set("Content-Type", "application/json")
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Contains(t, w.Body.String(), `"code":"invalid_body"`)
+
+	// a handler that writes its own response is left alone
+	router.GET("/written", func(c *gin.Context) {
+		c.Error(errors.New("logged only"))
+		c.JSON(http.StatusOK, gin.H{"ok": true})
+	})
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest("GET", "/written", nil)
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.JSONEq(t, `{"ok": true}`, w.Body.String())
+}
+
+func TestRequestContextReachesDB(t *testing.T) {
+	router, _ := setupTestRouter(t)
+
+	cancelled, cancel := context.WithCancel(context.Background())
+	cancel()
+	expired, cancel := context.WithTimeout(context.Background(), -time.Second)
+	defer cancel()
+
+	for name, ctx := range map[string]context.Context{"cancelled": cancelled, "timed out": expired} {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequestWithContext(ctx, "GET", "/groups", nil)
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusServiceUnavailable, w.Code, name)
+		assert.JSONEq(t, `{"code": "timeout", "message": "request timed out"}`, w.Body.String(), name)
+	}
+}
+
+func TestGetGroupsWithInvalidSort(t *testing.T) {
+	router, _ := setupTestRouter(t)
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/groups?sort_by=japanese", nil)
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
+
+// importRequest builds a multipart upload for POST /import
+func importRequest(t *testing.T, fileName, content string, fields map[string]string) *http.Request {
+	var body bytes.Buffer
+	form := multipart.NewWriter(&body)
+	if fileName != "" {
+		part, err := form.CreateFormFile("file", fileName)
+		assert.NoError(t, err)
+		part.Write([]byte(content))
+	}
+	for k, v := range fields {
+		form.WriteField(k, v)
+	}
+	assert.NoError(t, form.Close())
+
+	req, _ := http.NewRequest("POST", "/import", &body)
+	req.Header.Set("Content-Type", form.FormDataContentType())
+	return req
+}
+
+func TestImportWordsCSV(t *testing.T) {
+	router, _ := setupTestRouter(t)
+
+	w := httptest.NewRecorder()
+	csv := "Kanji,romaji,english\n猫,neko,cat\n犬,,dog\n"
+	router.ServeHTTP(w, importRequest(t, "animals.csv", csv, map[string]string{
+		"group_name": "Animals",
+		"mapping":    `{"japanese": "Kanji"}`,
+	}))
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	var response struct {
+		Group  string `json:"group"`
+		Added  int    `json:"added"`
+		Rows   int    `json:"rows"`
+		Errors []struct {
+			Row   int    `json:"row"`
+			Field string `json:"field"`
+		} `json:"errors"`
+	}
+	err := json.Unmarshal(w.Body.Bytes(), &response)
+	assert.NoError(t, err)
+	assert.Equal(t, "Animals", response.Group)
+	assert.Equal(t, 1, response.Added)
+	assert.Equal(t, 2, response.Rows)
+	assert.Len(t, response.Errors, 1)
+	assert.Equal(t, 3, response.Errors[0].Row)
+	assert.Equal(t, "romaji", response.Errors[0].Field)
+}
+
+func TestImportWordsInvalidRequests(t *testing.T) {
+	router, _ := setupTestRouter(t)
+	csv := "japanese,romaji,english\n猫,neko,cat\n"
+
+	tests := []struct {
+		name     string
+		fileName string
+		fields   map[string]string
+		status   int
+	}{
+		{"missing file", "", map[string]string{"group_name": "Animals"}, http.StatusBadRequest},
+		{"missing group", "animals.csv", nil, http.StatusBadRequest},
+		{"unknown group", "animals.csv", map[string]string{"group_id": "999"}, http.StatusNotFound},
+		{"unsupported format", "animals.xlsx", map[string]string{"group_name": "Animals"}, http.StatusBadRequest},
+		{"bad mapping", "animals.csv", map[string]string{"group_name": "Animals", "mapping": "[]"}, http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, importRequest(t, tt.fileName, csv, tt.fields))
+			assert.Equal(t, tt.status, w.Code)
+		})
+	}
+}
+
+func TestImportWordsTooLarge(t *testing.T) {
+	svc := service.NewService(&MockDB{})
+	handler := NewHandler(svc, auth.NewTokens([]byte("test secret"), 0))
+	handler.SetMaxImportSize(512)
+	router := gin.New()
+	router.Use(Errors(), actAsTestUser(svc))
+	router.POST("/import", handler.ImportWords)
+
+	fields := map[string]string{"group_name": "Animals"}
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, importRequest(t, "animals.csv", "japanese,romaji,english\n猫,neko,cat\n", fields))
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	w = httptest.NewRecorder()
+	big := "japanese,romaji,english\n" + strings.Repeat("猫,neko,cat\n", 100)
+	router.ServeHTTP(w, importRequest(t, "animals.csv", big, fields))
+	assert.Equal(t, http.StatusRequestEntityTooLarge, w.Code)
+	var response ErrorResponse
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+	assert.Equal(t, "body_too_large", response.Code)
+}
+
+func TestExportGroupWordsJSON(t *testing.T) {
+	router, _ := setupTestRouter(t)
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/groups/1/export?format=json", nil)
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Header().Get("Content-Disposition"), "group-1.json")
+
+	var response struct {
+		Group struct {
+			Name string `json:"name"`
+		} `json:"group"`
+		Words []map[string]interface{} `json:"words"`
+	}
+	err := json.Unmarshal(w.Body.Bytes(), &response)
+	assert.NoError(t, err)
+	assert.Equal(t, "Test Group", response.Group.Name)
+	assert.Len(t, response.Words, 2)
+}
+
+func TestExportGroupWordsCSV(t *testing.T) {
+	router, _ := setupTestRouter(t)
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/groups/1/export", nil)
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "text/csv; charset=utf-8", w.Header().Get("Content-Type"))
+	lines := strings.Split(strings.TrimSpace(w.Body.String()), "\n")
+	assert.Len(t, lines, 3)
+	assert.True(t, strings.HasPrefix(lines[0], "japanese,romaji,english,parts"))
+}
+
+func TestExportGroupWordsErrors(t *testing.T) {
+	router, _ := setupTestRouter(t)
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/groups/999/export", nil)
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest("GET", "/groups/1/export?format=xlsx", nil)
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
+
+func TestExportOutlastsWriteTimeout(t *testing.T) {
+	router, _ := setupTestRouter(t)
+
+	// the export only starts writing after the server's write timeout
+	slow := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(200 * time.Millisecond)
+		router.ServeHTTP(w, r)
+	})
+	server := httptest.NewUnstartedServer(slow)
+	server.Config.WriteTimeout = 50 * time.Millisecond
+	server.Start()
+	defer server.Close()
+
+	for _, path := range []string{"/groups/1/export", "/study_sessions/export"} {
+		resp, err := server.Client().Get(server.URL + path)
+		if !assert.NoError(t, err, path) {
+			continue
+		}
+		body, err := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		assert.NoError(t, err, path)
+		assert.Equal(t, http.StatusOK, resp.StatusCode, path)
+		assert.NotEmpty(t, body, path)
+	}
+}
+
+func TestExportReviews(t *testing.T) {
+	router, _ := setupTestRouter(t)
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/study_sessions/export?format=json&group_id=1", nil)
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	var response []map[string]interface{}
+	err := json.Unmarshal(w.Body.Bytes(), &response)
+	assert.NoError(t, err)
+	assert.Len(t, response, 1)
+	assert.Equal(t, "good", response[0]["grade"])
+
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest("GET", "/study_sessions/export?format=apkg", nil)
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
+
+func TestGetStudySessionNotFound(t *testing.T) {
+	router, _ := setupTestRouter(t)
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/study_sessions/999", nil)
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
+
+func TestEndStudySession(t *testing.T) {
+	router, _ := setupTestRouter(t)
+
+	end := func(path string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("POST", path, nil)
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	w := end("/study_sessions/1/end")
+	assert.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	var summary models.StudySessionSummary
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &summary))
+	assert.Equal(t, models.StudySessionCompleted, summary.Status)
+	assert.Equal(t, int64(600), summary.DurationSeconds)
+	assert.Equal(t, 75.0, summary.Accuracy)
+	assert.Equal(t, 3, summary.WordsSeen)
+	assert.Equal(t, 2, summary.NewWords)
+	assert.Equal(t, 1, summary.RepeatedWords)
+
+	w = end("/study_sessions/4/end")
+	assert.Equal(t, http.StatusConflict, w.Code)
+	assert.JSONEq(t, `{"code": "study_session_ended", "message": "study session has already ended"}`, w.Body.String())
+
+	assert.Equal(t, http.StatusNotFound, end("/study_sessions/999/end").Code)
+	assert.Equal(t, http.StatusNotFound, end("/study_sessions/3/end").Code, "cannot end another user's session")
+	assert.Equal(t, http.StatusBadRequest, end("/study_sessions/abc/end").Code)
+
+	w = httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/study_sessions/4/summary", nil)
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+}
+
+func TestReviewWordChecksSession(t *testing.T) {
+	router, svc := setupTestRouter(t)
+
+	review := func(path string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("POST", path, bytes.NewBufferString(`{"grade": "good"}`))
+		req.Header.Set("Content-Type", "application/json")
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	w := review("/study_sessions/1/words/5/review")
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.JSONEq(t, `{"code": "word_not_in_group", "message": "word is not in the study session's group"}`, w.Body.String())
+
+	w = review("/study_sessions/4/words/2/review")
+	assert.Equal(t, http.StatusConflict, w.Code)
+	assert.JSONEq(t, `{"code": "study_session_ended", "message": "study session has already ended"}`, w.Body.String())
+
+	w = review("/study_sessions/1/words/999/review")
+	assert.Equal(t, http.StatusNotFound, w.Code)
+	assert.Contains(t, w.Body.String(), "word_not_found")
+
+	svc.SetCrossGroupReviews(true)
+	assert.Equal(t, http.StatusCreated, review("/study_sessions/1/words/5/review").Code)
+}
+
+func postStatements(router *gin.Engine, body string) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("POST", "/xapi/statements", bytes.NewBufferString(body))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Experience-API-Version", "1.0.3")
+	router.ServeHTTP(w, req)
+	return w
+}
+
+func TestPostStatements(t *testing.T) {
+	router, _ := setupTestRouter(t)
+
+	answered := `{
+		"actor": {"mbox": "mailto:learner@example.com"},
+		"verb": {"id": "http://adlnet.gov/expapi/verbs/answered"},
+		"object": {"id": "https://app.example.com/api/words/1"},
+		"result": {"success": true, "response": "cat", "duration": "PT1.5S"},
+		"context": {"registration": "` + knownRegistration + `"}
+	}`
+	w := postStatements(router, answered)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "1.0.3", w.Header().Get("X-Experience-API-Version"))
+	var ids []string
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &ids))
+	assert.Len(t, ids, 1)
+	assert.Len(t, ids[0], 36, "a missing id is generated")
+
+	// A batch that repeats a recorded statement and starts a new registration
+	batch := `[
+		{
+			"id": "` + recordedStatementID + `",
+			"actor": {"mbox": "mailto:learner@example.com"},
+			"verb": {"id": "http://adlnet.gov/expapi/verbs/answered"},
+			"object": {"id": "https://app.example.com/api/words/1"},
+			"result": {"extensions": {"urn:lang-portal:extension:grade": "hard"}},
+			"context": {"contextActivities": {"parent": {"id": "https://app.example.com/api/study_sessions/1"}}}
+		},
+		{
+			"actor": {"mbox": "mailto:learner@example.com"},
+			"verb": {"id": "http://adlnet.gov/expapi/verbs/completed"},
+			"object": {"id": "https://app.example.com/api/groups/1"},
+			"context": {
+				"registration": "0b9e5c1d-2f3a-4b6c-8d7e-9f0a1b2c3d4e",
+				"contextActivities": {"grouping": [
+					{"id": "https://app.example.com/api/groups/1"},
+					{"id": "https://app.example.com/api/study_activities/1"}
+				]}
+			}
+		}
+	]`
+	w = postStatements(router, batch)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &ids))
+	assert.Len(t, ids, 2)
+	assert.Equal(t, recordedStatementID, ids[0])
+}
+
+func TestResetHistory(t *testing.T) {
+	router, _ := setupTestRouter(t)
+
+	for query, want := range map[string]int{
+		"":                                    http.StatusOK,
+		"?user_id=2":                          http.StatusOK,
+		"?user_id=" + strconv.Itoa(missingID): http.StatusNotFound,
+		"?user_id=ken":                        http.StatusBadRequest,
+	} {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("POST", "/reset_history"+query, nil)
+		router.ServeHTTP(w, req)
+		assert.Equal(t, want, w.Code, query)
+	}
+}
+
+func TestPostStatementsTooLarge(t *testing.T) {
+	router, _ := setupTestRouter(t)
+
+	w := postStatements(router, `["`+strings.Repeat("a", maxStatementsSize)+`"]`)
+	assert.Equal(t, http.StatusRequestEntityTooLarge, w.Code)
+	var response ErrorResponse
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+	assert.Equal(t, "body_too_large", response.Code)
+}
+
+func TestPostStatementsAgainAfterSessionEnds(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	db := models.NewDB(testdb.Open(t, models.DSN))
+	ctx := context.Background()
+	assert.NoError(t, db.InitSearchIndex(ctx))
+	word, err := db.CreateWord(ctx, &models.Word{Japanese: "猫", Romaji: "neko", English: "cat"})
+	assert.NoError(t, err)
+	group, err := db.CreateGroup(ctx, "Animals")
+	assert.NoError(t, err)
+	_, err = db.AddWordsToGroup(ctx, group.ID, []int64{word.ID})
+	assert.NoError(t, err)
+	activity, err := db.CreateStudyActivity(ctx, &models.StudyActivity{Name: "Cards", LaunchURL: "http://localhost", Enabled: true})
+	assert.NoError(t, err)
+
+	svc := service.NewService(db)
+	svc.SetActivityBase("https://app.example.com/api")
+	handler := NewHandler(svc, auth.NewTokens([]byte("test secret"), 0))
+	router := gin.New()
+	router.Use(Errors(), actAsTestUser(svc))
+	router.POST("/xapi/statements", handler.PostStatements)
+
+	stCtx := fmt.Sprintf(`{
+		"registration": "0b9e5c1d-2f3a-4b6c-8d7e-9f0a1b2c3d4e",
+		"contextActivities": {"grouping": [
+			{"id": "https://app.example.com/api/groups/%d"},
+			{"id": "https://app.example.com/api/study_activities/%d"}
+		]}
+	}`, group.ID, activity.ID)
+	batch := fmt.Sprintf(`[
+		{
+			"id": "4a1b2c3d-0000-4000-8000-0000000000a1",
+			"actor": {"mbox": "mailto:learner@example.com"},
+			"verb": {"id": "http://adlnet.gov/expapi/verbs/answered"},
+			"object": {"id": "https://app.example.com/api/words/%d"},
+			"result": {"success": true},
+			"context": %s
+		},
+		{
+			"actor": {"mbox": "mailto:learner@example.com"},
+			"verb": {"id": "http://adlnet.gov/expapi/verbs/completed"},
+			"object": {"id": "https://app.example.com/api/groups/%d"},
+			"context": %s
+		}
+	]`, word.ID, stCtx, group.ID, stCtx)
+
+	for _, attempt := range []string{"first", "again"} {
+		w := postStatements(router, batch)
+		assert.Equal(t, http.StatusOK, w.Code, "%s: %s", attempt, w.Body.String())
+	}
+	var reviews int
+	assert.NoError(t, db.QueryRow("SELECT COUNT(*) FROM word_review_items").Scan(&reviews))
+	assert.Equal(t, 1, reviews, "the answer is recorded once")
+}
+
+func TestPostStatementsInvalid(t *testing.T) {
+	router, _ := setupTestRouter(t)
+
+	tests := []struct {
+		name      string
+		body      string
+		wantField string
+	}{
+		{
+			name: "unsupported verb",
+			body: `{"actor": {"name": "x"}, "verb": {"id": "http://adlnet.gov/expapi/verbs/experienced"},
+				"object": {"id": "https://app.example.com/api/words/1"}}`,
+			wantField: "statements[0].verb.id",
+		},
+		{
+			name: "word under another base",
+			body: `{"actor": {"name": "x"}, "verb": {"id": "http://adlnet.gov/expapi/verbs/answered"},
+				"object": {"id": "https://evil.example.com/api/words/1"}, "result": {"success": true},
+				"context": {"registration": "` + knownRegistration + `"}}`,
+			wantField: "statements[0].object.id",
+		},
+		{
+			name: "completed activity under another base",
+			body: `{"actor": {"name": "x"}, "verb": {"id": "http://adlnet.gov/expapi/verbs/completed"},
+				"object": {"id": "https://app.example.com/lessons/7"},
+				"context": {"registration": "` + knownRegistration + `"}}`,
+			wantField: "statements[0].object.id",
+		},
+		{
+			name: "unknown word",
+			body: `{"actor": {"name": "x"}, "verb": {"id": "http://adlnet.gov/expapi/verbs/answered"},
+				"object": {"id": "https://app.example.com/api/words/999"}, "result": {"success": true},
+				"context": {"registration": "` + knownRegistration + `"}}`,
+			wantField: "statements[0].object.id",
+		},
+		{
+			name: "invalid grade",
+			body: `{"actor": {"name": "x"}, "verb": {"id": "http://adlnet.gov/expapi/verbs/answered"},
+				"object": {"id": "https://app.example.com/api/words/1"},
+				"result": {"extensions": {"urn:lang-portal:extension:grade": "perfect"}},
+				"context": {"registration": "` + knownRegistration + `"}}`,
+			wantField: "statements[0].result.extensions",
+		},
+		{
+			name: "word outside the session's group",
+			body: `{"actor": {"name": "x"}, "verb": {"id": "http://adlnet.gov/expapi/verbs/answered"},
+				"object": {"id": "https://app.example.com/api/words/5"}, "result": {"success": true},
+				"context": {"registration": "` + knownRegistration + `"}}`,
+			wantField: "statements[0].object.id",
+		},
+		{
+			name: "ended session",
+			body: `{"actor": {"name": "x"}, "verb": {"id": "http://adlnet.gov/expapi/verbs/answered"},
+				"object": {"id": "https://app.example.com/api/words/1"}, "result": {"success": true},
+				"context": {"contextActivities": {"parent": {"id": "https://app.example.com/api/study_sessions/4"}}}}`,
+			wantField: "statements[0].context",
+		},
+		{
+			name: "answer after completing",
+			body: `[{"actor": {"name": "x"}, "verb": {"id": "http://adlnet.gov/expapi/verbs/completed"},
+				"object": {"id": "https://app.example.com/api/study_sessions/1"}},
+				{"actor": {"name": "x"}, "verb": {"id": "http://adlnet.gov/expapi/verbs/answered"},
+				"object": {"id": "https://app.example.com/api/words/1"}, "result": {"success": true},
+				"context": {"registration": "` + knownRegistration + `"}}]`,
+			wantField: "statements[1].context",
+		},
+		{
+			name: "new registration without a group",
+			body: `{"actor": {"name": "x"}, "verb": {"id": "http://adlnet.gov/expapi/verbs/completed"},
+				"object": {"id": "https://app.example.com/api/study_activities/1"},
+				"context": {"registration": "0b9e5c1d-2f3a-4b6c-8d7e-9f0a1b2c3d4e"}}`,
+			wantField: "statements[0].context.contextActivities",
+		},
+		{
+			name: "missing session",
+			body: `[{"actor": {"name": "x"}, "verb": {"id": "http://adlnet.gov/expapi/verbs/completed"},
+				"object": {"id": "https://app.example.com/api/study_sessions/1"}},
+				{"actor": {"name": "x"}, "verb": {"id": "http://adlnet.gov/expapi/verbs/completed"},
+				"object": {"id": "https://app.example.com/api/study_sessions/999"}}]`,
+			wantField: "statements[1].context",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := postStatements(router, tt.body)
+			assert.Equal(t, http.StatusBadRequest, w.Code)
+			var response struct {
+				Fields []models.ValidationError `json:"fields"`
+			}
+			assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+			if assert.Len(t, response.Fields, 1) {
+				assert.Equal(t, tt.wantField, response.Fields[0].Field)
+			}
+		})
+	}
+
+	w := postStatements(router, `"not a statement"`)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
+
+func TestGetStatements(t *testing.T) {
+	router, _ := setupTestRouter(t)
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/xapi/statements?limit=2", nil)
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var result struct {
+		Statements []map[string]interface{} `json:"statements"`
+		More       string                   `json:"more"`
+	}
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &result))
+	assert.Len(t, result.Statements, 2)
+	assert.Equal(t, "/xapi/statements?cursor=2&limit=2", result.More)
+	first := result.Statements[0]
+	assert.Equal(t, recordedStatementID, first["id"])
+	assert.Equal(t, "https://app.example.com/api/words/1", first["object"].(map[string]interface{})["id"])
+	generatedID := result.Statements[1]["id"].(string)
+
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest("GET", result.More, nil)
+	router.ServeHTTP(w, req)
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &result))
+	assert.Len(t, result.Statements, 1)
+	assert.Empty(t, result.More)
+
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest("GET", "/xapi/statements?statementId="+generatedID, nil)
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+	var statement map[string]interface{}
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &statement))
+	assert.Equal(t, generatedID, statement["id"])
+
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest("GET", "/xapi/statements?statementId=4a1b2c3d-0000-4000-8000-000000000001", nil)
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest("GET", "/xapi/statements?since=yesterday", nil)
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
+
+func TestStudySessionsAreScopedToUser(t *testing.T) {
+	router, _ := setupTestRouter(t)
+
+	get := func(path, userID string) int {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("GET", path, nil)
+		if userID != "" {
+			req.Header.Set(testUserHeader, userID)
+		}
+		router.ServeHTTP(w, req)
+		return w.Code
+	}
+
+	assert.Equal(t, http.StatusOK, get("/study_sessions/1", ""))
+	assert.Equal(t, http.StatusNotFound, get("/study_sessions/3", ""), "other users' sessions are hidden, even from admins")
+	assert.Equal(t, http.StatusOK, get("/study_sessions/3", "2"))
+	assert.Equal(t, http.StatusNotFound, get("/study_sessions/1", "2"))
+
+	w := httptest.NewRecorder()
+	body := bytes.NewBufferString(`{"grade": "good"}`)
+	req, _ := http.NewRequest("POST", "/study_sessions/3/words/2/review", body)
+	req.Header.Set("Content-Type", "application/json")
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusNotFound, w.Code, "cannot review in another user's session")
+}
+
+func TestUsers(t *testing.T) {
+	router, _ := setupTestRouter(t)
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/users", nil)
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest("GET", "/users/999", nil)
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+
+	tests := []struct {
+		name       string
+		body       string
+		wantStatus int
+	}{
+		{"new user", `{"name": " ken "}`, http.StatusCreated},
+		{"name taken", `{"name": "hana"}`, http.StatusConflict},
+		{"blank name", `{"name": "  "}`, http.StatusBadRequest},
+		{"unknown role", `{"name": "ken", "role": "principal"}`, http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			req, _ := http.NewRequest("POST", "/users", bytes.NewBufferString(tt.body))
+			req.Header.Set("Content-Type", "application/json")
+			router.ServeHTTP(w, req)
+			assert.Equal(t, tt.wantStatus, w.Code)
+			if tt.wantStatus == http.StatusCreated {
+				var user models.User
+				assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &user))
+				assert.Equal(t, "ken", user.Name)
+			}
+		})
+	}
+}
+
+// setupAuthRouter returns a router that signs requests in with real
+// credentials, with a route for each role
+func setupAuthRouter(t *testing.T) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	svc := service.NewService(&MockDB{})
+	handler := NewHandler(svc, auth.NewTokens([]byte("test secret"), 0))
+
+	router := gin.New()
+	router.Use(Errors())
+	router.POST("/auth/login", handler.Login)
+	api := router.Group("", handler.Authenticate)
+	api.GET("/auth/me", handler.GetCurrentUser)
+	api.PATCH("/users/:id", handler.UpdateUser)
+	api.POST("/words", Require(models.RoleTeacher), handler.CreateWord)
+	api.POST("/full_reset", Require(models.RoleAdmin), handler.FullReset)
+	api.POST("/users/:id/api_keys", Require(models.RoleAdmin), handler.CreateAPIKey)
+	api.DELETE("/users/:id/api_keys/:key_id", Require(models.RoleAdmin), handler.DeleteAPIKey)
+	return router
+}
+
+func TestAuthenticate(t *testing.T) {
+	router := setupAuthRouter(t)
+
+	w := httptest.NewRecorder()
+	body := fmt.Sprintf(`{"name": %q, "password": %q}`, takenUserName, takenUserPassword)
+	req, _ := http.NewRequest("POST", "/auth/login", bytes.NewBufferString(body))
+	req.Header.Set("Content-Type", "application/json")
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+	var login struct {
+		Token string      `json:"token"`
+		User  models.User `json:"user"`
+	}
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &login))
+	assert.NotEmpty(t, login.Token)
+	assert.Equal(t, int64(otherUserID), login.User.ID)
+	assert.NotContains(t, w.Body.String(), "password")
+
+	tests := []struct {
+		name       string
+		header     string
+		value      string
+		wantStatus int
+		wantUserID int64
+	}{
+		{"no credentials", "", "", http.StatusUnauthorized, 0},
+		{"api key", APIKeyHeader, "teacher-key", http.StatusOK, teacherID},
+		{"unknown api key", APIKeyHeader, "stolen-key", http.StatusUnauthorized, 0},
+		{"session token", "Authorization", "Bearer " + login.Token, http.StatusOK, otherUserID},
+		{"tampered token", "Authorization", "Bearer " + login.Token + "x", http.StatusUnauthorized, 0},
+		{"token signed elsewhere", "Authorization", "Bearer " + otherToken(t), http.StatusUnauthorized, 0},
+		{"not a bearer token", "Authorization", "Basic aGFuYTpwdw==", http.StatusUnauthorized, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			req, _ := http.NewRequest("GET", "/auth/me", nil)
+			if tt.header != "" {
+				req.Header.Set(tt.header, tt.value)
+			}
+			router.ServeHTTP(w, req)
+			assert.Equal(t, tt.wantStatus, w.Code)
+			if tt.wantStatus == http.StatusOK {
+				var user models.User
+				assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &user))
+				assert.Equal(t, tt.wantUserID, user.ID)
+			} else {
+				assert.NotEmpty(t, w.Header().Get("WWW-Authenticate"))
+			}
+		})
+	}
+}
+
+// otherToken returns a token for user 1 signed with a different secret
+func otherToken(t *testing.T) string {
+	token, _, err := auth.NewTokens([]byte("another secret"), 0).Issue(1)
+	assert.NoError(t, err)
+	return token
+}
+
+func TestLoginFails(t *testing.T) {
+	router := setupAuthRouter(t)
+
+	tests := []struct {
+		name       string
+		body       string
+		wantStatus int
+	}{
+		{"wrong password", `{"name": "hana", "password": "incorrect"}`, http.StatusUnauthorized},
+		{"unknown user", `{"name": "ken", "password": "correct horse"}`, http.StatusUnauthorized},
+		{"missing password", `{"name": "hana"}`, http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			req, _ := http.NewRequest("POST", "/auth/login", bytes.NewBufferString(tt.body))
+			req.Header.Set("Content-Type", "application/json")
+			router.ServeHTTP(w, req)
+			assert.Equal(t, tt.wantStatus, w.Code)
+		})
+	}
+}
+
+func TestRoutePolicies(t *testing.T) {
+	router := setupAuthRouter(t)
+
+	tests := []struct {
+		name       string
+		key        string
+		method     string
+		path       string
+		body       string
+		wantStatus int
+	}{
+		{"learner cannot edit vocabulary", "learner-key", "POST", "/words", `{"japanese": "猫", "romaji": "neko", "english": "cat"}`, http.StatusForbidden},
+		{"teacher edits vocabulary", "teacher-key", "POST", "/words", `{"japanese": "猫", "romaji": "neko", "english": "cat"}`, http.StatusCreated},
+		{"admin edits vocabulary", "admin-key", "POST", "/words", `{"japanese": "猫", "romaji": "neko", "english": "cat"}`, http.StatusCreated},
+		{"teacher cannot reset", "teacher-key", "POST", "/full_reset", "", http.StatusForbidden},
+		{"admin resets", "admin-key", "POST", "/full_reset", "", http.StatusOK},
+		{"learner cannot make api keys", "learner-key", "POST", "/users/2/api_keys", `{"name": "flashcards"}`, http.StatusForbidden},
+		{"admin makes api keys", "admin-key", "POST", "/users/2/api_keys", `{"name": "flashcards"}`, http.StatusCreated},
+		{"unknown api key id", "admin-key", "DELETE", "/users/2/api_keys/7", "", http.StatusNotFound},
+		{"learner sets own password", "learner-key", "PATCH", "/users/2", `{"password": "new password", "current_password": "correct horse"}`, http.StatusOK},
+		{"learner needs current password", "learner-key", "PATCH", "/users/2", `{"password": "new password"}`, http.StatusBadRequest},
+		{"learner gives wrong current password", "learner-key", "PATCH", "/users/2", `{"password": "new password", "current_password": "incorrect"}`, http.StatusForbidden},
+		{"teacher cannot set a first password", "teacher-key", "PATCH", "/users/4", `{"password": "new password"}`, http.StatusForbidden},
+		{"admin sets others' passwords", "admin-key", "PATCH", "/users/2", `{"password": "new password"}`, http.StatusOK},
+		{"learner cannot change own role", "learner-key", "PATCH", "/users/2", `{"role": "admin"}`, http.StatusForbidden},
+		{"learner cannot change others", "learner-key", "PATCH", "/users/4", `{"password": "new password"}`, http.StatusForbidden},
+		{"admin changes roles", "admin-key", "PATCH", "/users/2", `{"role": "teacher"}`, http.StatusOK},
+		{"short password", "admin-key", "PATCH", "/users/2", `{"password": "short"}`, http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			req, _ := http.NewRequest(tt.method, tt.path, bytes.NewBufferString(tt.body))
+			req.Header.Set("Content-Type", "application/json")
+			req.Header.Set(APIKeyHeader, tt.key)
+			router.ServeHTTP(w, req)
+			assert.Equal(t, tt.wantStatus, w.Code, w.Body.String())
+		})
+	}
+}
+
+func TestCreateAPIKeyShowsKeyOnce(t *testing.T) {
+	router := setupAuthRouter(t)
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("POST", "/users/2/api_keys", bytes.NewBufferString(`{"name": "flashcards"}`))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(APIKeyHeader, "admin-key")
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusCreated, w.Code)
+
+	var key models.APIKey
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &key))
+	assert.True(t, strings.HasPrefix(key.Key, "lp_"), key.Key)
+	assert.Equal(t, int64(otherUserID), key.UserID)
+}
+
+func TestCORS(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(CORS([]string{"https://apps.example.com/"}))
+	router.GET("/words", func(c *gin.Context) { c.Status(http.StatusOK) })
+
+	tests := []struct {
+		name        string
+		method      string
+		origin      string
+		wantStatus  int
+		wantAllowed bool
+	}{
+		{"allowed origin", "GET", "https://apps.example.com", http.StatusOK, true},
+		{"other origin", "GET", "https://evil.example.com", http.StatusOK, false},
+		{"same origin", "GET", "", http.StatusOK, false},
+		{"preflight", "OPTIONS", "https://apps.example.com", http.StatusNoContent, true},
+		{"preflight from other origin", "OPTIONS", "https://evil.example.com", http.StatusNotFound, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			req, _ := http.NewRequest(tt.method, "/words", nil)
+			if tt.origin != "" {
+				req.Header.Set("Origin", tt.origin)
+			}
+			if tt.method == "OPTIONS" {
+				req.Header.Set("Access-Control-Request-Method", "POST")
+			}
+			router.ServeHTTP(w, req)
+			assert.Equal(t, tt.wantStatus, w.Code)
+			if tt.wantAllowed {
+				assert.Equal(t, tt.origin, w.Header().Get("Access-Control-Allow-Origin"))
+			} else {
+				assert.Empty(t, w.Header().Get("Access-Control-Allow-Origin"))
+			}
+			if tt.method == "OPTIONS" && tt.wantAllowed {
+				assert.Contains(t, w.Header().Get("Access-Control-Allow-Headers"), APIKeyHeader)
+			}
+		})
+	}
+}
+
+func TestLogger(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	var out bytes.Buffer
+	router := gin.New()
+	router.Use(Logger(slog.New(slog.NewTextHandler(&out, &slog.HandlerOptions{Level: slog.LevelWarn}))))
+	router.GET("/ok", func(c *gin.Context) { c.Status(http.StatusOK) })
+	router.GET("/fail", func(c *gin.Context) { c.Status(http.StatusInternalServerError) })
+
+	for _, path := range []string{"/ok", "/fail"} {
+		req, _ := http.NewRequest("GET", path, nil)
+		router.ServeHTTP(httptest.NewRecorder(), req)
+	}
+	assert.NotContains(t, out.String(), "path=/ok", "info is below the log level")
+	assert.Contains(t, out.String(), "level=ERROR msg=request method=GET path=/fail status=500")
+}
@@ -0,0 +1,148 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/gen-ai-bootcamp-2025/backend_go/internal/models"
+	"github.com/gen-ai-bootcamp-2025/backend_go/internal/service"
+	"github.com/gin-gonic/gin"
+)
+
+// GetUsers returns a paginated list of users
+func (h *Handler) GetUsers(c *gin.Context) {
+	page, err := parsePage(c)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+	response, err := h.svc.GetUsers(c.Request.Context(), page)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, response)
+}
+
+// GetUser returns a specific user
+func (h *Handler) GetUser(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		respondError(c, invalidField("id", "must be an integer"))
+		return
+	}
+
+	user, err := h.svc.GetUser(c.Request.Context(), id)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, user)
+}
+
+// CreateUser adds a user, a learner unless a role is given
+func (h *Handler) CreateUser(c *gin.Context) {
+	var req struct {
+		Name string      `json:"name"`
+		Role models.Role `json:"role"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondError(c, invalidBody(err))
+		return
+	}
+
+	user, err := h.svc.CreateUser(c.Request.Context(), req.Name, req.Role)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+	c.JSON(http.StatusCreated, user)
+}
+
+// UpdateUser changes the role or password of a user
+func (h *Handler) UpdateUser(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		respondError(c, invalidField("id", "must be an integer"))
+		return
+	}
+
+	var req struct {
+		Role            *models.Role `json:"role"`
+		Password        *string      `json:"password"`
+		CurrentPassword *string      `json:"current_password"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondError(c, invalidBody(err))
+		return
+	}
+
+	patch := service.UserPatch{Role: req.Role, Password: req.Password, CurrentPassword: req.CurrentPassword}
+	user, err := h.svc.UpdateUser(c.Request.Context(), id, patch)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, user)
+}
+
+// GetAPIKeys lists the API keys of a user
+func (h *Handler) GetAPIKeys(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		respondError(c, invalidField("id", "must be an integer"))
+		return
+	}
+
+	keys, err := h.svc.GetAPIKeys(c.Request.Context(), id)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"items": keys})
+}
+
+// CreateAPIKey makes an API key for a learning app to act for a user. The
+// response is the only time the key is shown.
+func (h *Handler) CreateAPIKey(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		respondError(c, invalidField("id", "must be an integer"))
+		return
+	}
+
+	var req struct {
+		Name string `json:"name"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondError(c, invalidBody(err))
+		return
+	}
+
+	key, err := h.svc.CreateAPIKey(c.Request.Context(), id, req.Name)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+	c.JSON(http.StatusCreated, key)
+}
+
+// DeleteAPIKey revokes an API key
+func (h *Handler) DeleteAPIKey(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		respondError(c, invalidField("id", "must be an integer"))
+		return
+	}
+	keyID, err := strconv.ParseInt(c.Param("key_id"), 10, 64)
+	if err != nil {
+		respondError(c, invalidField("key_id", "must be an integer"))
+		return
+	}
+
+	if err := h.svc.DeleteAPIKey(c.Request.Context(), id, keyID); err != nil {
+		respondError(c, err)
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
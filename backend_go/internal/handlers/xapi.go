@@ -0,0 +1,121 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gen-ai-bootcamp-2025/backend_go/internal/service"
+	"github.com/gen-ai-bootcamp-2025/backend_go/internal/xapi"
+	"github.com/gin-gonic/gin"
+)
+
+// maxStatementsSize is the largest request body, in bytes, PostStatements
+// reads; larger batches are refused with 413
+const maxStatementsSize = 4 << 20
+
+// xapiHeaders answers with the xAPI version and rejects clients speaking a
+// version other than 1.0.x
+func xapiHeaders(c *gin.Context) bool {
+	c.Header("X-Experience-API-Version", xapi.Version)
+	if v := c.GetHeader("X-Experience-API-Version"); v != "" && !strings.HasPrefix(v, "1.0") {
+		respondError(c, invalidField("X-Experience-API-Version", "must be 1.0.x"))
+		return false
+	}
+	return true
+}
+
+// PostStatements records a single xAPI statement or an array of them and
+// returns their ids
+func (h *Handler) PostStatements(c *gin.Context) {
+	if !xapiHeaders(c) {
+		return
+	}
+
+	body, err := io.ReadAll(http.MaxBytesReader(c.Writer, c.Request.Body, maxStatementsSize))
+	var tooLarge *http.MaxBytesError
+	if errors.As(err, &tooLarge) {
+		respondError(c, err)
+		return
+	}
+	if err != nil {
+		respondError(c, invalidBody(err))
+		return
+	}
+	statements, err := xapi.ParseStatements(body)
+	if err != nil {
+		respondError(c, invalidBody(fmt.Errorf("body must be a statement or an array of statements: %v", err)))
+		return
+	}
+
+	ids, err := h.svc.RecordStatements(c.Request.Context(), statements)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, ids)
+}
+
+// GetStatements returns a single statement by statementId, or a page of
+// statements with a "more" link to the next page
+func (h *Handler) GetStatements(c *gin.Context) {
+	if !xapiHeaders(c) {
+		return
+	}
+
+	if id := c.Query("statementId"); id != "" {
+		statement, err := h.svc.GetStatement(c.Request.Context(), id)
+		if err != nil {
+			respondError(c, err)
+			return
+		}
+		c.JSON(http.StatusOK, statement)
+		return
+	}
+
+	var query service.StatementQuery
+	var err error
+	if query.Since, err = parseTimeQuery(c.Query("since"), false); err != nil {
+		respondError(c, invalidField("since", "must be an RFC 3339 timestamp or a YYYY-MM-DD date"))
+		return
+	}
+	if query.Until, err = parseTimeQuery(c.Query("until"), false); err != nil {
+		respondError(c, invalidField("until", "must be an RFC 3339 timestamp or a YYYY-MM-DD date"))
+		return
+	}
+	if v := c.Query("limit"); v != "" {
+		if query.Limit, err = strconv.Atoi(v); err != nil || query.Limit < 0 {
+			respondError(c, invalidField("limit", "must be a whole number"))
+			return
+		}
+	}
+	if v := c.Query("ascending"); v != "" {
+		if query.Ascending, err = strconv.ParseBool(v); err != nil {
+			respondError(c, invalidField("ascending", "must be true or false"))
+			return
+		}
+	}
+	if v := c.Query("cursor"); v != "" {
+		if query.Cursor, err = strconv.ParseInt(v, 10, 64); err != nil {
+			respondError(c, invalidField("cursor", "must be an integer"))
+			return
+		}
+	}
+
+	page, err := h.svc.GetStatements(c.Request.Context(), query)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	result := xapi.StatementResult{Statements: page.Statements}
+	if page.Next != 0 {
+		params := c.Request.URL.Query()
+		params.Set("cursor", strconv.FormatInt(page.Next, 10))
+		result.More = c.Request.URL.Path + "?" + params.Encode()
+	}
+	c.JSON(http.StatusOK, result)
+}
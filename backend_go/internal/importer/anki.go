@@ -0,0 +1,192 @@
+package importer
+
+import (
+	"archive/zip"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"html"
+	"io"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+
+	_ "github.com/mattn/go-sqlite3"
+)
+
+// An .apkg file is a zip holding the Anki collection as a SQLite database.
+// Each note stores its field values in notes.flds separated by 0x1f, in the
+// order of its note type's fields. Older collections keep note types as JSON
+// in col.models, newer ones in the notetypes and fields tables.
+
+// ankiFieldSeparator separates field values in notes.flds
+const ankiFieldSeparator = "\x1f"
+
+// maxCollectionSize bounds the unzipped collection, so a small upload cannot
+// fill the disk. Decks of tens of thousands of notes are a few tens of MB.
+var maxCollectionSize int64 = 512 << 20
+
+var (
+	ankiBreak = regexp.MustCompile(`(?i)<br\s*/?>|<div>`)
+	ankiTag   = regexp.MustCompile(`<[^>]*>|\[sound:[^\]]*\]`)
+)
+
+// ParseAnki reads the notes of an .apkg deck. Rows are numbered by note, in
+// the order the notes were created.
+func ParseAnki(r io.ReaderAt, size int64, mapping Mapping) (*WordSet, error) {
+	archive, err := zip.NewReader(r, size)
+	if err != nil {
+		return nil, errors.New("not an Anki package (.apkg is a zip file)")
+	}
+
+	files := map[string]*zip.File{}
+	for _, f := range archive.File {
+		files[f.Name] = f
+	}
+	collection := files["collection.anki21"]
+	if collection == nil {
+		collection = files["collection.anki2"]
+	}
+	if collection == nil {
+		if files["collection.anki21b"] != nil {
+			return nil, errors.New(`this deck uses the newer compressed format; export it from Anki with "Support older Anki versions" checked`)
+		}
+		return nil, errors.New("Anki package has no collection")
+	}
+
+	path, err := extract(collection)
+	if err != nil {
+		return nil, err
+	}
+	defer os.Remove(path)
+
+	db, err := sql.Open("sqlite3", path)
+	if err != nil {
+		return nil, err
+	}
+	defer db.Close()
+
+	noteTypes, err := ankiNoteTypes(db)
+	if err != nil {
+		return nil, fmt.Errorf("reading note types: %v", err)
+	}
+
+	rows, err := db.Query("SELECT mid, flds FROM notes ORDER BY id")
+	if err != nil {
+		return nil, fmt.Errorf("reading notes: %v", err)
+	}
+	defer rows.Close()
+
+	set := &WordSet{}
+	targets := map[int64]map[string]int{}
+	targetErrs := map[int64]error{}
+	row := 0
+	for rows.Next() {
+		var noteTypeID int64
+		var fields string
+		if err := rows.Scan(&noteTypeID, &fields); err != nil {
+			return nil, err
+		}
+		row++
+
+		if _, ok := targets[noteTypeID]; !ok && targetErrs[noteTypeID] == nil {
+			targets[noteTypeID], targetErrs[noteTypeID] = mapping.resolve(noteTypes[noteTypeID])
+		}
+		if err := targetErrs[noteTypeID]; err != nil {
+			set.Rows++
+			set.Errors = append(set.Errors, RowError{Row: row, Message: err.Error()})
+			continue
+		}
+
+		values := strings.Split(fields, ankiFieldSeparator)
+		for i, v := range values {
+			values[i] = ankiText(v)
+		}
+		set.addRow(row, values, targets[noteTypeID])
+	}
+	return set, rows.Err()
+}
+
+// extract copies a zipped file to a temporary file, since SQLite needs a path
+func extract(f *zip.File) (string, error) {
+	src, err := f.Open()
+	if err != nil {
+		return "", err
+	}
+	defer src.Close()
+
+	dst, err := os.CreateTemp("", "import-*.anki2")
+	if err != nil {
+		return "", err
+	}
+	n, err := io.Copy(dst, io.LimitReader(src, maxCollectionSize+1))
+	if err == nil && n > maxCollectionSize {
+		err = fmt.Errorf("Anki collection is larger than %d MB", maxCollectionSize>>20)
+	}
+	if err != nil {
+		dst.Close()
+		os.Remove(dst.Name())
+		return "", err
+	}
+	if err := dst.Close(); err != nil {
+		os.Remove(dst.Name())
+		return "", err
+	}
+	return dst.Name(), nil
+}
+
+// ankiNoteTypes returns the field names of every note type, in field order
+func ankiNoteTypes(db *sql.DB) (map[int64][]string, error) {
+	var models string
+	if err := db.QueryRow("SELECT models FROM col").Scan(&models); err != nil {
+		return nil, err
+	}
+
+	noteTypes := map[int64][]string{}
+	if strings.TrimSpace(models) != "" && strings.TrimSpace(models) != "{}" {
+		var parsed map[string]struct {
+			ID     int64 `json:"id"`
+			Fields []struct {
+				Name string `json:"name"`
+				Ord  int    `json:"ord"`
+			} `json:"flds"`
+		}
+		if err := json.Unmarshal([]byte(models), &parsed); err != nil {
+			return nil, err
+		}
+		for _, m := range parsed {
+			sort.Slice(m.Fields, func(i, j int) bool { return m.Fields[i].Ord < m.Fields[j].Ord })
+			names := make([]string, len(m.Fields))
+			for i, f := range m.Fields {
+				names[i] = f.Name
+			}
+			noteTypes[m.ID] = names
+		}
+		return noteTypes, nil
+	}
+
+	rows, err := db.Query("SELECT ntid, name FROM fields ORDER BY ntid, ord")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var id int64
+		var name string
+		if err := rows.Scan(&id, &name); err != nil {
+			return nil, err
+		}
+		noteTypes[id] = append(noteTypes[id], name)
+	}
+	return noteTypes, rows.Err()
+}
+
+// ankiText reduces a field's HTML to plain text
+func ankiText(s string) string {
+	s = ankiBreak.ReplaceAllString(s, " ")
+	s = ankiTag.ReplaceAllString(s, "")
+	s = html.UnescapeString(s)
+	return strings.Join(strings.Fields(s), " ")
+}
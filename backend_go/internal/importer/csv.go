@@ -0,0 +1,56 @@
+package importer
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// ParseCSV reads a delimited file whose first row holds the column names.
+// comma is the delimiter: ',' for CSV or '\t' for TSV.
+func ParseCSV(r io.Reader, comma rune, mapping Mapping) (*WordSet, error) {
+	reader := csv.NewReader(r)
+	reader.Comma = comma
+	reader.FieldsPerRecord = -1
+	reader.LazyQuotes = comma == '\t'
+
+	header, err := reader.Read()
+	if err == io.EOF {
+		return nil, errors.New("file is empty")
+	}
+	if err != nil {
+		return nil, err
+	}
+	// Spreadsheet exports often start with a UTF-8 byte order mark
+	if len(header) > 0 {
+		header[0] = strings.TrimPrefix(header[0], "\ufeff")
+	}
+
+	targets, err := mapping.resolve(header)
+	if err != nil {
+		return nil, err
+	}
+
+	set := &WordSet{}
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		var parseErr *csv.ParseError
+		if errors.As(err, &parseErr) {
+			set.Rows++
+			set.Errors = append(set.Errors, RowError{Row: parseErr.StartLine, Message: parseErr.Err.Error()})
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("reading rows: %v", err)
+		}
+
+		line, _ := reader.FieldPos(0)
+		set.addRow(line, record, targets)
+	}
+	return set, nil
+}
@@ -0,0 +1,187 @@
+// Package importer reads vocabulary files and loads them into the database.
+//
+// Teachers' files (CSV, TSV and Anki decks) are parsed row by row: rows that
+// do not make a valid word are left out and reported with their row number,
+// and the rest are imported. See Mapping for how columns become word fields.
+//
+// Seed files in db/seeds are JSON, in one of two shapes. The original shape
+// is a bare array of words, imported into a group named after the file:
+//
+//	[{"japanese": "こんにちは", "romaji": "konnichiwa", "english": "hello"}]
+//
+// The object shape names the group explicitly and allows parts:
+//
+//	{
+//	  "group": {"name": "Basic Greetings"},
+//	  "words": [{"japanese": "...", "romaji": "...", "english": "...", "parts": {...}}]
+//	}
+package importer
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/gen-ai-bootcamp-2025/backend_go/internal/models"
+)
+
+// WordSet is the group and words read from one file
+type WordSet struct {
+	Group string
+	Words []*models.Word
+	// Rows counts the rows read, including rejected ones
+	Rows   int
+	Errors []RowError
+}
+
+// RowError explains why a row was not imported
+type RowError struct {
+	Row     int    `json:"row"`
+	Field   string `json:"field,omitempty"`
+	Message string `json:"message"`
+}
+
+// Report is the outcome of importing a file
+type Report struct {
+	*models.ImportResult
+	Rows   int        `json:"rows"`
+	Errors []RowError `json:"errors"`
+}
+
+// Store is the part of the database an import writes to
+type Store interface {
+	ImportWords(ctx context.Context, groupID int64, groupName string, words []*models.Word, dryRun bool) (*models.ImportResult, error)
+}
+
+type seedFile struct {
+	Group struct {
+		Name string `json:"name"`
+	} `json:"group"`
+	Words []seedWord `json:"words"`
+}
+
+type seedWord struct {
+	Japanese string          `json:"japanese"`
+	Romaji   string          `json:"romaji"`
+	English  string          `json:"english"`
+	Parts    json.RawMessage `json:"parts"`
+}
+
+// Formats lists the file formats Parse accepts
+var Formats = []string{"csv", "tsv", "apkg"}
+
+// Parse reads a CSV, TSV or Anki file. An empty format is taken from the
+// file name's extension.
+func Parse(name string, r io.ReaderAt, size int64, format string, mapping Mapping) (*WordSet, error) {
+	if format == "" {
+		format = strings.ToLower(strings.TrimPrefix(filepath.Ext(name), "."))
+		if format == "txt" {
+			format = "tsv"
+		}
+	}
+
+	switch format {
+	case "csv":
+		return ParseCSV(io.NewSectionReader(r, 0, size), ',', mapping)
+	case "tsv":
+		return ParseCSV(io.NewSectionReader(r, 0, size), '\t', mapping)
+	case "apkg":
+		return ParseAnki(r, size, mapping)
+	default:
+		return nil, fmt.Errorf("unsupported format %q, expected one of %s", format, strings.Join(Formats, ", "))
+	}
+}
+
+// ParseJSON reads a seed file. name is the file name, used as the group name
+// when the file does not give one.
+func ParseJSON(name string, data []byte) (*WordSet, error) {
+	var file seedFile
+	trimmed := bytes.TrimSpace(data)
+	if len(trimmed) > 0 && trimmed[0] == '[' {
+		if err := json.Unmarshal(trimmed, &file.Words); err != nil {
+			return nil, err
+		}
+	} else if err := json.Unmarshal(trimmed, &file); err != nil {
+		return nil, err
+	}
+
+	set := &WordSet{Group: strings.TrimSpace(file.Group.Name), Rows: len(file.Words)}
+	if set.Group == "" {
+		set.Group = strings.TrimSuffix(filepath.Base(name), filepath.Ext(name))
+	}
+
+	for _, w := range file.Words {
+		word := &models.Word{Japanese: w.Japanese, Romaji: w.Romaji, English: w.English}
+		if len(w.Parts) > 0 {
+			word.Parts.String = string(w.Parts)
+			word.Parts.Valid = true
+		}
+		set.Words = append(set.Words, word)
+	}
+	if err := set.validate(); err != nil {
+		return nil, err
+	}
+	return set, nil
+}
+
+// validate checks every word so a bad file is rejected before anything is written
+func (s *WordSet) validate() error {
+	for i, word := range s.Words {
+		if err := models.ValidateWord(word); err != nil {
+			return fmt.Errorf("word %d: %v", i+1, err)
+		}
+	}
+	return nil
+}
+
+// Import writes a word set to the store
+func Import(ctx context.Context, store Store, set *WordSet, dryRun bool) (*models.ImportResult, error) {
+	return store.ImportWords(ctx, 0, set.Group, set.Words, dryRun)
+}
+
+// SeedDir imports every .json file in dir, in name order
+func SeedDir(ctx context.Context, store Store, dir string, dryRun bool) ([]*models.ImportResult, error) {
+	files, err := filepath.Glob(filepath.Join(dir, "*.json"))
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(files)
+
+	results := []*models.ImportResult{}
+	for _, file := range files {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			return results, err
+		}
+		set, err := ParseJSON(file, data)
+		if err != nil {
+			return results, fmt.Errorf("%s: %v", file, err)
+		}
+		result, err := Import(ctx, store, set, dryRun)
+		if err != nil {
+			return results, fmt.Errorf("%s: %v", file, err)
+		}
+		results = append(results, result)
+	}
+	return results, nil
+}
+
+// Summary describes a result in one line, e.g. for command output
+func Summary(r *models.ImportResult) string {
+	var b strings.Builder
+	if r.DryRun {
+		b.WriteString("[dry run] ")
+	}
+	fmt.Fprintf(&b, "%s: %d added, %d updated, %d skipped, %d added to group",
+		r.Group, r.Added, r.Updated, r.Skipped, r.Linked)
+	if r.GroupCreated {
+		b.WriteString(" (new group)")
+	}
+	return b.String()
+}
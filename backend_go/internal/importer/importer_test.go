@@ -0,0 +1,151 @@
+package importer
+
+import (
+	"archive/zip"
+	"bytes"
+	"database/sql"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseJSONArray(t *testing.T) {
+	set, err := ParseJSON("db/seeds/basic_greetings.json", []byte(`[
+		{"japanese": "こんにちは", "romaji": "konnichiwa", "english": "hello"}
+	]`))
+	require.NoError(t, err)
+	assert.Equal(t, "basic_greetings", set.Group)
+	require.Len(t, set.Words, 1)
+	assert.False(t, set.Words[0].Parts.Valid)
+}
+
+func TestParseJSONObject(t *testing.T) {
+	set, err := ParseJSON("adjectives.json", []byte(`{
+		"group": {"name": "Adjectives"},
+		"words": [{
+			"japanese": "新しい", "romaji": "atarashii", "english": "new",
+			"parts": {"type": "i-adjective"}
+		}]
+	}`))
+	require.NoError(t, err)
+	assert.Equal(t, "Adjectives", set.Group)
+	require.Len(t, set.Words, 1)
+	assert.Equal(t, "i-adjective", set.Words[0].PartsMap["type"])
+}
+
+func TestParseJSONRejectsInvalidWords(t *testing.T) {
+	_, err := ParseJSON("bad.json", []byte(`[
+		{"japanese": "猫", "romaji": "neko", "english": "cat"},
+		{"japanese": "cat", "romaji": "neko", "english": "cat"}
+	]`))
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "word 2")
+}
+
+func TestParseCSVWithMapping(t *testing.T) {
+	data := "\ufeffKanji,Reading,Meaning,Type\n" +
+		"猫,neko,cat,noun\n" +
+		"\n" +
+		"dog,inu,dog,noun\n" +
+		"新しい,,new,i-adjective\n"
+	set, err := ParseCSV(strings.NewReader(data), ',', Mapping{
+		"japanese":   "kanji",
+		"romaji":     "Reading",
+		"english":    "Meaning",
+		"parts.type": "Type",
+	})
+	require.NoError(t, err)
+
+	require.Len(t, set.Words, 1)
+	assert.Equal(t, "猫", set.Words[0].Japanese)
+	assert.Equal(t, "noun", set.Words[0].PartsMap["type"])
+	assert.Equal(t, 3, set.Rows)
+	assert.Equal(t, []RowError{
+		{Row: 4, Field: "japanese", Message: "must contain hiragana, katakana or kanji"},
+		{Row: 5, Field: "romaji", Message: "is required"},
+	}, set.Errors)
+}
+
+func TestParseCSVMissingColumn(t *testing.T) {
+	_, err := ParseCSV(strings.NewReader("japanese,english\n猫,cat\n"), ',', nil)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "romaji")
+
+	_, err = ParseCSV(strings.NewReader("japanese,romaji,english\n"), ',', Mapping{"kanji": "japanese"})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "unknown word field")
+}
+
+func TestParseTSV(t *testing.T) {
+	data := "japanese\tromaji\tenglish\tparts\n" +
+		"猫\tneko\tcat\t{\"type\": \"noun\"}\n" +
+		"犬\tinu\tdog\t{not json}\n"
+	set, err := Parse("deck.txt", strings.NewReader(data), int64(len(data)), "", nil)
+	require.NoError(t, err)
+	require.Len(t, set.Words, 1)
+	assert.Equal(t, "noun", set.Words[0].PartsMap["type"])
+	assert.Equal(t, []RowError{{Row: 3, Field: "parts", Message: "must be a JSON object"}}, set.Errors)
+}
+
+func TestParseUnsupportedFormat(t *testing.T) {
+	_, err := Parse("deck.xlsx", strings.NewReader(""), 0, "", nil)
+	assert.Error(t, err)
+}
+
+func TestParseAnki(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "collection.anki2")
+	db, err := sql.Open("sqlite3", path)
+	require.NoError(t, err)
+	_, err = db.Exec(`
+		CREATE TABLE col (models TEXT);
+		CREATE TABLE notes (id INTEGER PRIMARY KEY, mid INTEGER, flds TEXT);
+		INSERT INTO col (models) VALUES ('{"1": {"id": 1, "flds": [
+			{"name": "Back", "ord": 1}, {"name": "Front", "ord": 0}, {"name": "Reading", "ord": 2}
+		]}}');
+		INSERT INTO notes (id, mid, flds) VALUES
+			(10, 1, '<b>猫</b>' || char(31) || 'cat<br>kitty' || char(31) || 'neko'),
+			(11, 1, '犬' || char(31) || 'dog &amp; hound' || char(31) || ''),
+			(12, 2, 'x' || char(31) || 'y')`)
+	require.NoError(t, err)
+	require.NoError(t, db.Close())
+
+	collection, err := os.ReadFile(path)
+	require.NoError(t, err)
+	var buf bytes.Buffer
+	archive := zip.NewWriter(&buf)
+	w, err := archive.Create("collection.anki2")
+	require.NoError(t, err)
+	_, err = w.Write(collection)
+	require.NoError(t, err)
+	require.NoError(t, archive.Close())
+
+	mapping := Mapping{"japanese": "Front", "english": "Back", "romaji": "Reading"}
+	set, err := Parse("deck.apkg", bytes.NewReader(buf.Bytes()), int64(buf.Len()), "", mapping)
+	require.NoError(t, err)
+
+	defer func(max int64) { maxCollectionSize = max }(maxCollectionSize)
+	maxCollectionSize = int64(len(collection)) - 1
+	_, err = Parse("deck.apkg", bytes.NewReader(buf.Bytes()), int64(buf.Len()), "", mapping)
+	assert.ErrorContains(t, err, "Anki collection is larger than")
+	maxCollectionSize = int64(len(collection))
+	_, err = Parse("deck.apkg", bytes.NewReader(buf.Bytes()), int64(buf.Len()), "", mapping)
+	assert.NoError(t, err, "a collection of exactly the limit is read")
+
+	require.Len(t, set.Words, 1)
+	assert.Equal(t, "猫", set.Words[0].Japanese)
+	assert.Equal(t, "cat kitty", set.Words[0].English)
+	assert.Equal(t, 3, set.Rows)
+	require.Len(t, set.Errors, 2)
+	assert.Equal(t, RowError{Row: 2, Field: "romaji", Message: "is required"}, set.Errors[0])
+	assert.Equal(t, 3, set.Errors[1].Row)
+}
+
+func TestParseAnkiRejectsNonZip(t *testing.T) {
+	_, err := ParseAnki(strings.NewReader("nope"), 4, nil)
+	assert.Error(t, err)
+}
@@ -0,0 +1,153 @@
+package importer
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/gen-ai-bootcamp-2025/backend_go/internal/models"
+)
+
+// Mapping maps word fields onto source columns: CSV headers or Anki note
+// field names, compared case-insensitively. The word fields are
+//
+//	japanese, romaji, english
+//	parts          a JSON object
+//	parts.<key>    a single key of parts, e.g. parts.type
+//
+// Any word field that is not mapped is read from a column of the same name
+// if there is one, so a file with japanese,romaji,english headers needs no
+// mapping at all.
+type Mapping map[string]string
+
+var wordFields = []string{"japanese", "romaji", "english", "parts"}
+
+// requiredFields must be found in every file, or no row could be valid
+var requiredFields = []string{"japanese", "romaji", "english"}
+
+// ParseMapping reads a mapping from a JSON object such as {"japanese": "Kanji"}
+func ParseMapping(s string) (Mapping, error) {
+	if strings.TrimSpace(s) == "" {
+		return nil, nil
+	}
+	var m Mapping
+	if err := json.Unmarshal([]byte(s), &m); err != nil {
+		return nil, errors.New("mapping must be a JSON object of field names to column names")
+	}
+	return m, nil
+}
+
+// resolve finds the column index of every mapped field
+func (m Mapping) resolve(columns []string) (map[string]int, error) {
+	index := map[string]int{}
+	for i, column := range columns {
+		key := strings.ToLower(strings.TrimSpace(column))
+		if _, ok := index[key]; !ok {
+			index[key] = i
+		}
+	}
+
+	targets := map[string]int{}
+	for field, column := range m {
+		if !isWordField(field) {
+			return nil, fmt.Errorf("mapping: unknown word field %q", field)
+		}
+		i, ok := index[strings.ToLower(strings.TrimSpace(column))]
+		if !ok {
+			return nil, fmt.Errorf("mapping: no column named %q for %s", column, field)
+		}
+		targets[field] = i
+	}
+	for column, i := range index {
+		if _, ok := targets[column]; !ok && isWordField(column) {
+			targets[column] = i
+		}
+	}
+
+	for _, field := range requiredFields {
+		if _, ok := targets[field]; !ok {
+			return nil, fmt.Errorf("no column for %s; name a column %s or map one to it", field, field)
+		}
+	}
+	return targets, nil
+}
+
+func isWordField(field string) bool {
+	if key := strings.TrimPrefix(field, "parts."); key != field {
+		return key != ""
+	}
+	for _, f := range wordFields {
+		if f == field {
+			return true
+		}
+	}
+	return false
+}
+
+// addRow turns one row into a word, or into row errors if it is invalid.
+// Blank rows are ignored.
+func (s *WordSet) addRow(row int, values []string, targets map[string]int) {
+	value := func(field string) string {
+		i, ok := targets[field]
+		if !ok || i >= len(values) {
+			return ""
+		}
+		return strings.TrimSpace(values[i])
+	}
+
+	blank := true
+	for _, v := range values {
+		if strings.TrimSpace(v) != "" {
+			blank = false
+			break
+		}
+	}
+	if blank {
+		return
+	}
+	s.Rows++
+
+	word := &models.Word{
+		Japanese: value("japanese"),
+		Romaji:   value("romaji"),
+		English:  value("english"),
+	}
+
+	parts := map[string]interface{}{}
+	if raw := value("parts"); raw != "" {
+		if err := json.Unmarshal([]byte(raw), &parts); err != nil {
+			s.Errors = append(s.Errors, RowError{Row: row, Field: "parts", Message: "must be a JSON object"})
+			return
+		}
+	}
+	for field := range targets {
+		if key := strings.TrimPrefix(field, "parts."); key != field {
+			if v := value(field); v != "" {
+				parts[key] = v
+			}
+		}
+	}
+	if len(parts) > 0 {
+		encoded, err := json.Marshal(parts)
+		if err != nil {
+			s.Errors = append(s.Errors, RowError{Row: row, Field: "parts", Message: err.Error()})
+			return
+		}
+		word.Parts.String = string(encoded)
+		word.Parts.Valid = true
+	}
+
+	if err := models.ValidateWord(word); err != nil {
+		var fieldErrs models.ValidationErrors
+		if !errors.As(err, &fieldErrs) {
+			s.Errors = append(s.Errors, RowError{Row: row, Message: err.Error()})
+			return
+		}
+		for _, fe := range fieldErrs {
+			s.Errors = append(s.Errors, RowError{Row: row, Field: fe.Field, Message: fe.Message})
+		}
+		return
+	}
+	s.Words = append(s.Words, word)
+}
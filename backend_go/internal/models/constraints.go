@@ -0,0 +1,88 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/mattn/go-sqlite3"
+)
+
+// busyTimeout is how long a write waits for another connection's write to
+// finish before failing with "database is locked"
+const busyTimeout = 5 * time.Second
+
+// DSN is the data source name to open the SQLite database at path with:
+// foreign keys enforced, a write-ahead log so reads do not wait for writes,
+// and a busy timeout so concurrent writes wait for each other
+func DSN(path string) string {
+	params := url.Values{}
+	params.Set("_foreign_keys", "on")
+	params.Set("_journal_mode", "WAL")
+	params.Set("_busy_timeout", fmt.Sprint(busyTimeout.Milliseconds()))
+
+	sep := "?"
+	if strings.Contains(path, "?") {
+		sep = "&"
+	}
+	return path + sep + params.Encode()
+}
+
+// Errors for writes the database refused because they would break a
+// constraint of the schema
+var (
+	// ErrMissingReference means a write referred to a row that does not exist
+	ErrMissingReference = NewError(ErrNotFound, "missing_reference", "refers to a row that does not exist")
+	// ErrReferenced means a row could not be deleted because other rows
+	// still refer to it
+	ErrReferenced = NewError(ErrConflict, "in_use", "still in use")
+	// ErrDuplicate means a write repeated a value that must be unique
+	ErrDuplicate = NewError(ErrConflict, "duplicate", "already exists")
+	// ErrInvalidValue means a write stored a value a column does not allow
+	ErrInvalidValue = NewError(ErrValidation, "invalid_value", "value not allowed")
+)
+
+// writeError translates a constraint violation reported by SQLite into one
+// of the errors above, naming the columns involved when SQLite does. Other
+// errors are returned unchanged.
+func writeError(err error) error {
+	var sqliteErr sqlite3.Error
+	if !errors.As(err, &sqliteErr) || sqliteErr.Code != sqlite3.ErrConstraint {
+		return err
+	}
+
+	var kind error
+	switch sqliteErr.ExtendedCode {
+	case sqlite3.ErrConstraintForeignKey:
+		kind = ErrMissingReference
+	case sqlite3.ErrConstraintTrigger:
+		// ON DELETE RESTRICT is reported as a trigger
+		if !strings.HasPrefix(sqliteErr.Error(), "FOREIGN KEY") {
+			return err
+		}
+		kind = ErrMissingReference
+	case sqlite3.ErrConstraintUnique, sqlite3.ErrConstraintPrimaryKey:
+		kind = ErrDuplicate
+	case sqlite3.ErrConstraintCheck, sqlite3.ErrConstraintNotNull:
+		kind = ErrInvalidValue
+	default:
+		return err
+	}
+	// e.g. "UNIQUE constraint failed: groups.name"
+	if _, columns, ok := strings.Cut(sqliteErr.Error(), "failed: "); ok {
+		return fmt.Errorf("%w (%s)", kind, columns)
+	}
+	return kind
+}
+
+// deleteError is writeError for deletes, where a foreign key violation means
+// the row is still referred to
+func deleteError(err error) error {
+	err = writeError(err)
+	if errors.Is(err, ErrMissingReference) {
+		return ErrReferenced
+	}
+	return err
+}
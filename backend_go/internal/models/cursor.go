@@ -0,0 +1,79 @@
+package models
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Cursor is a position in a listing ordered by creation time and then id:
+// the creation time and id of the last item of the previous page. Creation
+// times count to the second, like SQLite's datetime().
+type Cursor struct {
+	CreatedAt time.Time
+	ID        int64
+}
+
+// errCursor is returned for cursors this server did not hand out
+var errCursor = errors.New("not a cursor from this listing")
+
+// CursorAfter is the cursor continuing after an item created at createdAt
+// with the given id
+func CursorAfter(createdAt time.Time, id int64) Cursor {
+	return Cursor{CreatedAt: createdAt.UTC().Truncate(time.Second), ID: id}
+}
+
+// String encodes the cursor for clients, who pass it back unchanged
+func (c Cursor) String() string {
+	raw := c.CreatedAt.UTC().Format(sqliteTimeFormat) + "|" + fmt.Sprint(c.ID)
+	return base64.RawURLEncoding.EncodeToString([]byte(raw))
+}
+
+// IsZero reports whether the cursor is the start of a listing
+func (c Cursor) IsZero() bool {
+	return c.ID == 0
+}
+
+// ParseCursor decodes a cursor made by String. An empty string is the start
+// of a listing.
+func ParseCursor(s string) (Cursor, error) {
+	if s == "" {
+		return Cursor{}, nil
+	}
+	raw, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return Cursor{}, errCursor
+	}
+	createdAt, id, ok := strings.Cut(string(raw), "|")
+	if !ok {
+		return Cursor{}, errCursor
+	}
+	var c Cursor
+	if c.CreatedAt, err = time.Parse(sqliteTimeFormat, createdAt); err != nil {
+		return Cursor{}, errCursor
+	}
+	if _, err := fmt.Sscan(id, &c.ID); err != nil || c.ID <= 0 {
+		return Cursor{}, errCursor
+	}
+	return c, nil
+}
+
+// keyset narrows rows aliased alias to those after cursor in a listing
+// ordered by creation time and id, newest first when desc is set, and
+// returns that ORDER BY clause. Tables listed this way index
+// (user_id, datetime(created_at), id) to match.
+func keyset(q *queryBuilder, alias string, after Cursor, desc bool) string {
+	createdAt := "datetime(" + alias + ".created_at)"
+	direction, cmp := "ASC", ">"
+	if desc {
+		direction, cmp = "DESC", "<"
+	}
+	if !after.IsZero() {
+		at := after.CreatedAt.UTC().Format(sqliteTimeFormat)
+		q.where("("+createdAt+" "+cmp+" datetime(?) OR ("+createdAt+" = datetime(?) AND "+alias+".id "+cmp+" ?))",
+			at, at, after.ID)
+	}
+	return "ORDER BY " + createdAt + " " + direction + ", " + alias + ".id " + direction
+}
@@ -1,69 +1,199 @@
 package models
 
 import (
+	"context"
 	"database/sql"
 	"encoding/json"
+	"strings"
 	"time"
 )
 
+// sqliteTimeFormat matches the output of SQLite's datetime() so bound
+// parameters compare correctly against normalized created_at values.
+const sqliteTimeFormat = "2006-01-02 15:04:05"
+
 type DB struct {
 	*sql.DB
+	queryTimeout time.Duration
 }
 
 func NewDB(db *sql.DB) *DB {
-	return &DB{db}
+	return &DB{DB: db}
+}
+
+// SetQueryTimeout bounds how long each operation may run on top of the
+// deadline of the context it is given. Zero means no extra bound.
+func (db *DB) SetQueryTimeout(d time.Duration) {
+	db.queryTimeout = d
+}
+
+// withTimeout derives the context an operation runs under
+func (db *DB) withTimeout(ctx context.Context) (context.Context, context.CancelFunc) {
+	if db.queryTimeout <= 0 {
+		return context.WithCancel(ctx)
+	}
+	return context.WithTimeout(ctx, db.queryTimeout)
+}
+
+// Close writes everything in SQLite's write-ahead log, if it uses one, back
+// to the database file and closes the database. Call it once nothing else
+// is using the database.
+func (db *DB) Close() error {
+	_, checkpointErr := db.Exec("PRAGMA wal_checkpoint(TRUNCATE)")
+	if _, err := db.Exec("PRAGMA optimize"); err != nil && checkpointErr == nil {
+		checkpointErr = err
+	}
+	if err := db.DB.Close(); err != nil {
+		return err
+	}
+	return checkpointErr
 }
 
 // Word operations
-func (db *DB) GetWord(id int64) (*Word, error) {
-	word := &Word{}
-	err := db.QueryRow(`
-		SELECT id, japanese, romaji, english, parts 
-		FROM words WHERE id = ?`, id).Scan(
-		&word.ID, &word.Japanese, &word.Romaji, &word.English, &word.Parts)
+
+// GetWord returns a word with userID's review stats, or nil if there is none
+func (db *DB) GetWord(ctx context.Context, userID, id int64) (*Word, error) {
+	ctx, cancel := db.withTimeout(ctx)
+	defer cancel()
+
+	word, err := scanWord(db.QueryRowContext(ctx, `
+		SELECT `+wordColumns+`
+		FROM words w`+wordReviewStatsJoin(statsOfWord)+`
+		WHERE w.id = ?`, userID, id, id))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
 	if err != nil {
 		return nil, err
 	}
+	return word, nil
+}
+
+// wordReviewStatsJoin aggregates one user's reviews of each word once so
+// listings can sort and filter on them without per-row subqueries. Rows
+// alias words as w; the user id is bound where the join appears, followed by
+// the arguments of scope, a condition on word_id that narrows the aggregate
+// to the words the query can return ("" for every word).
+func wordReviewStatsJoin(scope string) string {
+	if scope != "" {
+		scope = " AND " + scope
+	}
+	return `
+		LEFT JOIN (
+			SELECT word_id,
+				SUM(CASE WHEN correct THEN 1 ELSE 0 END) as correct_count,
+				SUM(CASE WHEN correct THEN 0 ELSE 1 END) as wrong_count,
+				MAX(datetime(created_at)) as last_reviewed_at
+			FROM word_review_items
+			WHERE user_id = ?` + scope + `
+			GROUP BY word_id
+		) r ON r.word_id = w.id`
+}
+
+// Scopes for wordReviewStatsJoin, each binding one id
+const (
+	statsOfWord    = "word_id = ?"
+	statsOfGroup   = "word_id IN (SELECT word_id FROM words_groups WHERE group_id = ?)"
+	statsOfSession = "word_id IN (SELECT word_id FROM word_review_items WHERE study_session_id = ?)"
+)
+
+// wordColumns selects a word and its review stats in the order scanWord expects
+const wordColumns = `w.id, w.japanese, w.romaji, w.english, w.parts,
+			COALESCE(r.correct_count, 0), COALESCE(r.wrong_count, 0), r.last_reviewed_at`
+
+// scanWord reads a row selected with wordColumns, followed by any extra
+// columns into extra
+func scanWord(row interface{ Scan(...interface{}) error }, extra ...interface{}) (*Word, error) {
+	word := &Word{}
+	var lastReviewedAt sql.NullString
+	dest := append([]interface{}{
+		&word.ID, &word.Japanese, &word.Romaji, &word.English, &word.Parts,
+		&word.CorrectCount, &word.WrongCount, &lastReviewedAt,
+	}, extra...)
+	if err := row.Scan(dest...); err != nil {
+		return nil, err
+	}
 
 	if word.Parts.Valid {
-		err = json.Unmarshal([]byte(word.Parts.String), &word.PartsMap)
+		if err := json.Unmarshal([]byte(word.Parts.String), &word.PartsMap); err != nil {
+			return nil, err
+		}
+	}
+
+	if total := word.CorrectCount + word.WrongCount; total > 0 {
+		accuracy := 100 * float64(word.CorrectCount) / float64(total)
+		word.Accuracy = &accuracy
+	}
+	if lastReviewedAt.Valid {
+		t, err := time.Parse(sqliteTimeFormat, lastReviewedAt.String)
 		if err != nil {
 			return nil, err
 		}
+		word.LastReviewedAt = &t
 	}
 
 	return word, nil
 }
 
-func (db *DB) GetWords(page, perPage int) ([]*Word, *Pagination, error) {
+func (db *DB) GetWords(ctx context.Context, filter WordFilter, page, perPage int) ([]*Word, *Pagination, error) {
+	ctx, cancel := db.withTimeout(ctx)
+	defer cancel()
+
 	offset := (page - 1) * perPage
 	words := []*Word{}
 
-	rows, err := db.Query(`
-		SELECT id, japanese, romaji, english, parts 
-		FROM words LIMIT ? OFFSET ?`, perPage, offset)
+	var q queryBuilder
+	if filter.GroupID != 0 {
+		q.where("EXISTS (SELECT 1 FROM words_groups wg WHERE wg.word_id = w.id AND wg.group_id = ?)", filter.GroupID)
+	}
+	if filter.HasParts != nil {
+		if *filter.HasParts {
+			q.where("(w.parts IS NOT NULL AND w.parts <> '')")
+		} else {
+			q.where("(w.parts IS NULL OR w.parts = '')")
+		}
+	}
+	if filter.NeverReviewed != nil {
+		if *filter.NeverReviewed {
+			q.where("r.word_id IS NULL")
+		} else {
+			q.where("r.word_id IS NOT NULL")
+		}
+	}
+	if filter.AccuracyBelow != nil {
+		q.where("r.word_id IS NOT NULL AND 100.0 * r.correct_count / (r.correct_count + r.wrong_count) < ?", *filter.AccuracyBelow)
+	}
+
+	join := wordReviewStatsJoin("")
+	args := []interface{}{filter.UserID}
+	if filter.GroupID != 0 {
+		join = wordReviewStatsJoin(statsOfGroup)
+		args = append(args, filter.GroupID)
+	}
+	from := "FROM words w" + join + " " + q.whereClause()
+	order := orderBy(WordSortColumns, filter.SortBy, filter.Order, "id", "asc", "w.id")
+	args = append(args, q.args...)
+
+	rows, err := db.QueryContext(ctx, `
+		SELECT `+wordColumns+`
+		`+from+`
+		`+order+`
+		LIMIT ? OFFSET ?`, append(args, perPage, offset)...)
 	if err != nil {
 		return nil, nil, err
 	}
 	defer rows.Close()
 
 	for rows.Next() {
-		word := &Word{}
-		err := rows.Scan(&word.ID, &word.Japanese, &word.Romaji, &word.English, &word.Parts)
+		word, err := scanWord(rows)
 		if err != nil {
 			return nil, nil, err
 		}
-		if word.Parts.Valid {
-			err = json.Unmarshal([]byte(word.Parts.String), &word.PartsMap)
-			if err != nil {
-				return nil, nil, err
-			}
-		}
 		words = append(words, word)
 	}
 
 	var total int
-	err = db.QueryRow("SELECT COUNT(*) FROM words").Scan(&total)
+	err = db.QueryRowContext(ctx, "SELECT COUNT(*) "+from, args...).Scan(&total)
 	if err != nil {
 		return nil, nil, err
 	}
@@ -78,27 +208,138 @@ func (db *DB) GetWords(page, perPage int) ([]*Word, *Pagination, error) {
 	return words, pagination, nil
 }
 
+func (db *DB) CreateWord(ctx context.Context, word *Word) (*Word, error) {
+	ctx, cancel := db.withTimeout(ctx)
+	defer cancel()
+
+	// the word and its search entry are written together, so a word is
+	// never left out of search
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	result, err := tx.ExecContext(ctx, `
+		INSERT INTO words (japanese, romaji, english, parts)
+		VALUES (?, ?, ?, ?)`, word.Japanese, word.Romaji, word.English, word.Parts)
+	if err != nil {
+		return nil, writeError(err)
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		return nil, err
+	}
+	indexed := *word
+	indexed.ID = id
+	if err := indexWord(ctx, tx, &indexed); err != nil {
+		return nil, err
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	// a new word has no reviews, so no user's stats are needed
+	return db.GetWord(ctx, 0, id)
+}
+
+func (db *DB) UpdateWord(ctx context.Context, word *Word) (*Word, error) {
+	ctx, cancel := db.withTimeout(ctx)
+	defer cancel()
+
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	result, err := tx.ExecContext(ctx, `
+		UPDATE words
+		SET japanese = ?, romaji = ?, english = ?, parts = ?
+		WHERE id = ?`, word.Japanese, word.Romaji, word.English, word.Parts, word.ID)
+	if err != nil {
+		return nil, writeError(err)
+	}
+	n, err := result.RowsAffected()
+	if err != nil || n == 0 {
+		return nil, err
+	}
+	if err := indexWord(ctx, tx, word); err != nil {
+		return nil, err
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	// editing a word leaves its review stats alone, so the caller keeps the
+	// ones it read
+	return db.GetWord(ctx, 0, word.ID)
+}
+
+// DeleteWord removes a word together with its search entry; its group
+// memberships, reviews and spaced-repetition state go with it (ON DELETE
+// CASCADE)
+func (db *DB) DeleteWord(ctx context.Context, id int64) error {
+	ctx, cancel := db.withTimeout(ctx)
+	defer cancel()
+
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+
+	statements := []string{
+		"DELETE FROM " + searchTable + " WHERE rowid = ?",
+		"DELETE FROM words WHERE id = ?",
+	}
+
+	for _, statement := range statements {
+		_, err = tx.ExecContext(ctx, statement, id)
+		if err != nil {
+			tx.Rollback()
+			return deleteError(err)
+		}
+	}
+
+	return tx.Commit()
+}
+
 // Group operations
-func (db *DB) GetGroup(id int64) (*Group, error) {
+func (db *DB) GetGroup(ctx context.Context, id int64) (*Group, error) {
+	ctx, cancel := db.withTimeout(ctx)
+	defer cancel()
+
 	group := &Group{}
-	err := db.QueryRow(`
+	err := db.QueryRowContext(ctx, `
 		SELECT g.id, g.name, COUNT(wg.word_id) as word_count
 		FROM groups g
 		LEFT JOIN words_groups wg ON g.id = wg.group_id
 		WHERE g.id = ?
 		GROUP BY g.id`, id).Scan(&group.ID, &group.Name, &group.WordCount)
-	return group, err
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return group, nil
 }
 
-func (db *DB) GetGroups(page, perPage int) ([]*Group, *Pagination, error) {
+func (db *DB) GetGroups(ctx context.Context, filter GroupFilter, page, perPage int) ([]*Group, *Pagination, error) {
+	ctx, cancel := db.withTimeout(ctx)
+	defer cancel()
+
 	offset := (page - 1) * perPage
 	groups := []*Group{}
 
-	rows, err := db.Query(`
+	order := orderBy(GroupSortColumns, filter.SortBy, filter.Order, "id", "asc", "g.id")
+	rows, err := db.QueryContext(ctx, `
 		SELECT g.id, g.name, COUNT(wg.word_id) as word_count
 		FROM groups g
 		LEFT JOIN words_groups wg ON g.id = wg.group_id
 		GROUP BY g.id
+		`+order+`
 		LIMIT ? OFFSET ?`, perPage, offset)
 	if err != nil {
 		return nil, nil, err
@@ -115,7 +356,7 @@ func (db *DB) GetGroups(page, perPage int) ([]*Group, *Pagination, error) {
 	}
 
 	var total int
-	err = db.QueryRow("SELECT COUNT(*) FROM groups").Scan(&total)
+	err = db.QueryRowContext(ctx, "SELECT COUNT(*) FROM groups").Scan(&total)
 	if err != nil {
 		return nil, nil, err
 	}
@@ -130,68 +371,468 @@ func (db *DB) GetGroups(page, perPage int) ([]*Group, *Pagination, error) {
 	return groups, pagination, nil
 }
 
-// Study Session operations
-func (db *DB) CreateStudySession(groupID, activityID int64) (*StudySession, error) {
-	result, err := db.Exec(`
-		INSERT INTO study_sessions (group_id, study_activity_id, created_at)
-		VALUES (?, ?, ?)`, groupID, activityID, time.Now())
+func (db *DB) CreateGroup(ctx context.Context, name string) (*Group, error) {
+	ctx, cancel := db.withTimeout(ctx)
+	defer cancel()
+
+	result, err := db.ExecContext(ctx, "INSERT INTO groups (name) VALUES (?)", name)
+	if err != nil {
+		return nil, writeError(err)
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		return nil, err
+	}
+
+	return db.GetGroup(ctx, id)
+}
+
+func (db *DB) RenameGroup(ctx context.Context, id int64, name string) (*Group, error) {
+	ctx, cancel := db.withTimeout(ctx)
+	defer cancel()
+
+	_, err := db.ExecContext(ctx, "UPDATE groups SET name = ? WHERE id = ?", name, id)
+	if err != nil {
+		return nil, writeError(err)
+	}
+
+	return db.GetGroup(ctx, id)
+}
+
+// DeleteGroup removes a group and, through ON DELETE CASCADE, its
+// memberships. The words themselves are kept. Groups with study sessions
+// cannot be deleted (ErrReferenced).
+func (db *DB) DeleteGroup(ctx context.Context, id int64) error {
+	ctx, cancel := db.withTimeout(ctx)
+	defer cancel()
+
+	_, err := db.ExecContext(ctx, "DELETE FROM groups WHERE id = ?", id)
+	return deleteError(err)
+}
+
+func (db *DB) CountStudySessionsByGroup(ctx context.Context, groupID int64) (int, error) {
+	ctx, cancel := db.withTimeout(ctx)
+	defer cancel()
+
+	var count int
+	err := db.QueryRowContext(ctx, `
+		SELECT COUNT(*)
+		FROM study_sessions
+		WHERE group_id = ?`, groupID).Scan(&count)
+	return count, err
+}
+
+// AddWordsToGroup adds the words to a group, skipping words that are already
+// members, and returns how many memberships were created
+func (db *DB) AddWordsToGroup(ctx context.Context, groupID int64, wordIDs []int64) (int, error) {
+	ctx, cancel := db.withTimeout(ctx)
+	defer cancel()
+
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+
+	added := 0
+	for _, wordID := range wordIDs {
+		result, err := tx.ExecContext(ctx, `
+			INSERT OR IGNORE INTO words_groups (word_id, group_id)
+			VALUES (?, ?)`, wordID, groupID)
+		if err != nil {
+			tx.Rollback()
+			return 0, writeError(err)
+		}
+		n, err := result.RowsAffected()
+		if err != nil {
+			tx.Rollback()
+			return 0, err
+		}
+		added += int(n)
+	}
+
+	return added, tx.Commit()
+}
+
+// RemoveWordsFromGroup removes the words from a group and returns how many
+// memberships were deleted
+func (db *DB) RemoveWordsFromGroup(ctx context.Context, groupID int64, wordIDs []int64) (int, error) {
+	ctx, cancel := db.withTimeout(ctx)
+	defer cancel()
+
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+
+	removed := 0
+	for _, wordID := range wordIDs {
+		result, err := tx.ExecContext(ctx, `
+			DELETE FROM words_groups
+			WHERE word_id = ? AND group_id = ?`, wordID, groupID)
+		if err != nil {
+			tx.Rollback()
+			return 0, err
+		}
+		n, err := result.RowsAffected()
+		if err != nil {
+			tx.Rollback()
+			return 0, err
+		}
+		removed += int(n)
+	}
+
+	return removed, tx.Commit()
+}
+
+// IsWordInGroup reports whether a word is a member of a group
+func (db *DB) IsWordInGroup(ctx context.Context, groupID, wordID int64) (bool, error) {
+	ctx, cancel := db.withTimeout(ctx)
+	defer cancel()
+
+	var member bool
+	err := db.QueryRowContext(ctx, `
+		SELECT EXISTS(SELECT 1 FROM words_groups WHERE group_id = ? AND word_id = ?)`,
+		groupID, wordID).Scan(&member)
+	return member, err
+}
+
+// GetMissingWordIDs returns the ids from wordIDs that have no matching word,
+// in the order given. The ids are passed as one JSON array, so any number of
+// them takes a single query.
+func (db *DB) GetMissingWordIDs(ctx context.Context, wordIDs []int64) ([]int64, error) {
+	ctx, cancel := db.withTimeout(ctx)
+	defer cancel()
+
+	missing := []int64{}
+	if len(wordIDs) == 0 {
+		return missing, nil
+	}
+	ids, err := json.Marshal(wordIDs)
+	if err != nil {
+		return nil, err
+	}
+	rows, err := db.QueryContext(ctx, `
+		SELECT ids.value FROM json_each(?) ids
+		WHERE NOT EXISTS (SELECT 1 FROM words WHERE id = ids.value)
+		ORDER BY ids.key`, string(ids))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		missing = append(missing, id)
+	}
+	return missing, rows.Err()
+}
+
+// Study Activity operations
+func (db *DB) GetStudyActivity(ctx context.Context, id int64) (*StudyActivity, error) {
+	ctx, cancel := db.withTimeout(ctx)
+	defer cancel()
+
+	activity := &StudyActivity{}
+	err := db.QueryRowContext(ctx, `
+		SELECT id, name, description, thumbnail_url, launch_url, enabled, created_at
+		FROM study_activities WHERE id = ?`, id).Scan(
+		&activity.ID, &activity.Name, &activity.Description, &activity.ThumbnailURL,
+		&activity.LaunchURL, &activity.Enabled, &activity.CreatedAt)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
 	if err != nil {
 		return nil, err
 	}
+	return activity, nil
+}
+
+func (db *DB) GetStudyActivities(ctx context.Context, page, perPage int) ([]*StudyActivity, *Pagination, error) {
+	ctx, cancel := db.withTimeout(ctx)
+	defer cancel()
+
+	offset := (page - 1) * perPage
+	activities := []*StudyActivity{}
+
+	rows, err := db.QueryContext(ctx, `
+		SELECT id, name, description, thumbnail_url, launch_url, enabled, created_at
+		FROM study_activities
+		ORDER BY id
+		LIMIT ? OFFSET ?`, perPage, offset)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		activity := &StudyActivity{}
+		err := rows.Scan(
+			&activity.ID, &activity.Name, &activity.Description, &activity.ThumbnailURL,
+			&activity.LaunchURL, &activity.Enabled, &activity.CreatedAt)
+		if err != nil {
+			return nil, nil, err
+		}
+		activities = append(activities, activity)
+	}
+
+	var total int
+	err = db.QueryRowContext(ctx, "SELECT COUNT(*) FROM study_activities").Scan(&total)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	pagination := &Pagination{
+		CurrentPage:  page,
+		TotalPages:   (total + perPage - 1) / perPage,
+		TotalItems:   total,
+		ItemsPerPage: perPage,
+	}
+
+	return activities, pagination, nil
+}
+
+func (db *DB) CreateStudyActivity(ctx context.Context, activity *StudyActivity) (*StudyActivity, error) {
+	ctx, cancel := db.withTimeout(ctx)
+	defer cancel()
+
+	result, err := db.ExecContext(ctx, `
+		INSERT INTO study_activities (name, description, thumbnail_url, launch_url, enabled, created_at)
+		VALUES (?, ?, ?, ?, ?, ?)`,
+		activity.Name, activity.Description, activity.ThumbnailURL,
+		activity.LaunchURL, activity.Enabled, time.Now())
+	if err != nil {
+		return nil, writeError(err)
+	}
 
 	id, err := result.LastInsertId()
 	if err != nil {
 		return nil, err
 	}
 
-	return db.GetStudySession(id)
+	return db.GetStudyActivity(ctx, id)
 }
 
-func (db *DB) GetStudySession(id int64) (*StudySession, error) {
-	session := &StudySession{}
-	err := db.QueryRow(`
-		SELECT s.id, s.group_id, s.created_at, s.study_activity_id,
-			g.name as group_name,
-			(SELECT COUNT(*) FROM word_review_items WHERE study_session_id = s.id) as review_count
-		FROM study_sessions s
+func (db *DB) UpdateStudyActivity(ctx context.Context, activity *StudyActivity) (*StudyActivity, error) {
+	ctx, cancel := db.withTimeout(ctx)
+	defer cancel()
+
+	_, err := db.ExecContext(ctx, `
+		UPDATE study_activities
+		SET name = ?, description = ?, thumbnail_url = ?, launch_url = ?, enabled = ?
+		WHERE id = ?`,
+		activity.Name, activity.Description, activity.ThumbnailURL,
+		activity.LaunchURL, activity.Enabled, activity.ID)
+	if err != nil {
+		return nil, writeError(err)
+	}
+
+	return db.GetStudyActivity(ctx, activity.ID)
+}
+
+func (db *DB) DeleteStudyActivity(ctx context.Context, id int64) error {
+	ctx, cancel := db.withTimeout(ctx)
+	defer cancel()
+
+	_, err := db.ExecContext(ctx, "DELETE FROM study_activities WHERE id = ?", id)
+	return deleteError(err)
+}
+
+func (db *DB) CountStudySessionsByActivity(ctx context.Context, activityID int64) (int, error) {
+	ctx, cancel := db.withTimeout(ctx)
+	defer cancel()
+
+	var count int
+	err := db.QueryRowContext(ctx, `
+		SELECT COUNT(*)
+		FROM study_sessions
+		WHERE study_activity_id = ?`, activityID).Scan(&count)
+	return count, err
+}
+
+// studySessionColumns are the columns read by scanStudySession from study
+// sessions aliased s, joined with studySessionJoins
+const studySessionColumns = `s.id, s.user_id, s.group_id, s.created_at, s.ended_at, s.status, s.study_activity_id,
+			g.name as group_name, COALESCE(a.name, '') as activity_name,
+			(SELECT COUNT(*) FROM word_review_items WHERE study_session_id = s.id) as review_count`
+
+const studySessionJoins = `
 		JOIN groups g ON s.group_id = g.id
-		WHERE s.id = ?`, id).Scan(
-		&session.ID, &session.GroupID, &session.CreatedAt,
-		&session.StudyActivityID, &session.GroupName, &session.ReviewItemCount)
+		LEFT JOIN study_activities a ON s.study_activity_id = a.id`
+
+func scanStudySession(row interface{ Scan(...interface{}) error }) (*StudySession, error) {
+	session := &StudySession{}
+	var endedAt sql.NullTime
+	err := row.Scan(
+		&session.ID, &session.UserID, &session.GroupID, &session.CreatedAt, &endedAt,
+		&session.Status, &session.StudyActivityID, &session.GroupName, &session.ActivityName,
+		&session.ReviewItemCount)
+	if err != nil {
+		return nil, err
+	}
+	if endedAt.Valid {
+		session.EndedAt = &endedAt.Time
+	}
+	return session, nil
+}
+
+// Study Session operations
+func (db *DB) CreateStudySession(ctx context.Context, userID, groupID, activityID int64) (*StudySession, error) {
+	ctx, cancel := db.withTimeout(ctx)
+	defer cancel()
+
+	id, err := createStudySession(ctx, db, userID, groupID, activityID, "")
+	if err != nil {
+		return nil, err
+	}
+	return db.GetStudySession(ctx, id)
+}
+
+// createStudySession inserts a study session, reported under an xAPI
+// registration unless registration is empty, and returns its id
+func createStudySession(ctx context.Context, db execer, userID, groupID, activityID int64, registration string) (int64, error) {
+	var reg sql.NullString
+	if registration != "" {
+		reg = sql.NullString{String: registration, Valid: true}
+	}
+	result, err := db.ExecContext(ctx, `
+		INSERT INTO study_sessions (user_id, group_id, study_activity_id, registration, created_at)
+		VALUES (?, ?, ?, ?, ?)`, userID, groupID, activityID, reg, time.Now())
+	if err != nil {
+		return 0, writeError(err)
+	}
+	return result.LastInsertId()
+}
+
+func (db *DB) GetStudySession(ctx context.Context, id int64) (*StudySession, error) {
+	ctx, cancel := db.withTimeout(ctx)
+	defer cancel()
+
+	session, err := scanStudySession(db.QueryRowContext(ctx, `
+		SELECT `+studySessionColumns+`
+		FROM study_sessions s`+studySessionJoins+`
+		WHERE s.id = ?`, id))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
 	return session, err
 }
 
-func (db *DB) GetLastStudySession() (*StudySession, error) {
-	session := &StudySession{}
-	err := db.QueryRow(`
-		SELECT s.id, s.group_id, s.created_at, s.study_activity_id,
-			g.name as group_name,
-			(SELECT COUNT(*) FROM word_review_items WHERE study_session_id = s.id) as review_count
-		FROM study_sessions s
-		JOIN groups g ON s.group_id = g.id
+// EndStudySession completes a study session at endedAt. It reports false if
+// the session is missing or no longer active.
+func (db *DB) EndStudySession(ctx context.Context, id int64, endedAt time.Time) (bool, error) {
+	ctx, cancel := db.withTimeout(ctx)
+	defer cancel()
+
+	return endStudySession(ctx, db, id, endedAt)
+}
+
+func endStudySession(ctx context.Context, db execer, id int64, endedAt time.Time) (bool, error) {
+	result, err := db.ExecContext(ctx, `
+		UPDATE study_sessions SET status = ?, ended_at = ?
+		WHERE id = ? AND status = ?`, StudySessionCompleted, endedAt, id, StudySessionActive)
+	if err != nil {
+		return false, err
+	}
+	n, err := result.RowsAffected()
+	return n > 0, err
+}
+
+// AbandonIdleStudySessions abandons the active study sessions with no review
+// or start since idleSince, ending them at their last review, and returns
+// how many it abandoned
+func (db *DB) AbandonIdleStudySessions(ctx context.Context, idleSince time.Time) (int64, error) {
+	ctx, cancel := db.withTimeout(ctx)
+	defer cancel()
+
+	result, err := db.ExecContext(ctx, `
+		WITH last_activity AS (
+			SELECT s.id, COALESCE(MAX(datetime(r.created_at)), datetime(s.created_at)) AS at
+			FROM study_sessions s
+			LEFT JOIN word_review_items r ON r.study_session_id = s.id
+			WHERE s.status = ?
+			GROUP BY s.id
+		)
+		UPDATE study_sessions SET
+			status = ?,
+			ended_at = (SELECT at FROM last_activity WHERE last_activity.id = study_sessions.id)
+		WHERE id IN (SELECT id FROM last_activity WHERE at < datetime(?))`,
+		StudySessionActive, StudySessionAbandoned, idleSince.UTC().Format(sqliteTimeFormat))
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+// GetStudySessionSummary returns a study session with what was reviewed in
+// it, or nil if there is none. DurationSeconds and Accuracy are left for the
+// caller, who knows the time.
+func (db *DB) GetStudySessionSummary(ctx context.Context, id int64) (*StudySessionSummary, error) {
+	ctx, cancel := db.withTimeout(ctx)
+	defer cancel()
+
+	session, err := db.GetStudySession(ctx, id)
+	if session == nil || err != nil {
+		return nil, err
+	}
+	summary := &StudySessionSummary{StudySession: *session}
+	err = db.QueryRowContext(ctx, `
+		SELECT COUNT(DISTINCT r.word_id), COALESCE(SUM(r.correct), 0),
+			COUNT(DISTINCT CASE WHEN EXISTS (
+				SELECT 1 FROM word_review_items earlier
+				WHERE earlier.user_id = r.user_id AND earlier.word_id = r.word_id
+					AND earlier.study_session_id <> r.study_session_id
+					AND datetime(earlier.created_at) < datetime(s.created_at)
+			) THEN r.word_id END)
+		FROM word_review_items r
+		JOIN study_sessions s ON s.id = r.study_session_id
+		WHERE r.study_session_id = ?`, id).Scan(&summary.WordsSeen, &summary.CorrectCount, &summary.RepeatedWords)
+	if err != nil {
+		return nil, err
+	}
+	summary.NewWords = summary.WordsSeen - summary.RepeatedWords
+	return summary, nil
+}
+
+// GetLastStudySession returns a user's most recent study session, or nil if
+// they have none
+func (db *DB) GetLastStudySession(ctx context.Context, userID int64) (*StudySession, error) {
+	ctx, cancel := db.withTimeout(ctx)
+	defer cancel()
+
+	session, err := scanStudySession(db.QueryRowContext(ctx, `
+		SELECT `+studySessionColumns+`
+		FROM study_sessions s`+studySessionJoins+`
+		WHERE s.user_id = ?
 		ORDER BY s.created_at DESC
-		LIMIT 1`).Scan(
-		&session.ID, &session.GroupID, &session.CreatedAt,
-		&session.StudyActivityID, &session.GroupName, &session.ReviewItemCount)
+		LIMIT 1`, userID))
 	if err == sql.ErrNoRows {
 		return nil, nil
 	}
 	return session, err
 }
 
-func (db *DB) GetStudyProgress() (*StudyProgress, error) {
+// GetStudyProgress counts the words a user has reviewed out of all words
+func (db *DB) GetStudyProgress(ctx context.Context, userID int64) (*StudyProgress, error) {
+	ctx, cancel := db.withTimeout(ctx)
+	defer cancel()
+
 	progress := &StudyProgress{}
 	
 	// Get total words studied (unique words that have been reviewed)
-	err := db.QueryRow(`
+	err := db.QueryRowContext(ctx, `
 		SELECT COUNT(DISTINCT word_id)
-		FROM word_review_items`).Scan(&progress.TotalWordsStudied)
+		FROM word_review_items
+		WHERE user_id = ?`, userID).Scan(&progress.TotalWordsStudied)
 	if err != nil {
 		return nil, err
 	}
 
 	// Get total available words
-	err = db.QueryRow(`
+	err = db.QueryRowContext(ctx, `
 		SELECT COUNT(*)
 		FROM words`).Scan(&progress.TotalAvailableWords)
 	if err != nil {
@@ -201,29 +842,71 @@ func (db *DB) GetStudyProgress() (*StudyProgress, error) {
 	return progress, nil
 }
 
-func (db *DB) GetStudySessionsByActivity(activityID int64, page, perPage int) ([]*StudySession, *Pagination, error) {
+func (db *DB) GetStudySessionsByActivity(ctx context.Context, userID, activityID int64, page, perPage int) ([]*StudySession, *Pagination, error) {
+	ctx, cancel := db.withTimeout(ctx)
+	defer cancel()
+
 	offset := (page - 1) * perPage
 	sessions := []*StudySession{}
 
-	rows, err := db.Query(`
-		SELECT s.id, s.group_id, s.created_at, s.study_activity_id,
-			g.name as group_name,
-			(SELECT COUNT(*) FROM word_review_items WHERE study_session_id = s.id) as review_count
-		FROM study_sessions s
-		JOIN groups g ON s.group_id = g.id
-		WHERE s.study_activity_id = ?
-		ORDER BY s.created_at DESC
-		LIMIT ? OFFSET ?`, activityID, perPage, offset)
+	rows, err := db.QueryContext(ctx, `
+		SELECT `+studySessionColumns+`
+		FROM study_sessions s`+studySessionJoins+`
+		WHERE s.user_id = ? AND s.study_activity_id = ?
+		ORDER BY datetime(s.created_at) DESC, s.id DESC
+		LIMIT ? OFFSET ?`, userID, activityID, perPage, offset)
 	if err != nil {
 		return nil, nil, err
 	}
 	defer rows.Close()
 
 	for rows.Next() {
-		session := &StudySession{}
-		err := rows.Scan(
-			&session.ID, &session.GroupID, &session.CreatedAt,
-			&session.StudyActivityID, &session.GroupName, &session.ReviewItemCount)
+		session, err := scanStudySession(rows)
+		if err != nil {
+			return nil, nil, err
+		}
+		sessions = append(sessions, session)
+	}
+
+	var total int
+	err = db.QueryRowContext(ctx, `
+		SELECT COUNT(*)
+		FROM study_sessions
+		WHERE user_id = ? AND study_activity_id = ?`, userID, activityID).Scan(&total)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	pagination := &Pagination{
+		CurrentPage:  page,
+		TotalPages:   (total + perPage - 1) / perPage,
+		TotalItems:   total,
+		ItemsPerPage: perPage,
+	}
+
+	return sessions, pagination, nil
+}
+
+func (db *DB) GetStudySessionsByGroup(ctx context.Context, userID, groupID int64, page, perPage int) ([]*StudySession, *Pagination, error) {
+	ctx, cancel := db.withTimeout(ctx)
+	defer cancel()
+
+	offset := (page - 1) * perPage
+	sessions := []*StudySession{}
+
+	rows, err := db.QueryContext(ctx, `
+		SELECT `+studySessionColumns+`
+		FROM study_sessions s`+studySessionJoins+`
+		WHERE s.user_id = ? AND s.group_id = ?
+		ORDER BY datetime(s.created_at) DESC, s.id DESC
+		LIMIT ? OFFSET ?`, userID, groupID, perPage, offset)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		session, err := scanStudySession(rows)
 		if err != nil {
 			return nil, nil, err
 		}
@@ -231,10 +914,79 @@ func (db *DB) GetStudySessionsByActivity(activityID int64, page, perPage int) ([
 	}
 
 	var total int
-	err = db.QueryRow(`
+	err = db.QueryRowContext(ctx, `
 		SELECT COUNT(*)
 		FROM study_sessions
-		WHERE study_activity_id = ?`, activityID).Scan(&total)
+		WHERE user_id = ? AND group_id = ?`, userID, groupID).Scan(&total)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	pagination := &Pagination{
+		CurrentPage:  page,
+		TotalPages:   (total + perPage - 1) / perPage,
+		TotalItems:   total,
+		ItemsPerPage: perPage,
+	}
+
+	return sessions, pagination, nil
+}
+
+// studySessionConditions narrows study sessions, aliased s, to a filter
+func studySessionConditions(filter StudySessionFilter) queryBuilder {
+	var q queryBuilder
+	if filter.UserID != 0 {
+		q.where("s.user_id = ?", filter.UserID)
+	}
+	if filter.GroupID != 0 {
+		q.where("s.group_id = ?", filter.GroupID)
+	}
+	if filter.StudyActivityID != 0 {
+		q.where("s.study_activity_id = ?", filter.StudyActivityID)
+	}
+	if !filter.From.IsZero() {
+		q.where("datetime(s.created_at) >= datetime(?)", filter.From.UTC().Format(sqliteTimeFormat))
+	}
+	if !filter.To.IsZero() {
+		q.where("datetime(s.created_at) < datetime(?)", filter.To.UTC().Format(sqliteTimeFormat))
+	}
+	return q
+}
+
+func (db *DB) GetStudySessions(ctx context.Context, filter StudySessionFilter, page, perPage int) ([]*StudySession, *Pagination, error) {
+	ctx, cancel := db.withTimeout(ctx)
+	defer cancel()
+
+	offset := (page - 1) * perPage
+	sessions := []*StudySession{}
+
+	q := studySessionConditions(filter)
+	where := q.whereClause()
+	order := orderBy(StudySessionSortColumns, filter.SortBy, filter.Order, "created_at", "desc", "s.id")
+
+	query := `
+		SELECT ` + studySessionColumns + `
+		FROM study_sessions s` + studySessionJoins + `
+		` + where + `
+		` + order + `
+		LIMIT ? OFFSET ?`
+
+	rows, err := db.QueryContext(ctx, query, append(q.args, perPage, offset)...)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		session, err := scanStudySession(rows)
+		if err != nil {
+			return nil, nil, err
+		}
+		sessions = append(sessions, session)
+	}
+
+	var total int
+	err = db.QueryRowContext(ctx, "SELECT COUNT(*) FROM study_sessions s "+where, q.args...).Scan(&total)
 	if err != nil {
 		return nil, nil, err
 	}
@@ -249,124 +1001,375 @@ func (db *DB) GetStudySessionsByActivity(activityID int64, page, perPage int) ([
 	return sessions, pagination, nil
 }
 
+// studySessionsAfterQuery is the query of GetStudySessionsAfter
+func studySessionsAfterQuery(filter StudySessionFilter, after Cursor, limit int) (string, []interface{}) {
+	q := studySessionConditions(filter)
+	order := keyset(&q, "s", after, !strings.EqualFold(filter.Order, "asc"))
+	return `
+		SELECT ` + studySessionColumns + `
+		FROM study_sessions s` + studySessionJoins + `
+		` + q.whereClause() + `
+		` + order + `
+		LIMIT ?`, append(q.args, limit)
+}
+
+// GetStudySessionsAfter returns up to limit study sessions matching filter
+// that come after cursor, oldest first if filter.Order is "asc" and newest
+// first otherwise. filter.SortBy is ignored: the listing is always by
+// creation time.
+func (db *DB) GetStudySessionsAfter(ctx context.Context, filter StudySessionFilter, after Cursor, limit int) ([]*StudySession, error) {
+	ctx, cancel := db.withTimeout(ctx)
+	defer cancel()
+
+	query, args := studySessionsAfterQuery(filter, after, limit)
+	rows, err := db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	sessions := []*StudySession{}
+	for rows.Next() {
+		session, err := scanStudySession(rows)
+		if err != nil {
+			return nil, err
+		}
+		sessions = append(sessions, session)
+	}
+	return sessions, rows.Err()
+}
+
+// ErrSessionNotActive means a review was made in a study session that is not
+// active, or does not exist
+var ErrSessionNotActive = NewError(ErrConflict, "study_session_not_active", "study session is not active")
+
 // Word Review operations
-func (db *DB) CreateWordReview(wordID, sessionID int64, correct bool) (*WordReviewItem, error) {
-	result, err := db.Exec(`
-		INSERT INTO word_review_items (word_id, study_session_id, correct, created_at)
-		VALUES (?, ?, ?, ?)`, wordID, sessionID, correct, time.Now())
+func (db *DB) CreateWordReview(ctx context.Context, review *WordReviewItem) (*WordReviewItem, error) {
+	ctx, cancel := db.withTimeout(ctx)
+	defer cancel()
+
+	return createWordReview(ctx, db, review)
+}
+
+func createWordReview(ctx context.Context, db execer, review *WordReviewItem) (*WordReviewItem, error) {
+	var answer, direction, statementID sql.NullString
+	if review.Answer != "" {
+		answer = sql.NullString{String: review.Answer, Valid: true}
+	}
+	if review.Direction != "" {
+		direction = sql.NullString{String: string(review.Direction), Valid: true}
+	}
+	if review.StatementID != "" {
+		statementID = sql.NullString{String: review.StatementID, Valid: true}
+	}
+
+	// the session is checked in the insert itself, so a review cannot slip
+	// into a session that ends between a check and the write
+	createdAt := time.Now()
+	result, err := db.ExecContext(ctx, `
+		INSERT INTO word_review_items
+			(user_id, word_id, study_session_id, correct, grade, response_time_ms, answer, direction, statement_id, created_at)
+		SELECT ?, ?, ?, ?, ?, ?, ?, ?, ?, ?
+		WHERE EXISTS (SELECT 1 FROM study_sessions WHERE id = ? AND status = ?)`,
+		review.UserID, review.WordID, review.StudySessionID, review.Grade.Correct(), review.Grade,
+		review.ResponseTimeMs, answer, direction, statementID, createdAt,
+		review.StudySessionID, StudySessionActive)
+	if err != nil {
+		return nil, writeError(err)
+	}
+	n, err := result.RowsAffected()
 	if err != nil {
 		return nil, err
 	}
+	if n == 0 {
+		return nil, ErrSessionNotActive
+	}
 
 	id, err := result.LastInsertId()
 	if err != nil {
 		return nil, err
 	}
 
-	review := &WordReviewItem{
-		ID:             id,
-		WordID:         wordID,
-		StudySessionID: sessionID,
-		Correct:        correct,
-		CreatedAt:      time.Now(),
+	created := *review
+	created.ID = id
+	created.Correct = review.Grade.Correct()
+	created.CreatedAt = createdAt
+	return &created, nil
+}
+
+// RecordReview records a review and reschedules its word with schedule in one
+// transaction, so a review is never recorded without its progress
+func (db *DB) RecordReview(ctx context.Context, review *WordReviewItem, schedule Scheduler) (*WordReviewItem, error) {
+	ctx, cancel := db.withTimeout(ctx)
+	defer cancel()
+
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	created, err := recordReview(ctx, tx, review, schedule)
+	if err != nil {
+		return nil, err
+	}
+	return created, tx.Commit()
+}
+
+func recordReview(ctx context.Context, tx *sql.Tx, review *WordReviewItem, schedule Scheduler) (*WordReviewItem, error) {
+	created, err := createWordReview(ctx, tx, review)
+	if err != nil {
+		return nil, err
+	}
+	progress, err := getWordProgress(ctx, tx, review.UserID, review.WordID)
+	if err != nil {
+		return nil, err
+	}
+	if err := saveWordProgress(ctx, tx, schedule(progress, created)); err != nil {
+		return nil, err
+	}
+	return created, nil
+}
+
+// Spaced repetition operations
+func (db *DB) GetWordProgress(ctx context.Context, userID, wordID int64) (*WordProgress, error) {
+	ctx, cancel := db.withTimeout(ctx)
+	defer cancel()
+
+	return getWordProgress(ctx, db, userID, wordID)
+}
+
+func getWordProgress(ctx context.Context, db querier, userID, wordID int64) (*WordProgress, error) {
+	progress := &WordProgress{}
+	var lastReviewedAt sql.NullTime
+	err := db.QueryRowContext(ctx, `
+		SELECT user_id, word_id, ease_factor, interval_days, repetitions, lapses, due_at, last_reviewed_at
+		FROM word_progress WHERE user_id = ? AND word_id = ?`, userID, wordID).Scan(
+		&progress.UserID, &progress.WordID, &progress.EaseFactor, &progress.IntervalDays,
+		&progress.Repetitions, &progress.Lapses, &progress.DueAt, &lastReviewedAt)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if lastReviewedAt.Valid {
+		progress.LastReviewedAt = &lastReviewedAt.Time
+	}
+	return progress, nil
+}
+
+func (db *DB) SaveWordProgress(ctx context.Context, progress *WordProgress) error {
+	ctx, cancel := db.withTimeout(ctx)
+	defer cancel()
+
+	return saveWordProgress(ctx, db, progress)
+}
+
+func saveWordProgress(ctx context.Context, db execer, progress *WordProgress) error {
+	_, err := db.ExecContext(ctx, `
+		INSERT INTO word_progress (user_id, word_id, ease_factor, interval_days, repetitions, lapses, due_at, last_reviewed_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT(user_id, word_id) DO UPDATE SET
+			ease_factor = excluded.ease_factor,
+			interval_days = excluded.interval_days,
+			repetitions = excluded.repetitions,
+			lapses = excluded.lapses,
+			due_at = excluded.due_at,
+			last_reviewed_at = excluded.last_reviewed_at`,
+		progress.UserID, progress.WordID, progress.EaseFactor, progress.IntervalDays,
+		progress.Repetitions, progress.Lapses, progress.DueAt, progress.LastReviewedAt)
+	return writeError(err)
+}
+
+// GetDueWords returns the words a user is due to review at or before now,
+// most overdue first. A groupID of 0 covers all words. When includeNew is set,
+// words the user has never reviewed follow the due ones.
+func (db *DB) GetDueWords(ctx context.Context, userID, groupID int64, now time.Time, includeNew bool, page, perPage int) ([]*DueWord, *Pagination, error) {
+	ctx, cancel := db.withTimeout(ctx)
+	defer cancel()
+
+	offset := (page - 1) * perPage
+	dueWords := []*DueWord{}
+
+	from := "FROM words w" + wordReviewStatsJoin("")
+	args := []interface{}{userID}
+	if groupID != 0 {
+		from = "FROM words w" + wordReviewStatsJoin(statsOfGroup) +
+			" JOIN words_groups wg ON w.id = wg.word_id AND wg.group_id = ?"
+		args = append(args, groupID, groupID)
+	}
+	from += `
+		LEFT JOIN word_progress p ON w.id = p.word_id AND p.user_id = ?
+		WHERE datetime(p.due_at) <= datetime(?) OR (p.word_id IS NULL AND ?)`
+	args = append(args, userID, now.UTC().Format(sqliteTimeFormat), includeNew)
+
+	rows, err := db.QueryContext(ctx, `
+		SELECT `+wordColumns+`,
+			p.word_id, p.ease_factor, p.interval_days, p.repetitions, p.lapses,
+			p.due_at, p.last_reviewed_at
+		`+from+`
+		ORDER BY p.word_id IS NULL, datetime(p.due_at), w.id
+		LIMIT ? OFFSET ?`, append(args, perPage, offset)...)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var progressWordID sql.NullInt64
+		var easeFactor sql.NullFloat64
+		var intervalDays, repetitions, lapses sql.NullInt64
+		var dueAt, lastReviewedAt sql.NullTime
+		word, err := scanWord(rows,
+			&progressWordID, &easeFactor, &intervalDays, &repetitions, &lapses,
+			&dueAt, &lastReviewedAt)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		dueWord := &DueWord{Word: word}
+		if progressWordID.Valid {
+			dueWord.Progress = &WordProgress{
+				UserID:       userID,
+				WordID:       progressWordID.Int64,
+				EaseFactor:   easeFactor.Float64,
+				IntervalDays: int(intervalDays.Int64),
+				Repetitions:  int(repetitions.Int64),
+				Lapses:       int(lapses.Int64),
+				DueAt:        dueAt.Time,
+			}
+			if lastReviewedAt.Valid {
+				dueWord.Progress.LastReviewedAt = &lastReviewedAt.Time
+			}
+		}
+		dueWords = append(dueWords, dueWord)
+	}
+
+	var total int
+	err = db.QueryRowContext(ctx, "SELECT COUNT(*) "+from, args...).Scan(&total)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	pagination := &Pagination{
+		CurrentPage:  page,
+		TotalPages:   (total + perPage - 1) / perPage,
+		TotalItems:   total,
+		ItemsPerPage: perPage,
 	}
-	return review, nil
+
+	return dueWords, pagination, nil
 }
 
 // Statistics operations
-func (db *DB) GetQuickStats() (*QuickStats, error) {
+
+// GetQuickStats summarises a user's study history
+func (db *DB) GetQuickStats(ctx context.Context, userID int64) (*QuickStats, error) {
+	ctx, cancel := db.withTimeout(ctx)
+	defer cancel()
+
 	stats := &QuickStats{}
 
 	// Get success rate
-	err := db.QueryRow(`
+	err := db.QueryRowContext(ctx, `
 		SELECT COALESCE(AVG(CASE WHEN correct THEN 100.0 ELSE 0.0 END), 0)
-		FROM word_review_items`).Scan(&stats.SuccessRate)
+		FROM word_review_items
+		WHERE user_id = ?`, userID).Scan(&stats.SuccessRate)
 	if err != nil {
 		return nil, err
 	}
 
 	// Get total study sessions
-	err = db.QueryRow(`
-		SELECT COUNT(*) FROM study_sessions`).Scan(&stats.TotalStudySessions)
+	err = db.QueryRowContext(ctx, `
+		SELECT COUNT(*) FROM study_sessions WHERE user_id = ?`, userID).Scan(&stats.TotalStudySessions)
 	if err != nil {
 		return nil, err
 	}
 
 	// Get total active groups
-	err = db.QueryRow(`
-		SELECT COUNT(DISTINCT group_id) 
-		FROM study_sessions 
-		WHERE created_at >= datetime('now', '-30 days')`).Scan(&stats.TotalActiveGroups)
+	err = db.QueryRowContext(ctx, `
+		SELECT COUNT(DISTINCT group_id)
+		FROM study_sessions
+		WHERE user_id = ? AND created_at >= datetime('now', '-30 days')`, userID).Scan(&stats.TotalActiveGroups)
 	if err != nil {
 		return nil, err
 	}
 
 	// Get study streak
-	err = db.QueryRow(`
-		WITH RECURSIVE dates(date) AS (
-			SELECT date(MAX(created_at)) FROM study_sessions
+	err = db.QueryRowContext(ctx, `
+		WITH RECURSIVE sessions AS (
+			SELECT created_at FROM study_sessions WHERE user_id = ?
+		), dates(date) AS (
+			SELECT date(MAX(created_at)) FROM sessions
 			UNION ALL
 			SELECT date(date, '-1 day')
 			FROM dates
 			WHERE date > date((
-				SELECT MIN(created_at) FROM study_sessions
+				SELECT MIN(created_at) FROM sessions
 			))
 		)
 		SELECT COUNT(*)
 		FROM dates d
 		WHERE EXISTS (
-			SELECT 1 FROM study_sessions
+			SELECT 1 FROM sessions
 			WHERE date(created_at) = d.date
-		)`).Scan(&stats.StudyStreakDays)
+		)`, userID).Scan(&stats.StudyStreakDays)
 
 	return stats, err
 }
 
 // System operations
-func (db *DB) ResetHistory() error {
-	tx, err := db.Begin()
-	if err != nil {
-		return err
-	}
+// ResetHistory deletes the study sessions, reviews and spaced-repetition state
+// of userID, or of every user when userID is 0
+func (db *DB) ResetHistory(ctx context.Context, userID int64) error {
+	ctx, cancel := db.withTimeout(ctx)
+	defer cancel()
 
-	_, err = tx.Exec("DELETE FROM word_review_items")
+	tx, err := db.BeginTx(ctx, nil)
 	if err != nil {
-		tx.Rollback()
 		return err
 	}
 
-	_, err = tx.Exec("DELETE FROM study_sessions")
-	if err != nil {
-		tx.Rollback()
-		return err
+	var where string
+	var args []interface{}
+	if userID != 0 {
+		where = " WHERE user_id = ?"
+		args = append(args, userID)
 	}
-
-	_, err = tx.Exec("DELETE FROM study_activities")
-	if err != nil {
-		tx.Rollback()
-		return err
+	for _, table := range []string{"word_review_items", "study_sessions", "word_progress"} {
+		_, err = tx.ExecContext(ctx, "DELETE FROM "+table+where, args...)
+		if err != nil {
+			tx.Rollback()
+			return err
+		}
 	}
 
 	return tx.Commit()
 }
 
-func (db *DB) FullReset() error {
-	tx, err := db.Begin()
+func (db *DB) FullReset(ctx context.Context) error {
+	ctx, cancel := db.withTimeout(ctx)
+	defer cancel()
+
+	tx, err := db.BeginTx(ctx, nil)
 	if err != nil {
 		return err
 	}
 
 	tables := []string{
+		"word_progress",
 		"word_review_items",
 		"study_sessions",
 		"study_activities",
 		"words_groups",
+		searchTable,
 		"words",
 		"groups",
 	}
 
 	for _, table := range tables {
-		_, err = tx.Exec("DELETE FROM " + table)
+		_, err = tx.ExecContext(ctx, "DELETE FROM " + table)
 		if err != nil {
 			tx.Rollback()
 			return err
@@ -376,98 +1379,47 @@ func (db *DB) FullReset() error {
 	return tx.Commit()
 }
 
-func (db *DB) GetWordsByGroup(groupID int64, page, perPage int) ([]*Word, *Pagination, error) {
-	offset := (page - 1) * perPage
-	words := []*Word{}
-
-	rows, err := db.Query(`
-		SELECT w.id, w.japanese, w.romaji, w.english, w.parts,
-			(SELECT COUNT(*) FROM word_review_items wri WHERE wri.word_id = w.id AND wri.correct = 1) as correct_count,
-			(SELECT COUNT(*) FROM word_review_items wri WHERE wri.word_id = w.id AND wri.correct = 0) as wrong_count
-		FROM words w
-		JOIN words_groups wg ON w.id = wg.word_id
-		WHERE wg.group_id = ?
-		LIMIT ? OFFSET ?`, groupID, perPage, offset)
-	if err != nil {
-		return nil, nil, err
-	}
-	defer rows.Close()
-
-	for rows.Next() {
-		word := &Word{}
-		var correctCount, wrongCount int
-		err := rows.Scan(
-			&word.ID, &word.Japanese, &word.Romaji, &word.English, &word.Parts,
-			&correctCount, &wrongCount)
-		if err != nil {
-			return nil, nil, err
-		}
-		if word.Parts.Valid {
-			err = json.Unmarshal([]byte(word.Parts.String), &word.PartsMap)
-			if err != nil {
-				return nil, nil, err
-			}
-		}
-		words = append(words, word)
-	}
-
-	var total int
-	err = db.QueryRow(`
-		SELECT COUNT(*)
-		FROM words_groups
-		WHERE group_id = ?`, groupID).Scan(&total)
-	if err != nil {
-		return nil, nil, err
-	}
-
-	pagination := &Pagination{
-		CurrentPage:  page,
-		TotalPages:   (total + perPage - 1) / perPage,
-		TotalItems:   total,
-		ItemsPerPage: perPage,
-	}
+func (db *DB) GetWordsByGroup(ctx context.Context, groupID int64, filter WordFilter, page, perPage int) ([]*Word, *Pagination, error) {
+	ctx, cancel := db.withTimeout(ctx)
+	defer cancel()
 
-	return words, pagination, nil
+	filter.GroupID = groupID
+	return db.GetWords(ctx, filter, page, perPage)
 }
 
-func (db *DB) GetWordsByStudySession(sessionID int64, page, perPage int) ([]*Word, *Pagination, error) {
+// GetWordsByStudySession returns the words reviewed in a study session with
+// userID's review stats
+func (db *DB) GetWordsByStudySession(ctx context.Context, userID, sessionID int64, page, perPage int) ([]*Word, *Pagination, error) {
+	ctx, cancel := db.withTimeout(ctx)
+	defer cancel()
+
 	offset := (page - 1) * perPage
 	words := []*Word{}
 
-	rows, err := db.Query(`
-		SELECT w.id, w.japanese, w.romaji, w.english, w.parts,
-			(SELECT COUNT(*) FROM word_review_items wri WHERE wri.word_id = w.id AND wri.correct = 1) as correct_count,
-			(SELECT COUNT(*) FROM word_review_items wri WHERE wri.word_id = w.id AND wri.correct = 0) as wrong_count
-		FROM words w
-		JOIN word_review_items wri ON w.id = wri.word_id
-		WHERE wri.study_session_id = ?
-		GROUP BY w.id
-		LIMIT ? OFFSET ?`, sessionID, perPage, offset)
+	rows, err := db.QueryContext(ctx, `
+		SELECT `+wordColumns+`
+		FROM words w`+wordReviewStatsJoin(statsOfSession)+`
+		WHERE EXISTS (
+			SELECT 1 FROM word_review_items wri
+			WHERE wri.word_id = w.id AND wri.study_session_id = ?
+		)
+		ORDER BY w.id
+		LIMIT ? OFFSET ?`, userID, sessionID, sessionID, perPage, offset)
 	if err != nil {
 		return nil, nil, err
 	}
 	defer rows.Close()
 
 	for rows.Next() {
-		word := &Word{}
-		var correctCount, wrongCount int
-		err := rows.Scan(
-			&word.ID, &word.Japanese, &word.Romaji, &word.English, &word.Parts,
-			&correctCount, &wrongCount)
+		word, err := scanWord(rows)
 		if err != nil {
 			return nil, nil, err
 		}
-		if word.Parts.Valid {
-			err = json.Unmarshal([]byte(word.Parts.String), &word.PartsMap)
-			if err != nil {
-				return nil, nil, err
-			}
-		}
 		words = append(words, word)
 	}
 
 	var total int
-	err = db.QueryRow(`
+	err = db.QueryRowContext(ctx, `
 		SELECT COUNT(DISTINCT word_id)
 		FROM word_review_items
 		WHERE study_session_id = ?`, sessionID).Scan(&total)
@@ -0,0 +1,579 @@
+package models
+
+import (
+	"context"
+	"database/sql"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gen-ai-bootcamp-2025/backend_go/internal/testdb"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCloseCheckpointsWAL(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "words.db")
+	raw, err := sql.Open("sqlite3", path)
+	require.NoError(t, err)
+	raw.SetMaxOpenConns(1)
+	_, err = raw.Exec(`
+		PRAGMA journal_mode = WAL;
+		CREATE TABLE groups (id INTEGER PRIMARY KEY AUTOINCREMENT, name TEXT NOT NULL);
+		INSERT INTO groups (name) VALUES ('Animals')`)
+	require.NoError(t, err)
+
+	require.NoError(t, NewDB(raw).Close())
+	if info, err := os.Stat(path + "-wal"); err == nil {
+		assert.Zero(t, info.Size(), "the write-ahead log is written back")
+	}
+
+	raw, err = sql.Open("sqlite3", path+"?mode=ro")
+	require.NoError(t, err)
+	defer raw.Close()
+	var name string
+	require.NoError(t, raw.QueryRow("SELECT name FROM groups").Scan(&name))
+	assert.Equal(t, "Animals", name)
+}
+
+func TestQueriesStopWithTheirContext(t *testing.T) {
+	raw, err := sql.Open("sqlite3", ":memory:")
+	require.NoError(t, err)
+	defer raw.Close()
+	raw.SetMaxOpenConns(1)
+	_, err = raw.Exec(`
+		CREATE TABLE words (id INTEGER PRIMARY KEY AUTOINCREMENT, japanese TEXT NOT NULL);
+		INSERT INTO words (japanese) VALUES ('猫')`)
+	require.NoError(t, err)
+	db := NewDB(raw)
+
+	missing, err := db.GetMissingWordIDs(context.Background(), []int64{3, 1, 2})
+	require.NoError(t, err)
+	assert.Equal(t, []int64{3, 2}, missing)
+	missing, err = db.GetMissingWordIDs(context.Background(), nil)
+	require.NoError(t, err)
+	assert.Empty(t, missing)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err = db.GetMissingWordIDs(ctx, []int64{1})
+	assert.ErrorIs(t, err, context.Canceled)
+
+	// With the only connection held, the query waits until its timeout
+	tx, err := raw.Begin()
+	require.NoError(t, err)
+	defer tx.Rollback()
+	db.SetQueryTimeout(50 * time.Millisecond)
+	start := time.Now()
+	_, err = db.GetMissingWordIDs(context.Background(), []int64{1})
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.Less(t, time.Since(start), 5*time.Second)
+}
+
+// openTestDB opens a new database file at the current schema with its
+// search index, the way the server does
+func openTestDB(t *testing.T) *DB {
+	db := NewDB(testdb.Open(t, DSN))
+	require.NoError(t, db.InitSearchIndex(context.Background()))
+	return db
+}
+
+func TestDSN(t *testing.T) {
+	db := openTestDB(t)
+	for pragma, want := range map[string]string{
+		"foreign_keys": "1",
+		"journal_mode": "wal",
+		"busy_timeout": "5000",
+	} {
+		var got string
+		require.NoError(t, db.QueryRow("PRAGMA "+pragma).Scan(&got))
+		assert.Equal(t, want, got, pragma)
+	}
+}
+
+func TestConstraintErrors(t *testing.T) {
+	db := openTestDB(t)
+	ctx := context.Background()
+
+	group, err := db.CreateGroup(ctx, "Animals")
+	require.NoError(t, err)
+	_, err = db.CreateGroup(ctx, "Animals")
+	assert.ErrorIs(t, err, ErrDuplicate)
+	assert.ErrorIs(t, err, ErrConflict)
+	assert.NotErrorIs(t, err, ErrReferenced)
+	assert.EqualError(t, err, "already exists (groups.name)")
+
+	_, err = db.CreateUser(ctx, "hana", Role("owner"))
+	assert.ErrorIs(t, err, ErrInvalidValue)
+	assert.ErrorIs(t, err, ErrValidation)
+
+	_, err = db.CreateStudySession(ctx, 1, 99, 0)
+	assert.ErrorIs(t, err, ErrMissingReference)
+
+	activity, err := db.CreateStudyActivity(ctx, &StudyActivity{Name: "Cards", LaunchURL: "http://localhost", Enabled: true})
+	require.NoError(t, err)
+	session, err := db.CreateStudySession(ctx, 1, group.ID, activity.ID)
+	require.NoError(t, err)
+	assert.ErrorIs(t, db.DeleteGroup(ctx, group.ID), ErrReferenced)
+	assert.ErrorIs(t, db.DeleteStudyActivity(ctx, activity.ID), ErrReferenced)
+
+	_, err = db.CreateWordReview(ctx, &WordReviewItem{UserID: 1, WordID: 99, StudySessionID: session.ID, Grade: GradeGood})
+	assert.ErrorIs(t, err, ErrMissingReference)
+
+	_, err = db.EndStudySession(ctx, session.ID, time.Now())
+	require.NoError(t, err)
+	_, err = db.CreateWordReview(ctx, &WordReviewItem{UserID: 1, WordID: 1, StudySessionID: session.ID, Grade: GradeGood})
+	assert.ErrorIs(t, err, ErrSessionNotActive)
+	assert.ErrorIs(t, err, ErrConflict)
+}
+
+func TestDeleteWordCascades(t *testing.T) {
+	db := openTestDB(t)
+	ctx := context.Background()
+
+	word, err := db.CreateWord(ctx, &Word{Japanese: "猫", Romaji: "neko", English: "cat"})
+	require.NoError(t, err)
+	group, err := db.CreateGroup(ctx, "Animals")
+	require.NoError(t, err)
+	_, err = db.AddWordsToGroup(ctx, group.ID, []int64{word.ID})
+	require.NoError(t, err)
+	activity, err := db.CreateStudyActivity(ctx, &StudyActivity{Name: "Cards", LaunchURL: "http://localhost", Enabled: true})
+	require.NoError(t, err)
+	session, err := db.CreateStudySession(ctx, 1, group.ID, activity.ID)
+	require.NoError(t, err)
+	_, err = db.CreateWordReview(ctx, &WordReviewItem{UserID: 1, WordID: word.ID, StudySessionID: session.ID, Grade: GradeGood})
+	require.NoError(t, err)
+	require.NoError(t, db.SaveWordProgress(ctx, &WordProgress{UserID: 1, WordID: word.ID, EaseFactor: 2.5, DueAt: time.Now()}))
+
+	require.NoError(t, db.DeleteWord(ctx, word.ID))
+	for _, table := range []string{"words_groups", "word_review_items", "word_progress", searchTable} {
+		var n int
+		require.NoError(t, db.QueryRow("SELECT COUNT(*) FROM "+table).Scan(&n))
+		assert.Zero(t, n, table)
+	}
+
+	require.NoError(t, db.DeleteGroup(ctx, group.ID+1), "deleting a missing group is not an error")
+	_, err = db.ExecContext(ctx, "DELETE FROM study_sessions")
+	require.NoError(t, err)
+	require.NoError(t, db.DeleteGroup(ctx, group.ID))
+}
+
+func TestWordWritesKeepSearchIndex(t *testing.T) {
+	db := openTestDB(t)
+	ctx := context.Background()
+
+	word, err := db.CreateWord(ctx, &Word{Japanese: "猫", Romaji: "neko", English: "cat"})
+	require.NoError(t, err)
+	word.English = "kitten"
+	_, err = db.UpdateWord(ctx, word)
+	require.NoError(t, err)
+	found, _, err := db.SearchWords(ctx, DefaultUserID, "kitten", 1, 10)
+	require.NoError(t, err)
+	require.Len(t, found, 1)
+	assert.Equal(t, word.ID, found[0].ID)
+
+	// with the index gone, neither write is kept
+	_, err = db.Exec("DROP TABLE " + searchTable)
+	require.NoError(t, err)
+	_, err = db.CreateWord(ctx, &Word{Japanese: "犬", Romaji: "inu", English: "dog"})
+	assert.Error(t, err)
+	word.English = "cat"
+	_, err = db.UpdateWord(ctx, word)
+	assert.Error(t, err)
+
+	var count int
+	require.NoError(t, db.QueryRow("SELECT COUNT(*) FROM words").Scan(&count))
+	assert.Equal(t, 1, count)
+	got, err := db.GetWord(ctx, DefaultUserID, word.ID)
+	require.NoError(t, err)
+	assert.Equal(t, "kitten", got.English)
+}
+
+func TestWordStatsArePerUser(t *testing.T) {
+	db := openTestDB(t)
+	ctx := context.Background()
+
+	word, err := db.CreateWord(ctx, &Word{Japanese: "猫", Romaji: "neko", English: "cat"})
+	require.NoError(t, err)
+	group, err := db.CreateGroup(ctx, "Animals")
+	require.NoError(t, err)
+	_, err = db.AddWordsToGroup(ctx, group.ID, []int64{word.ID})
+	require.NoError(t, err)
+	activity, err := db.CreateStudyActivity(ctx, &StudyActivity{Name: "Cards", LaunchURL: "http://localhost", Enabled: true})
+	require.NoError(t, err)
+	other, err := db.CreateUser(ctx, "ken", RoleLearner)
+	require.NoError(t, err)
+	session, err := db.CreateStudySession(ctx, other.ID, group.ID, activity.ID)
+	require.NoError(t, err)
+	for _, grade := range []Grade{GradeGood, GradeAgain} {
+		_, err = db.CreateWordReview(ctx, &WordReviewItem{UserID: other.ID, WordID: word.ID, StudySessionID: session.ID, Grade: grade})
+		require.NoError(t, err)
+	}
+
+	got, err := db.GetWord(ctx, DefaultUserID, word.ID)
+	require.NoError(t, err)
+	assert.Zero(t, got.CorrectCount, "another user's reviews are not counted")
+	assert.Nil(t, got.LastReviewedAt)
+	got, err = db.GetWord(ctx, other.ID, word.ID)
+	require.NoError(t, err)
+	assert.Equal(t, 1, got.CorrectCount)
+	assert.Equal(t, 1, got.WrongCount)
+
+	neverReviewed := true
+	words, _, err := db.GetWordsByGroup(ctx, group.ID, WordFilter{UserID: DefaultUserID, NeverReviewed: &neverReviewed}, 1, 10)
+	require.NoError(t, err)
+	assert.Len(t, words, 1, "the word is unreviewed for this user")
+	words, _, err = db.GetWords(ctx, WordFilter{UserID: other.ID, NeverReviewed: &neverReviewed}, 1, 10)
+	require.NoError(t, err)
+	assert.Empty(t, words)
+
+	var exported []*Word
+	require.NoError(t, db.EachGroupWord(ctx, DefaultUserID, group.ID, func(w *Word) error {
+		exported = append(exported, w)
+		return nil
+	}))
+	require.Len(t, exported, 1)
+	assert.Zero(t, exported[0].WrongCount)
+
+	outside, err := db.CreateWord(ctx, &Word{Japanese: "犬", Romaji: "inu", English: "dog"})
+	require.NoError(t, err)
+	_, err = db.CreateWordReview(ctx, &WordReviewItem{UserID: other.ID, WordID: outside.ID, StudySessionID: session.ID, Grade: GradeGood})
+	require.NoError(t, err)
+	words, _, err = db.GetWordsByGroup(ctx, group.ID, WordFilter{UserID: other.ID}, 1, 10)
+	require.NoError(t, err)
+	require.Len(t, words, 1)
+	assert.Equal(t, 1, words[0].CorrectCount, "reviews of words outside the group are not counted")
+	got, err = db.GetWord(ctx, other.ID, outside.ID)
+	require.NoError(t, err)
+	assert.Equal(t, 1, got.CorrectCount)
+	assert.Zero(t, got.WrongCount)
+}
+
+func TestResetHistory(t *testing.T) {
+	db := openTestDB(t)
+	ctx := context.Background()
+
+	word, err := db.CreateWord(ctx, &Word{Japanese: "猫", Romaji: "neko", English: "cat"})
+	require.NoError(t, err)
+	group, err := db.CreateGroup(ctx, "Animals")
+	require.NoError(t, err)
+	activity, err := db.CreateStudyActivity(ctx, &StudyActivity{Name: "Cards", LaunchURL: "http://localhost", Enabled: true})
+	require.NoError(t, err)
+	other, err := db.CreateUser(ctx, "ken", RoleLearner)
+	require.NoError(t, err)
+	for _, userID := range []int64{DefaultUserID, other.ID} {
+		session, err := db.CreateStudySession(ctx, userID, group.ID, activity.ID)
+		require.NoError(t, err)
+		_, err = db.CreateWordReview(ctx, &WordReviewItem{UserID: userID, WordID: word.ID, StudySessionID: session.ID, Grade: GradeGood})
+		require.NoError(t, err)
+		require.NoError(t, db.SaveWordProgress(ctx, &WordProgress{UserID: userID, WordID: word.ID, EaseFactor: 2.5, DueAt: time.Now()}))
+	}
+	count := func(table string, userID int64) int {
+		var n int
+		require.NoError(t, db.QueryRow("SELECT COUNT(*) FROM "+table+" WHERE user_id = ?", userID).Scan(&n))
+		return n
+	}
+	tables := []string{"study_sessions", "word_review_items", "word_progress"}
+
+	require.NoError(t, db.ResetHistory(ctx, other.ID))
+	for _, table := range tables {
+		assert.Zero(t, count(table, other.ID), "%s of the user", table)
+		assert.Equal(t, 1, count(table, DefaultUserID), "%s of other users are kept", table)
+	}
+
+	require.NoError(t, db.ResetHistory(ctx, 0))
+	for _, table := range tables {
+		assert.Zero(t, count(table, DefaultUserID), "%s of every user", table)
+	}
+	var words int
+	require.NoError(t, db.QueryRow("SELECT COUNT(*) FROM words").Scan(&words))
+	assert.Equal(t, 1, words, "vocabulary is kept")
+}
+
+func TestRecordStatementsIsAllOrNothing(t *testing.T) {
+	db := openTestDB(t)
+	ctx := context.Background()
+
+	word, err := db.CreateWord(ctx, &Word{Japanese: "猫", Romaji: "neko", English: "cat"})
+	require.NoError(t, err)
+	group, err := db.CreateGroup(ctx, "Animals")
+	require.NoError(t, err)
+	activity, err := db.CreateStudyActivity(ctx, &StudyActivity{Name: "Cards", LaunchURL: "http://localhost", Enabled: true})
+	require.NoError(t, err)
+	schedule := func(progress *WordProgress, review *WordReviewItem) *WordProgress {
+		if progress == nil {
+			progress = &WordProgress{UserID: review.UserID, WordID: review.WordID, EaseFactor: 2.5}
+		}
+		progress.Repetitions++
+		return progress
+	}
+	const registration = "0b9e5c1d-2f3a-4b6c-8d7e-9f0a1b2c3d4e"
+	review := func(statementID string, wordID int64) *StatementRecord {
+		return &StatementRecord{
+			Registration: registration, GroupID: group.ID, ActivityID: activity.ID,
+			Review: &WordReviewItem{WordID: wordID, Grade: GradeGood, StatementID: statementID},
+		}
+	}
+	count := func(table string) int {
+		var n int
+		require.NoError(t, db.QueryRow("SELECT COUNT(*) FROM "+table).Scan(&n))
+		return n
+	}
+
+	err = db.RecordStatements(ctx, DefaultUserID, []*StatementRecord{
+		review("2d6e0f4a-1b3c-4d5e-8f7a-000000000001", word.ID),
+		review("2d6e0f4a-1b3c-4d5e-8f7a-000000000002", word.ID+1),
+	}, schedule)
+	assert.ErrorIs(t, err, ErrMissingReference)
+	for _, table := range []string{"study_sessions", "word_review_items", "word_progress"} {
+		assert.Zero(t, count(table), "%s after a failed batch", table)
+	}
+
+	batch := []*StatementRecord{
+		review("2d6e0f4a-1b3c-4d5e-8f7a-000000000001", word.ID),
+		review("2d6e0f4a-1b3c-4d5e-8f7a-000000000002", word.ID),
+	}
+	require.NoError(t, db.RecordStatements(ctx, DefaultUserID, batch, schedule))
+	require.NoError(t, db.RecordStatements(ctx, DefaultUserID, batch, schedule), "a repeated batch is skipped")
+	assert.Equal(t, 1, count("study_sessions"), "the registration starts one session")
+	assert.Equal(t, 2, count("word_review_items"))
+	progress, err := db.GetWordProgress(ctx, DefaultUserID, word.ID)
+	require.NoError(t, err)
+	assert.Equal(t, 2, progress.Repetitions, "each review builds on the one before")
+
+	other, err := db.CreateUser(ctx, "ken", RoleLearner)
+	require.NoError(t, err)
+	err = db.RecordStatements(ctx, other.ID, []*StatementRecord{
+		{Registration: "7c2d4e6f-8a9b-4c1d-9e2f-3a4b5c6d7e8f", GroupID: group.ID, ActivityID: activity.ID,
+			Review: &WordReviewItem{WordID: word.ID, Grade: GradeGood, StatementID: "2d6e0f4a-1b3c-4d5e-8f7a-000000000001"}},
+	}, schedule)
+	assert.ErrorIs(t, err, ErrDuplicate, "another user's statement id is not skipped")
+	recorded, err := db.GetRecordedStatementIDs(ctx, other.ID, []string{"2d6e0f4a-1b3c-4d5e-8f7a-000000000001"})
+	require.NoError(t, err)
+	assert.Empty(t, recorded)
+	assert.Equal(t, 1, count("study_sessions"))
+}
+
+func TestRecordReviewIsAtomic(t *testing.T) {
+	db := openTestDB(t)
+	ctx := context.Background()
+
+	word, err := db.CreateWord(ctx, &Word{Japanese: "猫", Romaji: "neko", English: "cat"})
+	require.NoError(t, err)
+	group, err := db.CreateGroup(ctx, "Animals")
+	require.NoError(t, err)
+	activity, err := db.CreateStudyActivity(ctx, &StudyActivity{Name: "Cards", LaunchURL: "http://localhost", Enabled: true})
+	require.NoError(t, err)
+	session, err := db.CreateStudySession(ctx, DefaultUserID, group.ID, activity.ID)
+	require.NoError(t, err)
+	review := &WordReviewItem{UserID: DefaultUserID, WordID: word.ID, StudySessionID: session.ID, Grade: GradeGood}
+
+	_, err = db.RecordReview(ctx, review, func(progress *WordProgress, review *WordReviewItem) *WordProgress {
+		return &WordProgress{UserID: review.UserID, WordID: review.WordID + 1, EaseFactor: 2.5}
+	})
+	assert.ErrorIs(t, err, ErrMissingReference)
+	var reviews int
+	require.NoError(t, db.QueryRow("SELECT COUNT(*) FROM word_review_items").Scan(&reviews))
+	assert.Zero(t, reviews, "the review is not kept when its progress fails")
+
+	created, err := db.RecordReview(ctx, review, func(progress *WordProgress, review *WordReviewItem) *WordProgress {
+		assert.Nil(t, progress)
+		return &WordProgress{UserID: review.UserID, WordID: review.WordID, EaseFactor: 2.5, Repetitions: 1}
+	})
+	require.NoError(t, err)
+	assert.NotZero(t, created.ID)
+	progress, err := db.GetWordProgress(ctx, DefaultUserID, word.ID)
+	require.NoError(t, err)
+	assert.Equal(t, 1, progress.Repetitions)
+}
+
+func TestCursorListings(t *testing.T) {
+	db := openTestDB(t)
+	ctx := context.Background()
+
+	group, err := db.CreateGroup(ctx, "Animals")
+	require.NoError(t, err)
+	activity, err := db.CreateStudyActivity(ctx, &StudyActivity{Name: "Cards", LaunchURL: "http://localhost", Enabled: true})
+	require.NoError(t, err)
+	word, err := db.CreateWord(ctx, &Word{Japanese: "猫", Romaji: "neko", English: "cat"})
+	require.NoError(t, err)
+
+	// three sessions and reviews in the same second, written in the formats
+	// of CURRENT_TIMESTAMP and of the driver
+	for i, createdAt := range []string{
+		"2025-01-01 10:00:00",
+		"2025-01-01 10:00:00",
+		"2025-01-01 11:00:00.5+01:00",
+		"2025-01-02 09:00:00",
+		"2024-12-31 23:59:59",
+	} {
+		_, err := db.Exec(`INSERT INTO study_sessions (id, user_id, group_id, study_activity_id, created_at) VALUES (?, 1, ?, ?, ?)`,
+			i+1, group.ID, activity.ID, createdAt)
+		require.NoError(t, err)
+		_, err = db.Exec(`INSERT INTO word_review_items (id, user_id, word_id, study_session_id, correct, created_at)
+			VALUES (?, 1, ?, ?, 1, ?)`, i+1, word.ID, i+1, createdAt)
+		require.NoError(t, err)
+	}
+
+	for order, want := range map[string][]int64{
+		"desc": {4, 3, 2, 1, 5},
+		"asc":  {5, 1, 2, 3, 4},
+	} {
+		filter := StudySessionFilter{UserID: 1, Order: order}
+
+		var sessionIDs, reviewIDs []int64
+		var after Cursor
+		for {
+			sessions, err := db.GetStudySessionsAfter(ctx, filter, after, 2)
+			require.NoError(t, err)
+			if len(sessions) == 0 {
+				break
+			}
+			for _, s := range sessions {
+				sessionIDs = append(sessionIDs, s.ID)
+			}
+			last := sessions[len(sessions)-1]
+			after, err = ParseCursor(CursorAfter(last.CreatedAt, last.ID).String())
+			require.NoError(t, err)
+		}
+		after = Cursor{}
+		for {
+			reviews, err := db.GetReviewsAfter(ctx, filter, after, 2)
+			require.NoError(t, err)
+			if len(reviews) == 0 {
+				break
+			}
+			for _, r := range reviews {
+				reviewIDs = append(reviewIDs, r.ID)
+			}
+			last := reviews[len(reviews)-1]
+			after = CursorAfter(last.CreatedAt, last.ID)
+		}
+		assert.Equal(t, want, sessionIDs, order)
+		assert.Equal(t, want, reviewIDs, order)
+	}
+
+	// numbered pages come in the same order, so their cursors carry on
+	sessions, _, err := db.GetStudySessions(ctx, StudySessionFilter{UserID: 1}, 1, 2)
+	require.NoError(t, err)
+	rest, err := db.GetStudySessionsAfter(ctx, StudySessionFilter{UserID: 1}, CursorAfter(sessions[1].CreatedAt, sessions[1].ID), 5)
+	require.NoError(t, err)
+	require.Len(t, rest, 3)
+	assert.Equal(t, []int64{4, 3, 2, 1, 5}, []int64{sessions[0].ID, sessions[1].ID, rest[0].ID, rest[1].ID, rest[2].ID})
+}
+
+func TestCursorListingsUseIndexes(t *testing.T) {
+	db := openTestDB(t)
+
+	for _, after := range []Cursor{{}, CursorAfter(time.Now(), 5)} {
+		for _, order := range []string{"asc", "desc"} {
+			filter := StudySessionFilter{UserID: 1, Order: order}
+			for index, build := range map[string]func(StudySessionFilter, Cursor, int) (string, []interface{}){
+				"idx_study_sessions_user_created":    studySessionsAfterQuery,
+				"idx_word_review_items_user_created": reviewsAfterQuery,
+			} {
+				query, args := build(filter, after, 10)
+				rows, err := db.Query("EXPLAIN QUERY PLAN "+query, args...)
+				require.NoError(t, err)
+				var plan []string
+				for rows.Next() {
+					var id, parent, unused int
+					var detail string
+					require.NoError(t, rows.Scan(&id, &parent, &unused, &detail))
+					plan = append(plan, detail)
+				}
+				require.NoError(t, rows.Close())
+
+				all := strings.Join(plan, "\n")
+				assert.Contains(t, all, index, order)
+				assert.NotContains(t, all, "TEMP B-TREE", "%s pages are read in order", order)
+			}
+		}
+	}
+}
+
+func TestParseCursor(t *testing.T) {
+	cursor := CursorAfter(time.Date(2025, 1, 1, 11, 0, 0, 500, time.FixedZone("", 3600)), 42)
+	parsed, err := ParseCursor(cursor.String())
+	require.NoError(t, err)
+	assert.Equal(t, int64(42), parsed.ID)
+	assert.True(t, parsed.CreatedAt.Equal(time.Date(2025, 1, 1, 10, 0, 0, 0, time.UTC)))
+
+	empty, err := ParseCursor("")
+	assert.NoError(t, err)
+	assert.True(t, empty.IsZero())
+
+	for _, bogus := range []string{"42", "not base64!", "MjAyNS0wMS0wMSAxMDowMDowMA"} {
+		_, err := ParseCursor(bogus)
+		assert.Error(t, err, bogus)
+	}
+}
+
+func TestStudySessionLifecycle(t *testing.T) {
+	db := openTestDB(t)
+	ctx := context.Background()
+
+	group, err := db.CreateGroup(ctx, "Animals")
+	require.NoError(t, err)
+	activity, err := db.CreateStudyActivity(ctx, &StudyActivity{Name: "Cards", LaunchURL: "http://localhost", Enabled: true})
+	require.NoError(t, err)
+	cat, err := db.CreateWord(ctx, &Word{Japanese: "猫", Romaji: "neko", English: "cat"})
+	require.NoError(t, err)
+	dog, err := db.CreateWord(ctx, &Word{Japanese: "犬", Romaji: "inu", English: "dog"})
+	require.NoError(t, err)
+
+	// cat was studied yesterday, in a session that is still open
+	_, err = db.Exec(`INSERT INTO study_sessions (id, user_id, group_id, study_activity_id, created_at) VALUES (1, 1, ?, ?, ?)`,
+		group.ID, activity.ID, "2025-01-01 10:00:00")
+	require.NoError(t, err)
+	_, err = db.Exec(`INSERT INTO word_review_items (user_id, word_id, study_session_id, correct, created_at) VALUES (1, ?, 1, 1, ?)`,
+		cat.ID, "2025-01-01 10:05:00")
+	require.NoError(t, err)
+
+	session, err := db.CreateStudySession(ctx, 1, group.ID, activity.ID)
+	require.NoError(t, err)
+	assert.Equal(t, StudySessionActive, session.Status)
+	assert.Nil(t, session.EndedAt)
+	for _, review := range []struct {
+		word  int64
+		grade Grade
+	}{{cat.ID, GradeGood}, {dog.ID, GradeAgain}, {dog.ID, GradeHard}} {
+		_, err := db.CreateWordReview(ctx, &WordReviewItem{UserID: 1, WordID: review.word, StudySessionID: session.ID, Grade: review.grade})
+		require.NoError(t, err)
+	}
+
+	n, err := db.AbandonIdleStudySessions(ctx, time.Now().Add(-30*time.Minute))
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), n, "only the old session is idle")
+	old, err := db.GetStudySession(ctx, 1)
+	require.NoError(t, err)
+	assert.Equal(t, StudySessionAbandoned, old.Status)
+	require.NotNil(t, old.EndedAt)
+	assert.True(t, old.EndedAt.Equal(time.Date(2025, 1, 1, 10, 5, 0, 0, time.UTC)), "ended at its last review")
+
+	ended, err := db.EndStudySession(ctx, session.ID, time.Now())
+	require.NoError(t, err)
+	assert.True(t, ended)
+	ended, err = db.EndStudySession(ctx, session.ID, time.Now())
+	require.NoError(t, err)
+	assert.False(t, ended, "a session ends once")
+	ended, err = db.EndStudySession(ctx, old.ID, time.Now())
+	require.NoError(t, err)
+	assert.False(t, ended, "abandoned sessions stay abandoned")
+
+	summary, err := db.GetStudySessionSummary(ctx, session.ID)
+	require.NoError(t, err)
+	assert.Equal(t, StudySessionCompleted, summary.Status)
+	assert.NotNil(t, summary.EndedAt)
+	assert.Equal(t, 3, summary.ReviewItemCount)
+	assert.Equal(t, 2, summary.WordsSeen)
+	assert.Equal(t, 2, summary.CorrectCount)
+	assert.Equal(t, 1, summary.NewWords)
+	assert.Equal(t, 1, summary.RepeatedWords)
+
+	summary, err = db.GetStudySessionSummary(ctx, 99)
+	assert.NoError(t, err)
+	assert.Nil(t, summary)
+}
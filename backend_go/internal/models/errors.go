@@ -0,0 +1,43 @@
+package models
+
+import "errors"
+
+// Kinds of failure. Every error the models and service packages return on
+// purpose is one of these kinds, so callers can decide what to do about an
+// error, such as which HTTP status to answer with, without knowing each one.
+var (
+	// ErrNotFound means something the caller asked for or referred to does
+	// not exist
+	ErrNotFound = errors.New("not found")
+	// ErrConflict means the request clashes with the current state, such as a
+	// name that is taken or a row that is still in use
+	ErrConflict = errors.New("conflict")
+	// ErrValidation means the request itself is malformed
+	ErrValidation = errors.New("validation failed")
+	// ErrUnauthorized means it is not known who is asking
+	ErrUnauthorized = errors.New("unauthorized")
+	// ErrForbidden means whoever is asking may not do this
+	ErrForbidden = errors.New("forbidden")
+)
+
+// Error is an error of one of the kinds above with a machine-readable code,
+// such as "word_not_found", that stays the same when the message changes.
+// errors.Is matches an Error against both itself and its kind.
+type Error struct {
+	Kind    error
+	Code    string
+	Message string
+}
+
+// NewError returns an Error of kind with code and message
+func NewError(kind error, code, message string) *Error {
+	return &Error{Kind: kind, Code: code, Message: message}
+}
+
+func (e *Error) Error() string {
+	return e.Message
+}
+
+func (e *Error) Is(target error) bool {
+	return target == e.Kind
+}
@@ -0,0 +1,163 @@
+package models
+
+import (
+	"context"
+	"database/sql"
+	"strings"
+	"time"
+)
+
+// ReviewExport is one review together with the session, group and word it
+// belongs to, so an export can be read without the database
+type ReviewExport struct {
+	ID              int64     `json:"id"`
+	UserID          int64     `json:"user_id"`
+	StudySessionID  int64     `json:"study_session_id"`
+	GroupID         int64     `json:"group_id"`
+	GroupName       string    `json:"group_name"`
+	StudyActivityID int64     `json:"study_activity_id"`
+	ActivityName    string    `json:"activity_name"`
+	WordID          int64     `json:"word_id"`
+	Japanese        string    `json:"japanese"`
+	English         string    `json:"english"`
+	Grade           Grade     `json:"grade"`
+	Correct         bool      `json:"correct"`
+	ResponseTimeMs  *int64    `json:"response_time_ms"`
+	Answer          string    `json:"answer"`
+	Direction       Direction `json:"direction"`
+	StatementID     string    `json:"statement_id,omitempty"`
+	Registration    string    `json:"registration,omitempty"`
+	CreatedAt       time.Time `json:"created_at"`
+}
+
+// reviewExportQuery selects the columns read by scanReviewExport; callers add
+// the WHERE and ORDER BY clauses
+const reviewExportQuery = `
+		SELECT wri.id, wri.user_id, s.id, s.group_id, g.name, COALESCE(s.study_activity_id, 0), COALESCE(a.name, ''),
+			w.id, w.japanese, w.english,
+			wri.grade, wri.correct, wri.response_time_ms, wri.answer, wri.direction,
+			wri.statement_id, s.registration, wri.created_at
+		FROM word_review_items wri
+		JOIN study_sessions s ON s.id = wri.study_session_id
+		JOIN groups g ON g.id = s.group_id
+		LEFT JOIN study_activities a ON a.id = s.study_activity_id
+		JOIN words w ON w.id = wri.word_id`
+
+func scanReviewExport(row interface{ Scan(...interface{}) error }) (*ReviewExport, error) {
+	review := &ReviewExport{}
+	var responseTime sql.NullInt64
+	var answer, direction, statementID, registration sql.NullString
+	err := row.Scan(
+		&review.ID, &review.UserID, &review.StudySessionID, &review.GroupID, &review.GroupName,
+		&review.StudyActivityID, &review.ActivityName,
+		&review.WordID, &review.Japanese, &review.English,
+		&review.Grade, &review.Correct, &responseTime, &answer, &direction,
+		&statementID, &registration, &review.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+	if responseTime.Valid {
+		review.ResponseTimeMs = &responseTime.Int64
+	}
+	review.Answer = answer.String
+	review.Direction = Direction(direction.String)
+	review.StatementID = statementID.String
+	review.Registration = registration.String
+	return review, nil
+}
+
+// EachGroupWord calls fn for every word in a group in id order, with userID's
+// review stats. Rows are read one at a time so a large group is never held
+// in memory; an error from fn stops the iteration and is returned.
+func (db *DB) EachGroupWord(ctx context.Context, userID, groupID int64, fn func(*Word) error) error {
+	// no withTimeout: a streamed export lasts as long as the client reads it
+	rows, err := db.QueryContext(ctx, `
+		SELECT `+wordColumns+`
+		FROM words w
+		JOIN words_groups wg ON wg.word_id = w.id`+wordReviewStatsJoin(statsOfGroup)+`
+		WHERE wg.group_id = ?
+		ORDER BY w.id`, userID, groupID, groupID)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		word, err := scanWord(rows)
+		if err != nil {
+			return err
+		}
+		if err := fn(word); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
+
+// EachReview calls fn for every review in the study sessions matching filter,
+// oldest first, reading rows one at a time like EachGroupWord
+func (db *DB) EachReview(ctx context.Context, filter StudySessionFilter, fn func(*ReviewExport) error) error {
+	// bounded by ctx alone, not withTimeout, for the same reason as EachGroupWord
+	q := studySessionConditions(filter)
+	rows, err := db.QueryContext(ctx, reviewExportQuery+`
+		`+q.whereClause()+`
+		ORDER BY datetime(wri.created_at), wri.id`, q.args...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		review, err := scanReviewExport(rows)
+		if err != nil {
+			return err
+		}
+		if err := fn(review); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
+
+// reviewsAfterQuery is the query of GetReviewsAfter. Reviews are filtered
+// on their own user_id, which every review shares with its session, so a
+// page is read in order from the reviews' (user_id, datetime(created_at), id)
+// index.
+func reviewsAfterQuery(filter StudySessionFilter, after Cursor, limit int) (string, []interface{}) {
+	userID := filter.UserID
+	filter.UserID = 0
+	q := studySessionConditions(filter)
+	if userID != 0 {
+		q.where("wri.user_id = ?", userID)
+	}
+	order := keyset(&q, "wri", after, !strings.EqualFold(filter.Order, "asc"))
+	return reviewExportQuery + `
+		` + q.whereClause() + `
+		` + order + `
+		LIMIT ?`, append(q.args, limit)
+}
+
+// GetReviewsAfter returns up to limit reviews in the study sessions matching
+// filter that come after cursor, oldest first if filter.Order is "asc" and
+// newest first otherwise
+func (db *DB) GetReviewsAfter(ctx context.Context, filter StudySessionFilter, after Cursor, limit int) ([]*ReviewExport, error) {
+	ctx, cancel := db.withTimeout(ctx)
+	defer cancel()
+
+	query, args := reviewsAfterQuery(filter, after, limit)
+	rows, err := db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	reviews := []*ReviewExport{}
+	for rows.Next() {
+		review, err := scanReviewExport(rows)
+		if err != nil {
+			return nil, err
+		}
+		reviews = append(reviews, review)
+	}
+	return reviews, rows.Err()
+}
@@ -0,0 +1,156 @@
+package models
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"reflect"
+)
+
+// ImportResult counts what importing one set of words changed. With a dry
+// run the counts describe what would have changed.
+type ImportResult struct {
+	GroupID      int64  `json:"group_id,omitempty"`
+	Group        string `json:"group,omitempty"`
+	GroupCreated bool   `json:"group_created"`
+	Added        int    `json:"added"`
+	Updated      int    `json:"updated"`
+	Skipped      int    `json:"skipped"`
+	// Linked counts words newly added to the group
+	Linked int  `json:"linked"`
+	DryRun bool `json:"dry_run"`
+}
+
+// ImportWords upserts words and adds them to a group: the group with id
+// groupID if it is set, otherwise the group named groupName, which is created
+// if needed. With neither, words are not added to any group.
+//
+// Words are matched on (japanese, english): a match with a different romaji
+// or parts is updated, an identical one is skipped. A word without parts
+// keeps the parts it already has.
+//
+// Everything happens in one transaction, which a dry run rolls back.
+func (db *DB) ImportWords(ctx context.Context, groupID int64, groupName string, words []*Word, dryRun bool) (*ImportResult, error) {
+	ctx, cancel := db.withTimeout(ctx)
+	defer cancel()
+
+	result := &ImportResult{GroupID: groupID, Group: groupName, DryRun: dryRun}
+
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	var searchIndexed bool
+	err = tx.QueryRowContext(ctx, `
+		SELECT COUNT(*) > 0 FROM sqlite_master
+		WHERE name = ?`, searchTable).Scan(&searchIndexed)
+	if err != nil {
+		return nil, err
+	}
+
+	if groupID != 0 {
+		err = tx.QueryRowContext(ctx, "SELECT name FROM groups WHERE id = ?", groupID).Scan(&result.Group)
+		if err != nil {
+			return nil, err
+		}
+	} else if groupName != "" {
+		err = tx.QueryRowContext(ctx, "SELECT id FROM groups WHERE name = ?", groupName).Scan(&groupID)
+		if err == sql.ErrNoRows {
+			res, err := tx.ExecContext(ctx, "INSERT INTO groups (name) VALUES (?)", groupName)
+			if err != nil {
+				return nil, err
+			}
+			if groupID, err = res.LastInsertId(); err != nil {
+				return nil, err
+			}
+			result.GroupCreated = true
+		} else if err != nil {
+			return nil, err
+		}
+		if !(dryRun && result.GroupCreated) {
+			result.GroupID = groupID
+		}
+	}
+
+	for _, word := range words {
+		existing := &Word{}
+		err := tx.QueryRowContext(ctx, `
+			SELECT id, romaji, parts FROM words
+			WHERE japanese = ? AND english = ?
+			ORDER BY id LIMIT 1`, word.Japanese, word.English).
+			Scan(&existing.ID, &existing.Romaji, &existing.Parts)
+
+		var id int64
+		switch {
+		case err == sql.ErrNoRows:
+			res, err := tx.ExecContext(ctx, `
+				INSERT INTO words (japanese, romaji, english, parts)
+				VALUES (?, ?, ?, ?)`, word.Japanese, word.Romaji, word.English, word.Parts)
+			if err != nil {
+				return nil, err
+			}
+			if id, err = res.LastInsertId(); err != nil {
+				return nil, err
+			}
+			result.Added++
+		case err != nil:
+			return nil, err
+		default:
+			id = existing.ID
+			parts := existing.Parts
+			if word.Parts.Valid {
+				parts = word.Parts
+			}
+			if existing.Romaji == word.Romaji && samePartsJSON(existing.Parts, parts) {
+				result.Skipped++
+				break
+			}
+			_, err := tx.ExecContext(ctx, "UPDATE words SET romaji = ?, parts = ? WHERE id = ?", word.Romaji, parts, id)
+			if err != nil {
+				return nil, err
+			}
+			result.Updated++
+		}
+
+		if searchIndexed {
+			indexed := *word
+			indexed.ID = id
+			if err := indexWord(ctx, tx, &indexed); err != nil {
+				return nil, err
+			}
+		}
+
+		if groupID != 0 {
+			res, err := tx.ExecContext(ctx, `
+				INSERT OR IGNORE INTO words_groups (word_id, group_id)
+				VALUES (?, ?)`, id, groupID)
+			if err != nil {
+				return nil, err
+			}
+			n, err := res.RowsAffected()
+			if err != nil {
+				return nil, err
+			}
+			result.Linked += int(n)
+		}
+	}
+
+	if dryRun {
+		return result, nil
+	}
+	return result, tx.Commit()
+}
+
+// samePartsJSON compares two parts values as JSON rather than as text
+func samePartsJSON(a, b sql.NullString) bool {
+	if !a.Valid || !b.Valid {
+		return a.Valid == b.Valid
+	}
+	var av, bv interface{}
+	if json.Unmarshal([]byte(a.String), &av) != nil || json.Unmarshal([]byte(b.String), &bv) != nil {
+		return a.String == b.String
+	}
+	return reflect.DeepEqual(av, bv)
+}
@@ -0,0 +1,80 @@
+package models
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestImportWords(t *testing.T) {
+	raw, err := sql.Open("sqlite3", ":memory:")
+	require.NoError(t, err)
+	defer raw.Close()
+	raw.SetMaxOpenConns(1)
+
+	_, err = raw.Exec(`
+		CREATE TABLE words (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			japanese TEXT NOT NULL,
+			romaji TEXT NOT NULL,
+			english TEXT NOT NULL,
+			parts TEXT
+		);
+		CREATE TABLE groups (id INTEGER PRIMARY KEY AUTOINCREMENT, name TEXT NOT NULL);
+		CREATE TABLE words_groups (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			word_id INTEGER NOT NULL,
+			group_id INTEGER NOT NULL
+		);
+		CREATE UNIQUE INDEX idx_words_groups_word_group ON words_groups(word_id, group_id);
+		INSERT INTO words (japanese, romaji, english, parts) VALUES ('猫', 'neko', 'cat', '{"type": "noun"}')`)
+	require.NoError(t, err)
+	db := NewDB(raw)
+	ctx := context.Background()
+
+	words := func() []*Word {
+		return []*Word{
+			{Japanese: "猫", Romaji: "neko", English: "cat"},
+			{Japanese: "犬", Romaji: "inu", English: "dog"},
+		}
+	}
+	count := func(table string) int {
+		var n int
+		require.NoError(t, raw.QueryRow("SELECT COUNT(*) FROM "+table).Scan(&n))
+		return n
+	}
+
+	result, err := db.ImportWords(ctx, 0, "Animals", words(), true)
+	require.NoError(t, err)
+	assert.Equal(t, &ImportResult{Group: "Animals", GroupCreated: true, Added: 1, Skipped: 1, Linked: 2, DryRun: true}, result)
+	assert.Equal(t, 1, count("words"), "dry run writes nothing")
+	assert.Equal(t, 0, count("groups"))
+
+	result, err = db.ImportWords(ctx, 0, "Animals", words(), false)
+	require.NoError(t, err)
+	assert.Equal(t, &ImportResult{GroupID: 1, Group: "Animals", GroupCreated: true, Added: 1, Skipped: 1, Linked: 2}, result)
+
+	result, err = db.ImportWords(ctx, 0, "Animals", words(), false)
+	require.NoError(t, err)
+	assert.Equal(t, &ImportResult{GroupID: 1, Group: "Animals", Skipped: 2}, result)
+	assert.Equal(t, 2, count("words"))
+	assert.Equal(t, 1, count("groups"))
+	assert.Equal(t, 2, count("words_groups"))
+
+	updated := words()
+	updated[0].Parts = sql.NullString{String: `{"type":"noun","note":"pet"}`, Valid: true}
+	result, err = db.ImportWords(ctx, 0, "Pets", updated, false)
+	require.NoError(t, err)
+	assert.Equal(t, &ImportResult{GroupID: 2, Group: "Pets", GroupCreated: true, Updated: 1, Skipped: 1, Linked: 2}, result)
+
+	result, err = db.ImportWords(ctx, 1, "", words(), false)
+	require.NoError(t, err)
+	assert.Equal(t, &ImportResult{GroupID: 1, Group: "Animals", Skipped: 2}, result)
+
+	var parts string
+	require.NoError(t, raw.QueryRow("SELECT parts FROM words WHERE english = 'cat'").Scan(&parts))
+	assert.JSONEq(t, `{"type":"noun","note":"pet"}`, parts)
+}
@@ -1,20 +1,69 @@
 package models
 
-// DBInterface defines the interface that both the real DB and mock DB must implement
+import (
+	"context"
+	"time"
+)
+
+// DBInterface defines the interface that both the real DB and mock DB must
+// implement. Every method runs under the context it is given and stops with
+// the context's error once it is cancelled.
 type DBInterface interface {
-	GetWord(id int64) (*Word, error)
-	GetWords(page, perPage int) ([]*Word, *Pagination, error)
-	GetGroup(id int64) (*Group, error)
-	GetGroups(page, perPage int) ([]*Group, *Pagination, error)
-	CreateStudySession(groupID, activityID int64) (*StudySession, error)
-	GetStudySession(id int64) (*StudySession, error)
-	GetLastStudySession() (*StudySession, error)
-	GetStudyProgress() (*StudyProgress, error)
-	GetStudySessionsByActivity(activityID int64, page, perPage int) ([]*StudySession, *Pagination, error)
-	CreateWordReview(wordID, sessionID int64, correct bool) (*WordReviewItem, error)
-	GetQuickStats() (*QuickStats, error)
-	ResetHistory() error
-	FullReset() error
-	GetWordsByGroup(groupID int64, page, perPage int) ([]*Word, *Pagination, error)
-	GetWordsByStudySession(sessionID int64, page, perPage int) ([]*Word, *Pagination, error)
+	GetUser(ctx context.Context, id int64) (*User, error)
+	GetUserByName(ctx context.Context, name string) (*User, error)
+	GetUsers(ctx context.Context, page, perPage int) ([]*User, *Pagination, error)
+	CreateUser(ctx context.Context, name string, role Role) (*User, error)
+	UpdateUser(ctx context.Context, user *User) (*User, error)
+	GetUserByAPIKey(ctx context.Context, keyHash string) (*User, error)
+	GetAPIKeys(ctx context.Context, userID int64) ([]*APIKey, error)
+	CreateAPIKey(ctx context.Context, userID int64, name, keyHash string) (*APIKey, error)
+	DeleteAPIKey(ctx context.Context, userID, id int64) error
+	GetWord(ctx context.Context, userID, id int64) (*Word, error)
+	GetWords(ctx context.Context, filter WordFilter, page, perPage int) ([]*Word, *Pagination, error)
+	SearchWords(ctx context.Context, userID int64, q string, page, perPage int) ([]*Word, *Pagination, error)
+	CreateWord(ctx context.Context, word *Word) (*Word, error)
+	UpdateWord(ctx context.Context, word *Word) (*Word, error)
+	DeleteWord(ctx context.Context, id int64) error
+	ImportWords(ctx context.Context, groupID int64, groupName string, words []*Word, dryRun bool) (*ImportResult, error)
+	GetGroup(ctx context.Context, id int64) (*Group, error)
+	GetGroups(ctx context.Context, filter GroupFilter, page, perPage int) ([]*Group, *Pagination, error)
+	CreateGroup(ctx context.Context, name string) (*Group, error)
+	RenameGroup(ctx context.Context, id int64, name string) (*Group, error)
+	DeleteGroup(ctx context.Context, id int64) error
+	CountStudySessionsByGroup(ctx context.Context, groupID int64) (int, error)
+	AddWordsToGroup(ctx context.Context, groupID int64, wordIDs []int64) (int, error)
+	RemoveWordsFromGroup(ctx context.Context, groupID int64, wordIDs []int64) (int, error)
+	IsWordInGroup(ctx context.Context, groupID, wordID int64) (bool, error)
+	GetMissingWordIDs(ctx context.Context, wordIDs []int64) ([]int64, error)
+	GetStudyActivity(ctx context.Context, id int64) (*StudyActivity, error)
+	GetStudyActivities(ctx context.Context, page, perPage int) ([]*StudyActivity, *Pagination, error)
+	CreateStudyActivity(ctx context.Context, activity *StudyActivity) (*StudyActivity, error)
+	UpdateStudyActivity(ctx context.Context, activity *StudyActivity) (*StudyActivity, error)
+	DeleteStudyActivity(ctx context.Context, id int64) error
+	CountStudySessionsByActivity(ctx context.Context, activityID int64) (int, error)
+	CreateStudySession(ctx context.Context, userID, groupID, activityID int64) (*StudySession, error)
+	GetStudySessionByRegistration(ctx context.Context, registration string) (*StudySession, error)
+	GetStudySession(ctx context.Context, id int64) (*StudySession, error)
+	EndStudySession(ctx context.Context, id int64, endedAt time.Time) (bool, error)
+	AbandonIdleStudySessions(ctx context.Context, idleSince time.Time) (int64, error)
+	GetStudySessionSummary(ctx context.Context, id int64) (*StudySessionSummary, error)
+	GetLastStudySession(ctx context.Context, userID int64) (*StudySession, error)
+	GetStudyProgress(ctx context.Context, userID int64) (*StudyProgress, error)
+	GetStudySessions(ctx context.Context, filter StudySessionFilter, page, perPage int) ([]*StudySession, *Pagination, error)
+	GetStudySessionsByActivity(ctx context.Context, userID, activityID int64, page, perPage int) ([]*StudySession, *Pagination, error)
+	GetStudySessionsByGroup(ctx context.Context, userID, groupID int64, page, perPage int) ([]*StudySession, *Pagination, error)
+	GetStudySessionsAfter(ctx context.Context, filter StudySessionFilter, after Cursor, limit int) ([]*StudySession, error)
+	RecordReview(ctx context.Context, review *WordReviewItem, schedule Scheduler) (*WordReviewItem, error)
+	GetRecordedStatementIDs(ctx context.Context, userID int64, statementIDs []string) ([]string, error)
+	RecordStatements(ctx context.Context, userID int64, records []*StatementRecord, schedule Scheduler) error
+	GetReviewStatements(ctx context.Context, filter ReviewStatementFilter) ([]*ReviewExport, error)
+	GetReviewsAfter(ctx context.Context, filter StudySessionFilter, after Cursor, limit int) ([]*ReviewExport, error)
+	GetDueWords(ctx context.Context, userID, groupID int64, now time.Time, includeNew bool, page, perPage int) ([]*DueWord, *Pagination, error)
+	GetQuickStats(ctx context.Context, userID int64) (*QuickStats, error)
+	ResetHistory(ctx context.Context, userID int64) error
+	FullReset(ctx context.Context) error
+	GetWordsByGroup(ctx context.Context, groupID int64, filter WordFilter, page, perPage int) ([]*Word, *Pagination, error)
+	GetWordsByStudySession(ctx context.Context, userID, sessionID int64, page, perPage int) ([]*Word, *Pagination, error)
+	EachGroupWord(ctx context.Context, userID, groupID int64, fn func(*Word) error) error
+	EachReview(ctx context.Context, filter StudySessionFilter, fn func(*ReviewExport) error) error
 }
@@ -12,6 +12,15 @@ type Word struct {
 	English  string         `json:"english"`
 	Parts    sql.NullString `json:"-"`
 	PartsMap map[string]interface{} `json:"parts,omitempty"`
+	WordStats
+}
+
+// WordPatch holds the fields of a partial word update; nil fields are left unchanged
+type WordPatch struct {
+	Japanese *string
+	Romaji   *string
+	English  *string
+	Parts    *string
 }
 
 type Group struct {
@@ -21,33 +30,201 @@ type Group struct {
 }
 
 type StudyActivity struct {
-	ID             int64     `json:"id"`
-	StudySessionID int64     `json:"study_session_id"`
-	GroupID        int64     `json:"group_id"`
-	CreatedAt      time.Time `json:"created_at"`
+	ID           int64     `json:"id"`
+	Name         string    `json:"name"`
+	Description  string    `json:"description"`
+	ThumbnailURL string    `json:"thumbnail_url"`
+	LaunchURL    string    `json:"launch_url"`
+	Enabled      bool      `json:"enabled"`
+	CreatedAt    time.Time `json:"created_at"`
+}
+
+// DefaultUserID is the user that owns study history recorded before there
+// were user accounts
+const DefaultUserID = 1
+
+type User struct {
+	ID           int64     `json:"id"`
+	Name         string    `json:"name"`
+	Role         Role      `json:"role"`
+	PasswordHash string    `json:"-"`
+	CreatedAt    time.Time `json:"created_at"`
+	// PasswordChangedAt is when the password was last set or removed;
+	// session tokens issued before then are no longer valid
+	PasswordChangedAt *time.Time `json:"-"`
+}
+
+// Role is what a user is allowed to do. Each role may do everything the
+// roles before it may.
+type Role string
+
+const (
+	RoleLearner Role = "learner"
+	RoleTeacher Role = "teacher"
+	RoleAdmin   Role = "admin"
+)
+
+var roleRanks = map[Role]int{RoleLearner: 1, RoleTeacher: 2, RoleAdmin: 3}
+
+// Valid reports whether r is one of the known roles
+func (r Role) Valid() bool {
+	return roleRanks[r] > 0
+}
+
+// Allows reports whether r may do what required may
+func (r Role) Allows(required Role) bool {
+	return r.Valid() && roleRanks[r] >= roleRanks[required]
+}
+
+// APIKey lets a learning app act for a user. The key itself is only shown
+// when it is created; the database keeps a hash of it.
+type APIKey struct {
+	ID         int64      `json:"id"`
+	UserID     int64      `json:"user_id"`
+	Name       string     `json:"name"`
+	Key        string     `json:"key,omitempty"`
+	CreatedAt  time.Time  `json:"created_at"`
+	LastUsedAt *time.Time `json:"last_used_at"`
 }
 
 type StudySession struct {
-	ID              int64     `json:"id"`
-	GroupID         int64     `json:"group_id"`
-	CreatedAt       time.Time `json:"created_at"`
-	StudyActivityID int64     `json:"study_activity_id"`
-	GroupName       string    `json:"group_name,omitempty"`
-	ActivityName    string    `json:"activity_name,omitempty"`
-	ReviewItemCount int       `json:"review_items_count,omitempty"`
+	ID              int64              `json:"id"`
+	UserID          int64              `json:"user_id"`
+	GroupID         int64              `json:"group_id"`
+	CreatedAt       time.Time          `json:"created_at"`
+	EndedAt         *time.Time         `json:"ended_at"`
+	Status          StudySessionStatus `json:"status"`
+	StudyActivityID int64              `json:"study_activity_id"`
+	GroupName       string             `json:"group_name,omitempty"`
+	ActivityName    string             `json:"activity_name,omitempty"`
+	ReviewItemCount int                `json:"review_items_count,omitempty"`
+}
+
+// StudySessionStatus is where a study session is in its lifecycle
+type StudySessionStatus string
+
+const (
+	// StudySessionActive sessions take reviews
+	StudySessionActive StudySessionStatus = "active"
+	// StudySessionCompleted sessions were ended by the learner or their app
+	StudySessionCompleted StudySessionStatus = "completed"
+	// StudySessionAbandoned sessions went idle and were ended by the server
+	StudySessionAbandoned StudySessionStatus = "abandoned"
+)
+
+// StudySessionSummary is how a study session went. A word is new if the
+// user had not reviewed it before the session started, else repeated.
+// Accuracy is the percentage of reviews that were correct.
+type StudySessionSummary struct {
+	StudySession
+	DurationSeconds int64   `json:"duration_seconds"`
+	WordsSeen       int     `json:"words_seen"`
+	CorrectCount    int     `json:"correct_count"`
+	Accuracy        float64 `json:"accuracy"`
+	NewWords        int     `json:"new_words"`
+	RepeatedWords   int     `json:"repeated_words"`
+}
+
+// StudySessionFilter narrows and orders a study session listing.
+// Zero values mean "no filter"; SortBy must be a key of StudySessionSortColumns.
+type StudySessionFilter struct {
+	UserID          int64
+	GroupID         int64
+	StudyActivityID int64
+	From            time.Time
+	To              time.Time
+	SortBy          string
+	Order           string
+}
+
+// StudySessionSortColumns maps the sort_by values accepted by the API onto SQL expressions
+var StudySessionSortColumns = map[string]string{
+	"created_at":   "datetime(s.created_at)",
+	"review_count": "review_count",
 }
 
 type WordReviewItem struct {
 	ID             int64     `json:"id"`
+	UserID         int64     `json:"user_id"`
 	WordID         int64     `json:"word_id"`
 	StudySessionID int64     `json:"study_session_id"`
 	Correct        bool      `json:"correct"`
+	Grade          Grade     `json:"grade"`
+	ResponseTimeMs *int64    `json:"response_time_ms,omitempty"`
+	Answer         string    `json:"answer,omitempty"`
+	Direction      Direction `json:"direction,omitempty"`
+	StatementID    string    `json:"statement_id,omitempty"`
 	CreatedAt      time.Time `json:"created_at"`
 }
 
+// Grade is how well a word was recalled during a review
+type Grade string
+
+const (
+	GradeAgain Grade = "again"
+	GradeHard  Grade = "hard"
+	GradeGood  Grade = "good"
+	GradeEasy  Grade = "easy"
+)
+
+// Valid reports whether g is one of the known grades
+func (g Grade) Valid() bool {
+	switch g {
+	case GradeAgain, GradeHard, GradeGood, GradeEasy:
+		return true
+	}
+	return false
+}
+
+// Correct reports whether the grade counts as a successful recall
+func (g Grade) Correct() bool {
+	return g != GradeAgain
+}
+
+// Direction is what the learner was shown and what they had to produce
+type Direction string
+
+const (
+	DirectionJapaneseToEnglish Direction = "jp_en"
+	DirectionEnglishToJapanese Direction = "en_jp"
+	DirectionKanaToKanji       Direction = "kana_kanji"
+)
+
+// Valid reports whether d is one of the known prompt directions
+func (d Direction) Valid() bool {
+	switch d {
+	case DirectionJapaneseToEnglish, DirectionEnglishToJapanese, DirectionKanaToKanji:
+		return true
+	}
+	return false
+}
+
+// WordProgress is the spaced-repetition state of a word
+type WordProgress struct {
+	UserID         int64      `json:"-"`
+	WordID         int64      `json:"word_id"`
+	EaseFactor     float64    `json:"ease_factor"`
+	IntervalDays   int        `json:"interval_days"`
+	Repetitions    int        `json:"repetitions"`
+	Lapses         int        `json:"lapses"`
+	DueAt          time.Time  `json:"due_at"`
+	LastReviewedAt *time.Time `json:"last_reviewed_at,omitempty"`
+}
+
+// DueWord is a word to drill together with its scheduling state.
+// Progress is nil for words that have never been reviewed.
+type DueWord struct {
+	*Word
+	Progress *WordProgress `json:"progress,omitempty"`
+}
+
+// WordStats summarizes a user's reviews of a word. Accuracy is the percentage of
+// correct reviews and, like LastReviewedAt, is null for unreviewed words.
 type WordStats struct {
-	CorrectCount int `json:"correct_count"`
-	WrongCount   int `json:"wrong_count"`
+	CorrectCount   int        `json:"correct_count"`
+	WrongCount     int        `json:"wrong_count"`
+	Accuracy       *float64   `json:"accuracy"`
+	LastReviewedAt *time.Time `json:"last_reviewed_at"`
 }
 
 type StudyProgress struct {
@@ -69,7 +246,21 @@ type Pagination struct {
 	ItemsPerPage  int `json:"items_per_page"`
 }
 
+// PaginatedResponse is a page of a listing. A page read by number has
+// Pagination; NextCursor, on listings that support cursors, continues after
+// the last item and is empty on the last page.
 type PaginatedResponse struct {
 	Items      interface{} `json:"items"`
-	Pagination Pagination  `json:"pagination"`
+	Pagination *Pagination `json:"pagination,omitempty"`
+	NextCursor string      `json:"next_cursor,omitempty"`
+}
+
+// PageRequest is the page of a listing a client asks for: page Page of
+// PerPage items, or, when Cursor is set, the PerPage items after the cursor
+// some earlier page returned. Zero values mean the first page and the
+// server's page size.
+type PageRequest struct {
+	Page    int
+	PerPage int
+	Cursor  string
 }
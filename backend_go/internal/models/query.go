@@ -0,0 +1,104 @@
+package models
+
+import (
+	"sort"
+	"strings"
+)
+
+// Listing endpoints accept user-supplied sort_by and order values. They are
+// never interpolated into SQL directly: sort_by is looked up in a per-listing
+// whitelist of SQL expressions and order is reduced to ASC or DESC.
+
+// WordSortColumns maps the sort_by values accepted for word listings onto SQL expressions
+var WordSortColumns = map[string]string{
+	"id":            "w.id",
+	"japanese":      "w.japanese",
+	"romaji":        "w.romaji",
+	"english":       "w.english",
+	"correct_count": "COALESCE(r.correct_count, 0)",
+	"wrong_count":   "COALESCE(r.wrong_count, 0)",
+}
+
+// GroupSortColumns maps the sort_by values accepted for group listings onto SQL expressions
+var GroupSortColumns = map[string]string{
+	"id":         "g.id",
+	"name":       "g.name",
+	"word_count": "word_count",
+}
+
+// WordFilter narrows and orders a word listing. Zero values mean "no filter".
+type WordFilter struct {
+	// UserID is the user whose reviews the stats, NeverReviewed and
+	// AccuracyBelow are about
+	UserID        int64
+	GroupID       int64
+	HasParts      *bool
+	NeverReviewed *bool
+	// AccuracyBelow keeps reviewed words whose percentage of correct reviews is below it
+	AccuracyBelow *float64
+	SortBy        string
+	Order         string
+}
+
+// GroupFilter orders a group listing
+type GroupFilter struct {
+	SortBy string
+	Order  string
+}
+
+// ValidateSort checks sort_by against a whitelist and order against asc/desc.
+// Empty values are allowed and fall back to the listing's default.
+func ValidateSort(columns map[string]string, sortBy, order string) error {
+	var errs ValidationErrors
+	if _, ok := columns[sortBy]; sortBy != "" && !ok {
+		keys := make([]string, 0, len(columns))
+		for k := range columns {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		errs.add("sort_by", "must be one of "+strings.Join(keys, ", "))
+	}
+	if order != "" && order != "asc" && order != "desc" {
+		errs.add("order", "must be asc or desc")
+	}
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
+
+// queryBuilder accumulates WHERE conditions and their arguments
+type queryBuilder struct {
+	conditions []string
+	args       []interface{}
+}
+
+func (q *queryBuilder) where(condition string, args ...interface{}) {
+	q.conditions = append(q.conditions, condition)
+	q.args = append(q.args, args...)
+}
+
+// whereClause returns the WHERE clause, or "" when there are no conditions
+func (q *queryBuilder) whereClause() string {
+	if len(q.conditions) == 0 {
+		return ""
+	}
+	return "WHERE " + strings.Join(q.conditions, " AND ")
+}
+
+// orderBy resolves sortBy through the whitelist, falling back to defaultSort,
+// and appends tieBreaker so paging is stable
+func orderBy(columns map[string]string, sortBy, order, defaultSort, defaultOrder, tieBreaker string) string {
+	column, ok := columns[sortBy]
+	if !ok {
+		column = columns[defaultSort]
+	}
+	if order == "" {
+		order = defaultOrder
+	}
+	direction := "ASC"
+	if strings.EqualFold(order, "desc") {
+		direction = "DESC"
+	}
+	return "ORDER BY " + column + " " + direction + ", " + tieBreaker + " " + direction
+}
@@ -0,0 +1,26 @@
+package models
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidateSort(t *testing.T) {
+	assert.NoError(t, ValidateSort(WordSortColumns, "", ""))
+	assert.NoError(t, ValidateSort(WordSortColumns, "correct_count", "desc"))
+
+	err := ValidateSort(WordSortColumns, "parts; DROP TABLE words", "sideways")
+	var errs ValidationErrors
+	assert.ErrorAs(t, err, &errs)
+	assert.Len(t, errs, 2)
+	assert.Equal(t, "sort_by", errs[0].Field)
+	assert.Equal(t, "order", errs[1].Field)
+}
+
+func TestOrderByFallsBackToDefault(t *testing.T) {
+	assert.Equal(t, "ORDER BY g.name DESC, g.id DESC",
+		orderBy(GroupSortColumns, "name", "desc", "id", "asc", "g.id"))
+	assert.Equal(t, "ORDER BY g.id ASC, g.id ASC",
+		orderBy(GroupSortColumns, "unknown", "", "id", "asc", "g.id"))
+}
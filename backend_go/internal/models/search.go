@@ -0,0 +1,262 @@
+package models
+
+import (
+	"context"
+	"database/sql"
+	"strings"
+	"unicode"
+)
+
+// The search index lives in searchTable, keyed by word id (rowid), and holds a
+// normalized copy of each word so spelling variants compare equal:
+//
+//   - japanese: katakana folded to hiragana
+//   - romaji:   macrons and doubled long vowels folded (tōkyō, toukyou, tookyoo -> tokyo)
+//   - english:  lower-cased
+//
+// With the sqlite_fts5 build tag the index is an FTS5 table, word_search,
+// ranked with bm25; without it a plain table, word_search_like, is scanned with
+// LIKE. The table is created by InitSearchIndex rather than a migration
+// because its definition depends on the build.
+
+// searchQuery is a search term normalized once per indexed column
+type searchQuery struct {
+	japanese string
+	romaji   string
+	english  string
+}
+
+func newSearchQuery(q string) searchQuery {
+	return searchQuery{
+		japanese: normalizeJapanese(q),
+		romaji:   normalizeRomaji(q),
+		english:  strings.ToLower(strings.TrimSpace(q)),
+	}
+}
+
+// InitSearchIndex creates the search index if needed and rebuilds it from the
+// words table, picking up words that were added outside the API (e.g. seeds)
+func (db *DB) InitSearchIndex(ctx context.Context) error {
+	if err := dropStaleSearchIndexes(ctx, db); err != nil {
+		return err
+	}
+	if _, err := db.ExecContext(ctx, searchIndexSchema); err != nil {
+		return err
+	}
+
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+
+	if _, err := tx.ExecContext(ctx, "DELETE FROM "+searchTable); err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	rows, err := tx.QueryContext(ctx, "SELECT id, japanese, romaji, english FROM words")
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+	words := []*Word{}
+	for rows.Next() {
+		word := &Word{}
+		if err := rows.Scan(&word.ID, &word.Japanese, &word.Romaji, &word.English); err != nil {
+			rows.Close()
+			tx.Rollback()
+			return err
+		}
+		words = append(words, word)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	for _, word := range words {
+		if _, err := tx.ExecContext(ctx, `
+			INSERT INTO `+searchTable+` (rowid, japanese, romaji, english)
+			VALUES (?, ?, ?, ?)`,
+			word.ID, normalizeJapanese(word.Japanese), normalizeRomaji(word.Romaji),
+			strings.ToLower(word.English)); err != nil {
+			tx.Rollback()
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+// dropStaleSearchIndexes drops search index tables this build does not use: a
+// searchTable of the wrong kind, left by an older version, and the other
+// build's table. An FTS5 table can only be dropped by a build with FTS5; left
+// behind, it is never read.
+func dropStaleSearchIndexes(ctx context.Context, db *DB) error {
+	for _, name := range []string{searchTable, otherSearchTable} {
+		var schema string
+		err := db.QueryRowContext(ctx, `
+			SELECT sql FROM sqlite_master WHERE type = 'table' AND name = ?`, name).Scan(&schema)
+		if err == sql.ErrNoRows {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		virtual := strings.HasPrefix(strings.ToUpper(schema), "CREATE VIRTUAL TABLE")
+		inUse := name == searchTable && virtual == FullTextSearch
+		if inUse || virtual && !FullTextSearch {
+			continue
+		}
+		if _, err := db.ExecContext(ctx, "DROP TABLE "+name); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// execer is satisfied by both *sql.DB and *sql.Tx
+type execer interface {
+	ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+}
+
+// querier is satisfied by both *sql.DB and *sql.Tx
+type querier interface {
+	execer
+	QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row
+}
+
+// indexWord replaces the search index entry of a word
+func indexWord(ctx context.Context, db execer, word *Word) error {
+	if _, err := db.ExecContext(ctx, "DELETE FROM "+searchTable+" WHERE rowid = ?", word.ID); err != nil {
+		return err
+	}
+	_, err := db.ExecContext(ctx, `
+		INSERT INTO `+searchTable+` (rowid, japanese, romaji, english)
+		VALUES (?, ?, ?, ?)`,
+		word.ID, normalizeJapanese(word.Japanese), normalizeRomaji(word.Romaji),
+		strings.ToLower(word.English))
+	return err
+}
+
+// SearchWords finds words whose japanese, romaji or english contains q,
+// best matches first, with userID's review stats
+func (db *DB) SearchWords(ctx context.Context, userID int64, q string, page, perPage int) ([]*Word, *Pagination, error) {
+	ctx, cancel := db.withTimeout(ctx)
+	defer cancel()
+
+	offset := (page - 1) * perPage
+	words := []*Word{}
+
+	where, whereArgs, order, orderArgs := searchClauses(newSearchQuery(q))
+	args := append(append(append([]interface{}{userID}, whereArgs...), orderArgs...), perPage, offset)
+
+	rows, err := db.QueryContext(ctx, `
+		SELECT `+wordColumns+`
+		FROM `+searchTable+`
+		JOIN words w ON w.id = `+searchTable+`.rowid`+wordReviewStatsJoin("")+`
+		WHERE `+where+`
+		ORDER BY `+order+`, w.id
+		LIMIT ? OFFSET ?`, args...)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		word, err := scanWord(rows)
+		if err != nil {
+			return nil, nil, err
+		}
+		words = append(words, word)
+	}
+
+	var total int
+	err = db.QueryRowContext(ctx, "SELECT COUNT(*) FROM "+searchTable+" WHERE "+where, whereArgs...).Scan(&total)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	pagination := &Pagination{
+		CurrentPage:  page,
+		TotalPages:   (total + perPage - 1) / perPage,
+		TotalItems:   total,
+		ItemsPerPage: perPage,
+	}
+
+	return words, pagination, nil
+}
+
+// exactMatchOrder ranks rows where a column equals the query first
+const exactMatchOrder = "(" + searchTable + ".japanese = ? OR " + searchTable + ".romaji = ? OR " +
+	searchTable + ".english = ?) DESC"
+
+func exactMatchOrderArgs(q searchQuery) []interface{} {
+	return []interface{}{q.japanese, q.romaji, q.english}
+}
+
+// likeClauses matches q as a substring of any indexed column
+func likeClauses(q searchQuery) (string, []interface{}) {
+	where := `(` + searchTable + `.japanese LIKE ? ESCAPE '\'
+			OR ` + searchTable + `.romaji LIKE ? ESCAPE '\'
+			OR ` + searchTable + `.english LIKE ? ESCAPE '\')`
+	args := []interface{}{
+		"%" + escapeLike(q.japanese) + "%",
+		"%" + escapeLike(q.romaji) + "%",
+		"%" + escapeLike(q.english) + "%",
+	}
+	return where, args
+}
+
+// escapeLike escapes LIKE wildcards for use with ESCAPE '\'
+func escapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(s)
+}
+
+// normalizeJapanese lower-cases s and folds katakana to hiragana
+func normalizeJapanese(s string) string {
+	s = strings.ToLower(strings.TrimSpace(s))
+	return strings.Map(func(r rune) rune {
+		if r >= 'ァ' && r <= 'ヶ' {
+			return r - ('ァ' - 'ぁ')
+		}
+		return r
+	}, s)
+}
+
+var macrons = strings.NewReplacer(
+	"ā", "a", "ī", "i", "ū", "u", "ē", "e", "ō", "o",
+	"â", "a", "î", "i", "û", "u", "ê", "e", "ô", "o",
+	"'", "", "-", "",
+)
+
+// longVowels lists the vowel pairs romanized Japanese uses for long vowels
+var longVowels = map[[2]rune]bool{
+	{'a', 'a'}: true,
+	{'i', 'i'}: true,
+	{'u', 'u'}: true,
+	{'e', 'e'}: true,
+	{'o', 'o'}: true,
+	{'o', 'u'}: true,
+}
+
+// normalizeRomaji lower-cases s and folds long vowel spellings so that
+// ō, ou and oo (and ā/aa, ū/uu, ...) all become a single vowel
+func normalizeRomaji(s string) string {
+	s = macrons.Replace(strings.ToLower(strings.TrimSpace(s)))
+
+	var b strings.Builder
+	var prev rune
+	for _, r := range s {
+		if longVowels[[2]rune{prev, r}] {
+			continue
+		}
+		b.WriteRune(r)
+		prev = r
+		if !unicode.IsLetter(r) {
+			prev = 0
+		}
+	}
+	return b.String()
+}
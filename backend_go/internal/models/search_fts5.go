@@ -0,0 +1,47 @@
+//go:build sqlite_fts5
+
+package models
+
+import (
+	"strings"
+	"unicode/utf8"
+)
+
+// FullTextSearch reports whether word search uses SQLite FTS5, as builds
+// with the sqlite_fts5 tag do
+const FullTextSearch = true
+
+// The index is an FTS5 table; the plain table of builds without the tag has
+// another name, so a database opened by both builds never keeps the wrong one.
+const (
+	searchTable      = "word_search"
+	otherSearchTable = "word_search_like"
+)
+
+// The trigram tokenizer indexes every three-character sequence, which gives
+// substring matching for Japanese text that has no word boundaries.
+const searchIndexSchema = `
+	CREATE VIRTUAL TABLE IF NOT EXISTS ` + searchTable + `
+	USING fts5(japanese, romaji, english, tokenize = 'trigram')`
+
+// searchClauses uses an FTS5 MATCH ranked by bm25. Trigrams cannot match
+// queries shorter than three characters, so those fall back to LIKE.
+func searchClauses(q searchQuery) (string, []interface{}, string, []interface{}) {
+	if utf8.RuneCountInString(q.japanese) < 3 ||
+		utf8.RuneCountInString(q.romaji) < 3 ||
+		utf8.RuneCountInString(q.english) < 3 {
+		where, args := likeClauses(q)
+		return where, args, exactMatchOrder + ", length(" + searchTable + ".english)", exactMatchOrderArgs(q)
+	}
+
+	match := "japanese : " + ftsPhrase(q.japanese) +
+		" OR romaji : " + ftsPhrase(q.romaji) +
+		" OR english : " + ftsPhrase(q.english)
+	return searchTable + " MATCH ?", []interface{}{match},
+		exactMatchOrder + ", bm25(" + searchTable + ")", exactMatchOrderArgs(q)
+}
+
+// ftsPhrase quotes s as an FTS5 string so operators in user input are literal
+func ftsPhrase(s string) string {
+	return `"` + strings.ReplaceAll(s, `"`, `""`) + `"`
+}
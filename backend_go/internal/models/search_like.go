@@ -0,0 +1,29 @@
+//go:build !sqlite_fts5
+
+package models
+
+// FullTextSearch reports whether word search uses SQLite FTS5. This build
+// lacks the sqlite_fts5 tag, so search scans the index with LIKE.
+const FullTextSearch = false
+
+// The index is a plain table, named apart from the FTS5 table of builds with
+// the tag, which this build could not drop or query.
+const (
+	searchTable      = "word_search_like"
+	otherSearchTable = "word_search"
+)
+
+const searchIndexSchema = `
+	CREATE TABLE IF NOT EXISTS ` + searchTable + ` (
+		word_id INTEGER PRIMARY KEY,
+		japanese TEXT NOT NULL,
+		romaji TEXT NOT NULL,
+		english TEXT NOT NULL
+	)`
+
+// searchClauses scans the index with LIKE, ranking exact matches first and
+// shorter entries (closer matches) after them
+func searchClauses(q searchQuery) (string, []interface{}, string, []interface{}) {
+	where, args := likeClauses(q)
+	return where, args, exactMatchOrder + ", length(" + searchTable + ".english)", exactMatchOrderArgs(q)
+}
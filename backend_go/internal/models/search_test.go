@@ -0,0 +1,133 @@
+package models
+
+import (
+	"context"
+	"database/sql"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gen-ai-bootcamp-2025/backend_go/internal/testdb"
+	_ "github.com/mattn/go-sqlite3"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNormalizeRomaji(t *testing.T) {
+	for _, variant := range []string{"Tōkyō", "toukyou", "tookyoo", "TOKYO"} {
+		assert.Equal(t, "tokyo", normalizeRomaji(variant), variant)
+	}
+	assert.Equal(t, "konnichiwa", normalizeRomaji("kon'nichiwa"))
+	assert.Equal(t, "oishi", normalizeRomaji("oishii"))
+}
+
+func TestNormalizeJapanese(t *testing.T) {
+	assert.Equal(t, "てすと", normalizeJapanese("テスト"))
+	assert.Equal(t, "こんにちは", normalizeJapanese("こんにちは"))
+	assert.Equal(t, "tしゃつ", normalizeJapanese("Tシャツ"))
+}
+
+func TestSearchWords(t *testing.T) {
+	raw, err := sql.Open("sqlite3", ":memory:")
+	require.NoError(t, err)
+	defer raw.Close()
+	raw.SetMaxOpenConns(1)
+
+	_, err = raw.Exec(`
+		CREATE TABLE words (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			japanese TEXT NOT NULL,
+			romaji TEXT NOT NULL,
+			english TEXT NOT NULL,
+			parts TEXT
+		);
+		CREATE TABLE words_groups (word_id INTEGER, group_id INTEGER);
+		CREATE TABLE word_review_items (user_id INTEGER, word_id INTEGER, correct BOOLEAN, created_at DATETIME);
+		CREATE TABLE word_progress (word_id INTEGER);
+		INSERT INTO words (japanese, romaji, english) VALUES
+			('東京', 'tōkyō', 'Tokyo'),
+			('ありがとう', 'arigatou', 'thank you'),
+			('テレビ', 'terebi', 'television'),
+			('おはよう', 'ohayou', 'good morning');
+		INSERT INTO word_review_items (user_id, word_id, correct, created_at) VALUES
+			(1, 1, 1, '2025-01-01 09:00:00'),
+			(1, 1, 1, '2025-01-02 09:00:00'),
+			(1, 1, 1, '2025-01-03 09:00:00'),
+			(1, 1, 0, '2025-01-04 09:00:00')`)
+	require.NoError(t, err)
+
+	db := NewDB(raw)
+	ctx := context.Background()
+	require.NoError(t, db.InitSearchIndex(ctx))
+
+	search := func(q string) []string {
+		words, _, err := db.SearchWords(ctx, 1, q, 1, 10)
+		require.NoError(t, err)
+		english := []string{}
+		for _, w := range words {
+			english = append(english, w.English)
+		}
+		return english
+	}
+
+	assert.Equal(t, []string{"Tokyo"}, search("toukyou"))
+	assert.Equal(t, []string{"Tokyo"}, search("tookyoo"))
+	assert.Equal(t, []string{"television"}, search("てれび"))
+	assert.Equal(t, []string{"thank you"}, search("アリガトウ"))
+	assert.Equal(t, []string{"good morning"}, search("morn"))
+	assert.Empty(t, search("100%"))
+
+	words, _, err := db.SearchWords(ctx, 1, "tokyo", 1, 10)
+	require.NoError(t, err)
+	require.Len(t, words, 1)
+	assert.Equal(t, 3, words[0].CorrectCount)
+	assert.Equal(t, 1, words[0].WrongCount)
+	require.NotNil(t, words[0].Accuracy)
+	assert.InDelta(t, 75.0, *words[0].Accuracy, 0.001)
+	require.NotNil(t, words[0].LastReviewedAt)
+	assert.Equal(t, "2025-01-04T09:00:00Z", words[0].LastReviewedAt.Format(time.RFC3339))
+
+	words, _, err = db.SearchWords(ctx, 1, "terebi", 1, 10)
+	require.NoError(t, err)
+	require.Len(t, words, 1)
+	assert.Nil(t, words[0].Accuracy)
+	assert.Nil(t, words[0].LastReviewedAt)
+
+	created, err := db.CreateWord(ctx, &Word{Japanese: "テスト", Romaji: "tesuto", English: "test"})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"test"}, search("tesuto"))
+
+	require.NoError(t, db.DeleteWord(ctx, created.ID))
+	assert.Empty(t, search("tesuto"))
+}
+
+func TestInitSearchIndexReplacesOtherBuildsIndex(t *testing.T) {
+	raw := testdb.Open(t, DSN)
+	ctx := context.Background()
+	plain := func(name string) string {
+		return "CREATE TABLE " + name + " (word_id INTEGER PRIMARY KEY, japanese TEXT, romaji TEXT, english TEXT)"
+	}
+	// an index left by the other build, and by a build that gave this
+	// build's name to a table of the other kind
+	_, err := raw.Exec(plain(otherSearchTable))
+	require.NoError(t, err)
+	if FullTextSearch {
+		_, err = raw.Exec(plain(searchTable))
+		require.NoError(t, err)
+	}
+
+	db := NewDB(raw)
+	_, err = raw.Exec("INSERT INTO words (japanese, romaji, english) VALUES ('猫', 'neko', 'cat')")
+	require.NoError(t, err)
+	require.NoError(t, db.InitSearchIndex(ctx))
+
+	var schema string
+	require.NoError(t, raw.QueryRow("SELECT sql FROM sqlite_master WHERE name = ?", searchTable).Scan(&schema))
+	assert.Equal(t, FullTextSearch, strings.HasPrefix(schema, "CREATE VIRTUAL TABLE"))
+	err = raw.QueryRow("SELECT sql FROM sqlite_master WHERE name = ?", otherSearchTable).Scan(&schema)
+	assert.ErrorIs(t, err, sql.ErrNoRows, "the other build's index is dropped")
+
+	words, _, err := db.SearchWords(ctx, DefaultUserID, "neko", 1, 10)
+	require.NoError(t, err)
+	assert.Len(t, words, 1)
+}
@@ -0,0 +1,201 @@
+package models
+
+import (
+	"context"
+	"database/sql"
+	"time"
+)
+
+const userColumns = `id, name, role, COALESCE(password_hash, ''), created_at, password_changed_at`
+
+func scanUser(row interface{ Scan(...interface{}) error }) (*User, error) {
+	user := &User{}
+	var passwordChanged sql.NullTime
+	err := row.Scan(&user.ID, &user.Name, &user.Role, &user.PasswordHash, &user.CreatedAt, &passwordChanged)
+	if passwordChanged.Valid {
+		user.PasswordChangedAt = &passwordChanged.Time
+	}
+	return user, err
+}
+
+// GetUser returns a user, or nil if there is none with that id
+func (db *DB) GetUser(ctx context.Context, id int64) (*User, error) {
+	ctx, cancel := db.withTimeout(ctx)
+	defer cancel()
+
+	user, err := scanUser(db.QueryRowContext(ctx, `SELECT `+userColumns+` FROM users WHERE id = ?`, id))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return user, nil
+}
+
+// GetUserByName returns a user, or nil if there is none with that name
+func (db *DB) GetUserByName(ctx context.Context, name string) (*User, error) {
+	ctx, cancel := db.withTimeout(ctx)
+	defer cancel()
+
+	user, err := scanUser(db.QueryRowContext(ctx, `SELECT `+userColumns+` FROM users WHERE name = ?`, name))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return user, nil
+}
+
+func (db *DB) GetUsers(ctx context.Context, page, perPage int) ([]*User, *Pagination, error) {
+	ctx, cancel := db.withTimeout(ctx)
+	defer cancel()
+
+	offset := (page - 1) * perPage
+	users := []*User{}
+
+	rows, err := db.QueryContext(ctx, `
+		SELECT `+userColumns+` FROM users
+		ORDER BY name, id
+		LIMIT ? OFFSET ?`, perPage, offset)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		user, err := scanUser(rows)
+		if err != nil {
+			return nil, nil, err
+		}
+		users = append(users, user)
+	}
+
+	var total int
+	if err := db.QueryRowContext(ctx, "SELECT COUNT(*) FROM users").Scan(&total); err != nil {
+		return nil, nil, err
+	}
+
+	pagination := &Pagination{
+		CurrentPage:  page,
+		TotalPages:   (total + perPage - 1) / perPage,
+		TotalItems:   total,
+		ItemsPerPage: perPage,
+	}
+
+	return users, pagination, nil
+}
+
+func (db *DB) CreateUser(ctx context.Context, name string, role Role) (*User, error) {
+	ctx, cancel := db.withTimeout(ctx)
+	defer cancel()
+
+	result, err := db.ExecContext(ctx, `
+		INSERT INTO users (name, role, created_at) VALUES (?, ?, ?)`, name, role, time.Now())
+	if err != nil {
+		return nil, writeError(err)
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		return nil, err
+	}
+	return db.GetUser(ctx, id)
+}
+
+// UpdateUser saves the role, password hash and password change time of a
+// user; an empty hash removes the password
+func (db *DB) UpdateUser(ctx context.Context, user *User) (*User, error) {
+	ctx, cancel := db.withTimeout(ctx)
+	defer cancel()
+
+	_, err := db.ExecContext(ctx, `
+		UPDATE users SET role = ?, password_hash = NULLIF(?, ''), password_changed_at = ? WHERE id = ?`,
+		user.Role, user.PasswordHash, user.PasswordChangedAt, user.ID)
+	if err != nil {
+		return nil, writeError(err)
+	}
+	return db.GetUser(ctx, user.ID)
+}
+
+// GetUserByAPIKey returns the user an API key acts for and records that the
+// key was used, or returns nil if no key has that hash
+func (db *DB) GetUserByAPIKey(ctx context.Context, keyHash string) (*User, error) {
+	ctx, cancel := db.withTimeout(ctx)
+	defer cancel()
+
+	var userID int64
+	err := db.QueryRowContext(ctx, `SELECT user_id FROM api_keys WHERE key_hash = ?`, keyHash).Scan(&userID)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := db.ExecContext(ctx, `
+		UPDATE api_keys SET last_used_at = ? WHERE key_hash = ?`, time.Now(), keyHash); err != nil {
+		return nil, err
+	}
+	return db.GetUser(ctx, userID)
+}
+
+// GetAPIKeys returns the API keys of a user, without the keys themselves
+func (db *DB) GetAPIKeys(ctx context.Context, userID int64) ([]*APIKey, error) {
+	ctx, cancel := db.withTimeout(ctx)
+	defer cancel()
+
+	keys := []*APIKey{}
+	rows, err := db.QueryContext(ctx, `
+		SELECT id, user_id, name, created_at, last_used_at
+		FROM api_keys
+		WHERE user_id = ?
+		ORDER BY id`, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		key := &APIKey{}
+		var lastUsed sql.NullTime
+		if err := rows.Scan(&key.ID, &key.UserID, &key.Name, &key.CreatedAt, &lastUsed); err != nil {
+			return nil, err
+		}
+		if lastUsed.Valid {
+			key.LastUsedAt = &lastUsed.Time
+		}
+		keys = append(keys, key)
+	}
+	return keys, rows.Err()
+}
+
+// CreateAPIKey stores the hash of a new API key for a user
+func (db *DB) CreateAPIKey(ctx context.Context, userID int64, name, keyHash string) (*APIKey, error) {
+	ctx, cancel := db.withTimeout(ctx)
+	defer cancel()
+
+	now := time.Now()
+	result, err := db.ExecContext(ctx, `
+		INSERT INTO api_keys (user_id, name, key_hash, created_at) VALUES (?, ?, ?, ?)`,
+		userID, name, keyHash, now)
+	if err != nil {
+		return nil, writeError(err)
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		return nil, err
+	}
+	return &APIKey{ID: id, UserID: userID, Name: name, CreatedAt: now}, nil
+}
+
+// DeleteAPIKey revokes one of a user's API keys
+func (db *DB) DeleteAPIKey(ctx context.Context, userID, id int64) error {
+	ctx, cancel := db.withTimeout(ctx)
+	defer cancel()
+
+	_, err := db.ExecContext(ctx, "DELETE FROM api_keys WHERE id = ? AND user_id = ?", id, userID)
+	return err
+}
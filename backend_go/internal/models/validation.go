@@ -0,0 +1,157 @@
+package models
+
+import (
+	"encoding/json"
+	"strings"
+	"unicode"
+)
+
+// ValidationError describes a single invalid field
+type ValidationError struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+// ValidationErrors collects every invalid field of a request so clients can
+// fix them all at once
+type ValidationErrors []ValidationError
+
+func (e ValidationErrors) Error() string {
+	msgs := make([]string, len(e))
+	for i, fe := range e {
+		msgs[i] = fe.Field + ": " + fe.Message
+	}
+	return "validation failed: " + strings.Join(msgs, "; ")
+}
+
+// Is makes ValidationErrors an ErrValidation
+func (e ValidationErrors) Is(target error) bool {
+	return target == ErrValidation
+}
+
+func (e *ValidationErrors) add(field, message string) {
+	*e = append(*e, ValidationError{Field: field, Message: message})
+}
+
+// ValidateWord checks a word before it is written and fills in PartsMap from
+// the raw Parts JSON.
+//
+// parts must be a JSON object. Two keys have a fixed shape:
+//
+//	"type":       string, e.g. "noun" or "i-adjective"
+//	"components": [{"kanji": "新", "romaji": ["shi", "n"]}, ...]
+//
+// Any other key is kept as-is.
+func ValidateWord(word *Word) error {
+	var errs ValidationErrors
+
+	word.Japanese = strings.TrimSpace(word.Japanese)
+	word.Romaji = strings.TrimSpace(word.Romaji)
+	word.English = strings.TrimSpace(word.English)
+
+	if word.Japanese == "" {
+		errs.add("japanese", "is required")
+	} else if !containsJapanese(word.Japanese) {
+		errs.add("japanese", "must contain hiragana, katakana or kanji")
+	}
+
+	if word.Romaji == "" {
+		errs.add("romaji", "is required")
+	} else if !isRomaji(word.Romaji) {
+		errs.add("romaji", "must only contain ASCII letters, spaces, apostrophes and hyphens")
+	}
+
+	if word.English == "" {
+		errs.add("english", "is required")
+	}
+
+	word.PartsMap = nil
+	if word.Parts.Valid && word.Parts.String != "" && word.Parts.String != "null" {
+		if err := json.Unmarshal([]byte(word.Parts.String), &word.PartsMap); err != nil {
+			errs.add("parts", "must be a JSON object")
+		} else {
+			validateParts(word.PartsMap, &errs)
+		}
+	} else {
+		word.Parts.Valid = false
+	}
+
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
+
+func validateParts(parts map[string]interface{}, errs *ValidationErrors) {
+	for key := range parts {
+		if strings.TrimSpace(key) == "" {
+			errs.add("parts", "keys must not be empty")
+		}
+	}
+
+	if v, ok := parts["type"]; ok {
+		if _, ok := v.(string); !ok {
+			errs.add("parts.type", "must be a string")
+		}
+	}
+
+	v, ok := parts["components"]
+	if !ok {
+		return
+	}
+	components, ok := v.([]interface{})
+	if !ok {
+		errs.add("parts.components", "must be an array")
+		return
+	}
+	for _, c := range components {
+		component, ok := c.(map[string]interface{})
+		if !ok {
+			errs.add("parts.components", "entries must be objects")
+			return
+		}
+		kanji, ok := component["kanji"].(string)
+		if !ok || !containsJapanese(kanji) {
+			errs.add("parts.components.kanji", "must be a Japanese string")
+			return
+		}
+		readings, ok := component["romaji"].([]interface{})
+		if !ok || len(readings) == 0 {
+			errs.add("parts.components.romaji", "must be a non-empty array of strings")
+			return
+		}
+		for _, r := range readings {
+			reading, ok := r.(string)
+			if !ok || !isRomaji(reading) {
+				errs.add("parts.components.romaji", "must be a non-empty array of strings")
+				return
+			}
+		}
+	}
+}
+
+// containsJapanese reports whether s has at least one hiragana, katakana or
+// kanji character. Other characters are allowed so words like "Tシャツ" pass.
+func containsJapanese(s string) bool {
+	for _, r := range s {
+		if unicode.In(r, unicode.Hiragana, unicode.Katakana, unicode.Han) || r == 'ー' {
+			return true
+		}
+	}
+	return false
+}
+
+func isRomaji(s string) bool {
+	if s == "" {
+		return false
+	}
+	for _, r := range s {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z':
+		case r == ' ', r == '\'', r == '-':
+		default:
+			return false
+		}
+	}
+	return true
+}
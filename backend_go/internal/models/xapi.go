@@ -0,0 +1,201 @@
+package models
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"time"
+)
+
+// ReviewStatementFilter selects the reviews returned as xAPI statements.
+// Zero values mean "no filter". Reviews are ordered by id, newest first
+// unless Ascending is set, and AfterID continues a listing from the last
+// id of the previous page.
+type ReviewStatementFilter struct {
+	UserID      int64
+	StatementID string
+	ReviewID    int64
+	Since       time.Time
+	Until       time.Time
+	AfterID     int64
+	Ascending   bool
+	Limit       int
+}
+
+// GetStudySessionByRegistration returns the study session reported under an
+// xAPI registration, or nil if there is none
+func (db *DB) GetStudySessionByRegistration(ctx context.Context, registration string) (*StudySession, error) {
+	ctx, cancel := db.withTimeout(ctx)
+	defer cancel()
+
+	var id int64
+	err := db.QueryRowContext(ctx, `SELECT id FROM study_sessions WHERE registration = ?`, registration).Scan(&id)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return db.GetStudySession(ctx, id)
+}
+
+// GetRecordedStatementIDs returns the ids from statementIDs that userID has
+// already recorded
+func (db *DB) GetRecordedStatementIDs(ctx context.Context, userID int64, statementIDs []string) ([]string, error) {
+	ctx, cancel := db.withTimeout(ctx)
+	defer cancel()
+
+	recorded := []string{}
+	if len(statementIDs) == 0 {
+		return recorded, nil
+	}
+	ids, err := json.Marshal(statementIDs)
+	if err != nil {
+		return nil, err
+	}
+	rows, err := db.QueryContext(ctx, `
+		SELECT statement_id FROM word_review_items
+		WHERE user_id = ? AND statement_id IN (SELECT value FROM json_each(?))`, userID, string(ids))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		recorded = append(recorded, id)
+	}
+	return recorded, rows.Err()
+}
+
+// StatementRecord is what one xAPI statement records: Review, or the end of
+// the study session at EndedAt when Review is nil. The session is SessionID
+// or, when that is 0, the session of Registration, created in GroupID for
+// ActivityID the first time the batch needs it.
+type StatementRecord struct {
+	SessionID    int64
+	Registration string
+	GroupID      int64
+	ActivityID   int64
+	Review       *WordReviewItem
+	EndedAt      time.Time
+}
+
+// Scheduler returns the progress of a word after a review, given its
+// progress before, which is nil if the user never reviewed it
+type Scheduler func(progress *WordProgress, review *WordReviewItem) *WordProgress
+
+// RecordStatements records a batch of statements of a user in order,
+// rescheduling each reviewed word with schedule. A review whose statement id
+// the user already recorded is skipped, as is the session it would have
+// started; one whose id another user recorded fails with ErrDuplicate.
+//
+// Everything happens in one transaction, so either the whole batch is
+// recorded or none of it is.
+func (db *DB) RecordStatements(ctx context.Context, userID int64, records []*StatementRecord, schedule Scheduler) error {
+	ctx, cancel := db.withTimeout(ctx)
+	defer cancel()
+
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	sessions := map[string]int64{}
+	for _, record := range records {
+		if record.Review != nil && record.Review.StatementID != "" {
+			var exists bool
+			err := tx.QueryRowContext(ctx, `
+				SELECT EXISTS (SELECT 1 FROM word_review_items WHERE statement_id = ? AND user_id = ?)`,
+				record.Review.StatementID, userID).Scan(&exists)
+			if err != nil {
+				return err
+			}
+			if exists {
+				continue
+			}
+		}
+
+		sessionID := record.SessionID
+		if sessionID == 0 {
+			if sessionID = sessions[record.Registration]; sessionID == 0 {
+				sessionID, err = createStudySession(ctx, tx, userID, record.GroupID, record.ActivityID, record.Registration)
+				if err != nil {
+					return err
+				}
+				sessions[record.Registration] = sessionID
+			}
+		}
+
+		if record.Review == nil {
+			// a session that has already ended is left as it is
+			if _, err := endStudySession(ctx, tx, sessionID, record.EndedAt); err != nil {
+				return err
+			}
+			continue
+		}
+		review := *record.Review
+		review.UserID = userID
+		review.StudySessionID = sessionID
+		if _, err := recordReview(ctx, tx, &review, schedule); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// GetReviewStatements returns the reviews matching filter for the xAPI
+// statements listing
+func (db *DB) GetReviewStatements(ctx context.Context, filter ReviewStatementFilter) ([]*ReviewExport, error) {
+	ctx, cancel := db.withTimeout(ctx)
+	defer cancel()
+
+	q := queryBuilder{}
+	if filter.UserID != 0 {
+		q.where("wri.user_id = ?", filter.UserID)
+	}
+	if filter.StatementID != "" {
+		q.where("wri.statement_id = ?", filter.StatementID)
+	}
+	if filter.ReviewID != 0 {
+		q.where("wri.id = ?", filter.ReviewID)
+	}
+	if !filter.Since.IsZero() {
+		q.where("datetime(wri.created_at) > datetime(?)", filter.Since.UTC().Format(sqliteTimeFormat))
+	}
+	if !filter.Until.IsZero() {
+		q.where("datetime(wri.created_at) <= datetime(?)", filter.Until.UTC().Format(sqliteTimeFormat))
+	}
+	order := "DESC"
+	if filter.Ascending {
+		order = "ASC"
+		if filter.AfterID != 0 {
+			q.where("wri.id > ?", filter.AfterID)
+		}
+	} else if filter.AfterID != 0 {
+		q.where("wri.id < ?", filter.AfterID)
+	}
+
+	rows, err := db.QueryContext(ctx, reviewExportQuery+`
+		`+q.whereClause()+`
+		ORDER BY wri.id `+order+`
+		LIMIT ?`, append(q.args, filter.Limit)...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	reviews := []*ReviewExport{}
+	for rows.Next() {
+		review, err := scanReviewExport(rows)
+		if err != nil {
+			return nil, err
+		}
+		reviews = append(reviews, review)
+	}
+	return reviews, rows.Err()
+}
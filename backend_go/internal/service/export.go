@@ -0,0 +1,61 @@
+package service
+
+import (
+	"context"
+	"io"
+	"strings"
+
+	"github.com/gen-ai-bootcamp-2025/backend_go/internal/exporter"
+	"github.com/gen-ai-bootcamp-2025/backend_go/internal/models"
+)
+
+// ValidateExportFormat checks format against the formats an export supports
+func ValidateExportFormat(formats []string, format string) error {
+	if !exporter.IsFormat(formats, format) {
+		return models.ValidationErrors{{Field: "format", Message: "must be one of " + strings.Join(formats, ", ")}}
+	}
+	return nil
+}
+
+// ExportGroupWords streams a group's words, with the acting user's review
+// stats, to w
+func (s *Service) ExportGroupWords(ctx context.Context, group *models.Group, format string, w io.Writer) error {
+	user, err := actingUser(ctx)
+	if err != nil {
+		return err
+	}
+	if err := ValidateExportFormat(exporter.WordFormats, format); err != nil {
+		return err
+	}
+	writer, err := exporter.NewWordWriter(format, w, group.Name)
+	if err != nil {
+		return err
+	}
+	err = s.db.EachGroupWord(ctx, user.ID, group.ID, writer.Write)
+	if closeErr := writer.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
+
+// ExportReviews streams the reviews of the acting user's study sessions
+// matching filter to w
+func (s *Service) ExportReviews(ctx context.Context, filter models.StudySessionFilter, format string, w io.Writer) error {
+	user, err := actingUser(ctx)
+	if err != nil {
+		return err
+	}
+	filter.UserID = user.ID
+	if err := ValidateExportFormat(exporter.ReviewFormats, format); err != nil {
+		return err
+	}
+	writer, err := exporter.NewReviewWriter(format, w)
+	if err != nil {
+		return err
+	}
+	err = s.db.EachReview(ctx, filter, writer.Write)
+	if closeErr := writer.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
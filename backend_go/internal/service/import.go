@@ -0,0 +1,56 @@
+package service
+
+import (
+	"context"
+	"io"
+	"strings"
+
+	"github.com/gen-ai-bootcamp-2025/backend_go/internal/importer"
+	"github.com/gen-ai-bootcamp-2025/backend_go/internal/models"
+)
+
+// ImportRequest describes a vocabulary file to import and the group its words
+// go into: an existing group by id, or a group by name that is created if it
+// does not exist
+type ImportRequest struct {
+	FileName  string
+	File      io.ReaderAt
+	Size      int64
+	Format    string
+	Mapping   importer.Mapping
+	GroupID   int64
+	GroupName string
+	DryRun    bool
+}
+
+// ImportWords imports the valid rows of a CSV, TSV or Anki file and reports
+// the rows that were left out
+func (s *Service) ImportWords(ctx context.Context, req ImportRequest) (*importer.Report, error) {
+	req.GroupName = strings.TrimSpace(req.GroupName)
+	switch {
+	case req.GroupID != 0 && req.GroupName != "":
+		return nil, models.ValidationErrors{{Field: "group_id", Message: "cannot be combined with group_name"}}
+	case req.GroupID != 0:
+		if _, err := s.GetGroup(ctx, req.GroupID); err != nil {
+			return nil, err
+		}
+	case req.GroupName == "":
+		return nil, models.ValidationErrors{{Field: "group_name", Message: "is required when group_id is not given"}}
+	}
+
+	set, err := importer.Parse(req.FileName, req.File, req.Size, req.Format, req.Mapping)
+	if err != nil {
+		return nil, models.ValidationErrors{{Field: "file", Message: err.Error()}}
+	}
+
+	result, err := s.db.ImportWords(ctx, req.GroupID, req.GroupName, set.Words, req.DryRun)
+	if err != nil {
+		return nil, err
+	}
+
+	errs := set.Errors
+	if errs == nil {
+		errs = []importer.RowError{}
+	}
+	return &importer.Report{ImportResult: result, Rows: set.Rows, Errors: errs}, nil
+}
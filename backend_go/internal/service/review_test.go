@@ -0,0 +1,118 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/gen-ai-bootcamp-2025/backend_go/internal/models"
+	"github.com/gen-ai-bootcamp-2025/backend_go/internal/testdb"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// openTestDB opens a new database file at the current schema with its
+// search index, the way the server does
+func openTestDB(t *testing.T) *models.DB {
+	db := models.NewDB(testdb.Open(t, models.DSN))
+	require.NoError(t, db.InitSearchIndex(context.Background()))
+	return db
+}
+
+func TestReviewWordChecksSession(t *testing.T) {
+	db := openTestDB(t)
+	svc := NewService(db)
+	ctx := context.Background()
+
+	user, err := db.CreateUser(ctx, "hana", models.RoleLearner)
+	require.NoError(t, err)
+	other, err := db.CreateUser(ctx, "ken", models.RoleLearner)
+	require.NoError(t, err)
+	animals, err := db.CreateGroup(ctx, "Animals")
+	require.NoError(t, err)
+	colors, err := db.CreateGroup(ctx, "Colors")
+	require.NoError(t, err)
+	activity, err := db.CreateStudyActivity(ctx, &models.StudyActivity{Name: "Cards", LaunchURL: "http://localhost", Enabled: true})
+	require.NoError(t, err)
+	cat, err := db.CreateWord(ctx, &models.Word{Japanese: "猫", Romaji: "neko", English: "cat"})
+	require.NoError(t, err)
+	red, err := db.CreateWord(ctx, &models.Word{Japanese: "赤", Romaji: "aka", English: "red"})
+	require.NoError(t, err)
+	_, err = db.AddWordsToGroup(ctx, animals.ID, []int64{cat.ID})
+	require.NoError(t, err)
+	_, err = db.AddWordsToGroup(ctx, colors.ID, []int64{red.ID})
+	require.NoError(t, err)
+
+	ctx = WithUser(ctx, user)
+	session, err := svc.CreateStudySession(ctx, animals.ID, activity.ID)
+	require.NoError(t, err)
+	othersSession, err := db.CreateStudySession(ctx, other.ID, animals.ID, activity.ID)
+	require.NoError(t, err)
+
+	review := func(sessionID, wordID int64) error {
+		_, err := svc.ReviewWord(ctx, &models.WordReviewItem{StudySessionID: sessionID, WordID: wordID, Grade: models.GradeGood})
+		return err
+	}
+
+	assert.NoError(t, review(session.ID, cat.ID))
+	err = review(session.ID, red.ID)
+	assert.ErrorIs(t, err, ErrWordNotInGroup)
+	assert.ErrorIs(t, err, models.ErrValidation)
+	assert.ErrorIs(t, review(session.ID, 999), ErrWordNotFound)
+	assert.ErrorIs(t, review(999, cat.ID), ErrStudySessionNotFound)
+	assert.ErrorIs(t, review(othersSession.ID, cat.ID), ErrStudySessionNotFound)
+
+	svc.SetCrossGroupReviews(true)
+	assert.NoError(t, review(session.ID, red.ID), "cross-group drills may review any word")
+	assert.ErrorIs(t, review(session.ID, 999), ErrWordNotFound)
+
+	_, err = svc.EndStudySession(ctx, session.ID)
+	require.NoError(t, err)
+	err = review(session.ID, cat.ID)
+	assert.ErrorIs(t, err, ErrStudySessionEnded)
+	assert.ErrorIs(t, err, models.ErrConflict)
+
+	summary, err := svc.GetStudySessionSummary(ctx, session.ID)
+	require.NoError(t, err)
+	assert.Equal(t, 2, summary.ReviewItemCount, "only the accepted reviews were recorded")
+}
+
+// endingDB ends the study session of a review just before recording it, as
+// a concurrent request might
+type endingDB struct {
+	*models.DB
+}
+
+func (db endingDB) RecordReview(ctx context.Context, review *models.WordReviewItem, schedule models.Scheduler) (*models.WordReviewItem, error) {
+	if _, err := db.EndStudySession(ctx, review.StudySessionID, time.Now()); err != nil {
+		return nil, err
+	}
+	return db.DB.RecordReview(ctx, review, schedule)
+}
+
+func TestReviewWordRacesSessionEnd(t *testing.T) {
+	db := openTestDB(t)
+	svc := NewService(endingDB{db})
+	ctx := context.Background()
+
+	user, err := db.CreateUser(ctx, "hana", models.RoleLearner)
+	require.NoError(t, err)
+	group, err := db.CreateGroup(ctx, "Animals")
+	require.NoError(t, err)
+	activity, err := db.CreateStudyActivity(ctx, &models.StudyActivity{Name: "Cards", LaunchURL: "http://localhost", Enabled: true})
+	require.NoError(t, err)
+	cat, err := db.CreateWord(ctx, &models.Word{Japanese: "猫", Romaji: "neko", English: "cat"})
+	require.NoError(t, err)
+	_, err = db.AddWordsToGroup(ctx, group.ID, []int64{cat.ID})
+	require.NoError(t, err)
+
+	ctx = WithUser(ctx, user)
+	session, err := svc.CreateStudySession(ctx, group.ID, activity.ID)
+	require.NoError(t, err)
+	_, err = svc.ReviewWord(ctx, &models.WordReviewItem{StudySessionID: session.ID, WordID: cat.ID, Grade: models.GradeGood})
+	assert.ErrorIs(t, err, ErrStudySessionEnded)
+
+	summary, err := svc.GetStudySessionSummary(ctx, session.ID)
+	require.NoError(t, err)
+	assert.Zero(t, summary.ReviewItemCount, "no review lands in the ended session")
+}
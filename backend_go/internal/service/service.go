@@ -1,118 +1,853 @@
 package service
 
 import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
 	"github.com/gen-ai-bootcamp-2025/backend_go/internal/models"
 )
 
+// Errors the service returns, each of one of the kinds in models, e.g.
+// errors.Is(ErrWordNotFound, models.ErrNotFound) is true
+var (
+	ErrStudyActivityNotFound = models.NewError(models.ErrNotFound, "study_activity_not_found", "study activity not found")
+	ErrStudyActivityDisabled = models.NewError(models.ErrValidation, "study_activity_disabled", "study activity is disabled")
+	ErrStudyActivityInUse    = models.NewError(models.ErrConflict, "study_activity_in_use", "study activity has recorded study sessions")
+	ErrGroupNotFound         = models.NewError(models.ErrNotFound, "group_not_found", "group not found")
+	ErrGroupInUse            = models.NewError(models.ErrConflict, "group_in_use", "group has recorded study sessions")
+	ErrGroupExists           = models.NewError(models.ErrConflict, "group_exists", "a group with that name already exists")
+	ErrWordNotFound          = models.NewError(models.ErrNotFound, "word_not_found", "word not found")
+	ErrWordNotInGroup        = models.NewError(models.ErrValidation, "word_not_in_group", "word is not in the study session's group")
+	ErrStudySessionNotFound  = models.NewError(models.ErrNotFound, "study_session_not_found", "study session not found")
+	ErrStudySessionEnded     = models.NewError(models.ErrConflict, "study_session_ended", "study session has already ended")
+	ErrStatementNotFound     = models.NewError(models.ErrNotFound, "statement_not_found", "statement not found")
+	ErrStatementIDTaken      = models.NewError(models.ErrConflict, "statement_id_taken", "statement id was recorded by another user")
+	ErrUserNotFound          = models.NewError(models.ErrNotFound, "user_not_found", "user not found")
+	ErrUserExists            = models.NewError(models.ErrConflict, "user_exists", "a user with that name already exists")
+	ErrNoUser                = models.NewError(models.ErrUnauthorized, "no_user", "no user to act for")
+	ErrInvalidCredentials    = models.NewError(models.ErrUnauthorized, "invalid_credentials", "invalid credentials")
+	ErrForbidden             = models.NewError(models.ErrForbidden, "forbidden", "not allowed")
+	ErrWrongPassword         = models.NewError(models.ErrForbidden, "wrong_password", "current password is incorrect")
+	ErrAPIKeyNotFound        = models.NewError(models.ErrNotFound, "api_key_not_found", "api key not found")
+)
+
+// Page sizes used until SetPageSize is called
+const (
+	DefaultPerPage    = 100
+	DefaultMaxPerPage = 500
+)
+
+// DefaultSessionIdleTimeout is used until SetSessionIdleTimeout is called
+const DefaultSessionIdleTimeout = 30 * time.Minute
+
+// DefaultActivityBase is used until SetActivityBase is called
+const DefaultActivityBase = "http://localhost:8080/api"
+
 type Service struct {
-	db models.DBInterface
+	db          models.DBInterface
+	now         func() time.Time
+	perPage     int
+	maxPerPage  int
+	idleTimeout time.Duration
+	crossGroup  bool
+	base        string
 }
 
 func NewService(db models.DBInterface) *Service {
-	return &Service{db: db}
+	return &Service{db: db, now: time.Now, perPage: DefaultPerPage, maxPerPage: DefaultMaxPerPage,
+		idleTimeout: DefaultSessionIdleTimeout, base: DefaultActivityBase}
+}
+
+// SetCrossGroupReviews sets whether a study session may review words from
+// outside its group, for drills that mix groups. By default it may not.
+func (s *Service) SetCrossGroupReviews(allow bool) {
+	s.crossGroup = allow
+}
+
+// SetActivityBase sets the base of the xAPI activity IRIs the portal emits
+// and accepts, the public URL of the API such as https://portal.example.com/api
+func (s *Service) SetActivityBase(base string) {
+	s.base = strings.TrimRight(base, "/")
 }
 
-func (s *Service) GetWord(id int64) (*models.Word, error) {
-	return s.db.GetWord(id)
+// SetSessionIdleTimeout sets how long a study session may go without a
+// review before AbandonIdleStudySessions abandons it; zero means never
+func (s *Service) SetSessionIdleTimeout(d time.Duration) {
+	s.idleTimeout = d
 }
 
-func (s *Service) GetWords(page int) (*models.PaginatedResponse, error) {
-	perPage := 100
-	words, pagination, err := s.db.GetWords(page, perPage)
+// SetPageSize sets the page size of paginated listings and the largest page
+// size a request may ask for
+func (s *Service) SetPageSize(perPage, maxPerPage int) {
+	s.perPage = perPage
+	s.maxPerPage = maxPerPage
+}
+
+// checkPage checks the page a listing is asked for, fills in the default page
+// size and returns the cursor to continue after. Only listings that support
+// cursors pass cursors.
+func (s *Service) checkPage(page *models.PageRequest, cursors bool) (models.Cursor, error) {
+	var errs models.ValidationErrors
+	switch {
+	case page.Page == 0:
+		page.Page = 1
+	case page.Page < 0:
+		errs = append(errs, models.ValidationError{Field: "page", Message: "must be at least 1"})
+	}
+	switch {
+	case page.PerPage == 0:
+		page.PerPage = s.perPage
+	case page.PerPage < 0 || page.PerPage > s.maxPerPage:
+		errs = append(errs, models.ValidationError{Field: "per_page", Message: fmt.Sprintf("must be between 1 and %d", s.maxPerPage)})
+	}
+
+	var after models.Cursor
+	if page.Cursor != "" {
+		var err error
+		switch {
+		case !cursors:
+			errs = append(errs, models.ValidationError{Field: "cursor", Message: "is not supported by this listing"})
+		case page.Page > 1:
+			errs = append(errs, models.ValidationError{Field: "cursor", Message: "cannot be combined with page"})
+		default:
+			if after, err = models.ParseCursor(page.Cursor); err != nil {
+				errs = append(errs, models.ValidationError{Field: "cursor", Message: err.Error()})
+			}
+		}
+	}
+	if len(errs) > 0 {
+		return models.Cursor{}, errs
+	}
+	return after, nil
+}
+
+// GetWord returns a word with the acting user's review stats
+func (s *Service) GetWord(ctx context.Context, id int64) (*models.Word, error) {
+	user, err := actingUser(ctx)
+	if err != nil {
+		return nil, err
+	}
+	word, err := s.db.GetWord(ctx, user.ID, id)
+	if err != nil {
+		return nil, err
+	}
+	if word == nil {
+		return nil, ErrWordNotFound
+	}
+	return word, nil
+}
+
+// SearchWords matches q against the japanese, romaji and english of every word
+func (s *Service) SearchWords(ctx context.Context, q string, page models.PageRequest) (*models.PaginatedResponse, error) {
+	user, err := actingUser(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if strings.TrimSpace(q) == "" {
+		return nil, models.ValidationErrors{{Field: "q", Message: "is required"}}
+	}
+
+	if _, err := s.checkPage(&page, false); err != nil {
+		return nil, err
+	}
+	words, pagination, err := s.db.SearchWords(ctx, user.ID, q, page.Page, page.PerPage)
 	if err != nil {
 		return nil, err
 	}
 
 	return &models.PaginatedResponse{
 		Items:      words,
-		Pagination: *pagination,
+		Pagination: pagination,
 	}, nil
 }
 
-func (s *Service) GetGroup(id int64) (*models.Group, error) {
-	return s.db.GetGroup(id)
+func (s *Service) CreateWord(ctx context.Context, word *models.Word) (*models.Word, error) {
+	if err := models.ValidateWord(word); err != nil {
+		return nil, err
+	}
+	return s.db.CreateWord(ctx, word)
+}
+
+// UpdateWord replaces every field of an existing word
+func (s *Service) UpdateWord(ctx context.Context, word *models.Word) (*models.Word, error) {
+	existing, err := s.GetWord(ctx, word.ID)
+	if err != nil {
+		return nil, err
+	}
+	if err := models.ValidateWord(word); err != nil {
+		return nil, err
+	}
+	return s.saveWord(ctx, word, existing.WordStats)
+}
+
+// PatchWord updates only the fields set in patch
+func (s *Service) PatchWord(ctx context.Context, id int64, patch models.WordPatch) (*models.Word, error) {
+	word, err := s.GetWord(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if patch.Japanese != nil {
+		word.Japanese = *patch.Japanese
+	}
+	if patch.Romaji != nil {
+		word.Romaji = *patch.Romaji
+	}
+	if patch.English != nil {
+		word.English = *patch.English
+	}
+	if patch.Parts != nil {
+		word.Parts.String = *patch.Parts
+		word.Parts.Valid = true
+	}
+
+	if err := models.ValidateWord(word); err != nil {
+		return nil, err
+	}
+	return s.saveWord(ctx, word, word.WordStats)
 }
 
-func (s *Service) GetGroups(page int) (*models.PaginatedResponse, error) {
-	perPage := 100
-	groups, pagination, err := s.db.GetGroups(page, perPage)
+// saveWord writes an edited word and returns it with the review stats it had
+// before, which editing does not change
+func (s *Service) saveWord(ctx context.Context, word *models.Word, stats models.WordStats) (*models.Word, error) {
+	updated, err := s.db.UpdateWord(ctx, word)
+	if err != nil || updated == nil {
+		return updated, err
+	}
+	updated.WordStats = stats
+	return updated, nil
+}
+
+func (s *Service) DeleteWord(ctx context.Context, id int64) error {
+	if _, err := s.GetWord(ctx, id); err != nil {
+		return err
+	}
+	return s.db.DeleteWord(ctx, id)
+}
+
+// GetWords lists words with the acting user's review stats
+func (s *Service) GetWords(ctx context.Context, filter models.WordFilter, page models.PageRequest) (*models.PaginatedResponse, error) {
+	user, err := actingUser(ctx)
+	if err != nil {
+		return nil, err
+	}
+	filter.UserID = user.ID
+	if err := models.ValidateSort(models.WordSortColumns, filter.SortBy, filter.Order); err != nil {
+		return nil, err
+	}
+
+	if _, err := s.checkPage(&page, false); err != nil {
+		return nil, err
+	}
+	words, pagination, err := s.db.GetWords(ctx, filter, page.Page, page.PerPage)
+	if err != nil {
+		return nil, err
+	}
+
+	return &models.PaginatedResponse{
+		Items:      words,
+		Pagination: pagination,
+	}, nil
+}
+
+func (s *Service) GetGroup(ctx context.Context, id int64) (*models.Group, error) {
+	group, err := s.db.GetGroup(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if group == nil {
+		return nil, ErrGroupNotFound
+	}
+	return group, nil
+}
+
+func (s *Service) CreateGroup(ctx context.Context, name string) (*models.Group, error) {
+	name, err := validateGroupName(name)
+	if err != nil {
+		return nil, err
+	}
+	group, err := s.db.CreateGroup(ctx, name)
+	if errors.Is(err, models.ErrDuplicate) {
+		return nil, ErrGroupExists
+	}
+	return group, err
+}
+
+func (s *Service) RenameGroup(ctx context.Context, id int64, name string) (*models.Group, error) {
+	if _, err := s.GetGroup(ctx, id); err != nil {
+		return nil, err
+	}
+	name, err := validateGroupName(name)
+	if err != nil {
+		return nil, err
+	}
+	group, err := s.db.RenameGroup(ctx, id, name)
+	if errors.Is(err, models.ErrDuplicate) {
+		return nil, ErrGroupExists
+	}
+	return group, err
+}
+
+// DeleteGroup removes a group and its memberships. Groups that already have
+// study sessions are kept so history stays attributable.
+func (s *Service) DeleteGroup(ctx context.Context, id int64) error {
+	if _, err := s.GetGroup(ctx, id); err != nil {
+		return err
+	}
+
+	count, err := s.db.CountStudySessionsByGroup(ctx, id)
+	if err != nil {
+		return err
+	}
+	if count > 0 {
+		return ErrGroupInUse
+	}
+
+	// A session recorded since counting still keeps the group
+	err = s.db.DeleteGroup(ctx, id)
+	if errors.Is(err, models.ErrReferenced) {
+		return ErrGroupInUse
+	}
+	return err
+}
+
+// AddWordsToGroup adds words to a group and returns how many were not
+// already members. Unknown word ids reject the whole request.
+func (s *Service) AddWordsToGroup(ctx context.Context, groupID int64, wordIDs []int64) (int, error) {
+	if _, err := s.GetGroup(ctx, groupID); err != nil {
+		return 0, err
+	}
+	if err := s.validateWordIDs(ctx, wordIDs); err != nil {
+		return 0, err
+	}
+	return s.db.AddWordsToGroup(ctx, groupID, wordIDs)
+}
+
+// RemoveWordsFromGroup removes words from a group and returns how many were members
+func (s *Service) RemoveWordsFromGroup(ctx context.Context, groupID int64, wordIDs []int64) (int, error) {
+	if _, err := s.GetGroup(ctx, groupID); err != nil {
+		return 0, err
+	}
+	if len(wordIDs) == 0 {
+		return 0, errNoWordIDs
+	}
+	return s.db.RemoveWordsFromGroup(ctx, groupID, wordIDs)
+}
+
+// errNoWordIDs rejects bulk membership changes without any words
+var errNoWordIDs = models.ValidationErrors{{Field: "word_ids", Message: "must not be empty"}}
+
+func (s *Service) validateWordIDs(ctx context.Context, wordIDs []int64) error {
+	if len(wordIDs) == 0 {
+		return errNoWordIDs
+	}
+
+	missing, err := s.db.GetMissingWordIDs(ctx, wordIDs)
+	if err != nil {
+		return err
+	}
+	if len(missing) > 0 {
+		ids := make([]string, len(missing))
+		for i, id := range missing {
+			ids[i] = strconv.FormatInt(id, 10)
+		}
+		return models.ValidationErrors{{Field: "word_ids", Message: "unknown word ids: " + strings.Join(ids, ", ")}}
+	}
+	return nil
+}
+
+func validateGroupName(name string) (string, error) {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return "", models.ValidationErrors{{Field: "name", Message: "is required"}}
+	}
+	return name, nil
+}
+
+func (s *Service) GetGroups(ctx context.Context, filter models.GroupFilter, page models.PageRequest) (*models.PaginatedResponse, error) {
+	if err := models.ValidateSort(models.GroupSortColumns, filter.SortBy, filter.Order); err != nil {
+		return nil, err
+	}
+
+	if _, err := s.checkPage(&page, false); err != nil {
+		return nil, err
+	}
+	groups, pagination, err := s.db.GetGroups(ctx, filter, page.Page, page.PerPage)
 	if err != nil {
 		return nil, err
 	}
 
 	return &models.PaginatedResponse{
 		Items:      groups,
-		Pagination: *pagination,
+		Pagination: pagination,
 	}, nil
 }
 
-func (s *Service) CreateStudySession(groupID, activityID int64) (*models.StudySession, error) {
-	return s.db.CreateStudySession(groupID, activityID)
+func (s *Service) GetStudyActivity(ctx context.Context, id int64) (*models.StudyActivity, error) {
+	activity, err := s.db.GetStudyActivity(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if activity == nil {
+		return nil, ErrStudyActivityNotFound
+	}
+	return activity, nil
 }
 
-func (s *Service) GetStudySession(id int64) (*models.StudySession, error) {
-	return s.db.GetStudySession(id)
+func (s *Service) GetStudyActivities(ctx context.Context, page models.PageRequest) (*models.PaginatedResponse, error) {
+	if _, err := s.checkPage(&page, false); err != nil {
+		return nil, err
+	}
+	activities, pagination, err := s.db.GetStudyActivities(ctx, page.Page, page.PerPage)
+	if err != nil {
+		return nil, err
+	}
+
+	return &models.PaginatedResponse{
+		Items:      activities,
+		Pagination: pagination,
+	}, nil
 }
 
-func (s *Service) ReviewWord(wordID, sessionID int64, correct bool) (*models.WordReviewItem, error) {
-	return s.db.CreateWordReview(wordID, sessionID, correct)
+func (s *Service) CreateStudyActivity(ctx context.Context, activity *models.StudyActivity) (*models.StudyActivity, error) {
+	return s.db.CreateStudyActivity(ctx, activity)
 }
 
-func (s *Service) GetQuickStats() (*models.QuickStats, error) {
-	return s.db.GetQuickStats()
+func (s *Service) UpdateStudyActivity(ctx context.Context, activity *models.StudyActivity) (*models.StudyActivity, error) {
+	if _, err := s.GetStudyActivity(ctx, activity.ID); err != nil {
+		return nil, err
+	}
+	return s.db.UpdateStudyActivity(ctx, activity)
 }
 
-func (s *Service) ResetHistory() error {
-	return s.db.ResetHistory()
+// DeleteStudyActivity removes an activity from the catalog. Activities that
+// already have study sessions are kept so history stays attributable; disable
+// them instead.
+func (s *Service) DeleteStudyActivity(ctx context.Context, id int64) error {
+	if _, err := s.GetStudyActivity(ctx, id); err != nil {
+		return err
+	}
+
+	count, err := s.db.CountStudySessionsByActivity(ctx, id)
+	if err != nil {
+		return err
+	}
+	if count > 0 {
+		return ErrStudyActivityInUse
+	}
+
+	err = s.db.DeleteStudyActivity(ctx, id)
+	if errors.Is(err, models.ErrReferenced) {
+		return ErrStudyActivityInUse
+	}
+	return err
 }
 
-func (s *Service) FullReset() error {
-	return s.db.FullReset()
+// CreateStudySession starts a study session for the acting user
+func (s *Service) CreateStudySession(ctx context.Context, groupID, activityID int64) (*models.StudySession, error) {
+	user, err := actingUser(ctx)
+	if err != nil {
+		return nil, err
+	}
+	activity, err := s.GetStudyActivity(ctx, activityID)
+	if err != nil {
+		return nil, err
+	}
+	if !activity.Enabled {
+		return nil, ErrStudyActivityDisabled
+	}
+	session, err := s.db.CreateStudySession(ctx, user.ID, groupID, activityID)
+	if errors.Is(err, models.ErrMissingReference) {
+		// the activity was found above, so the group is missing
+		return nil, ErrGroupNotFound
+	}
+	return session, err
 }
 
-func (s *Service) GetLastStudySession() (*models.StudySession, error) {
-	return s.db.GetLastStudySession()
+// GetStudySession returns one of the acting user's study sessions. Other
+// users' sessions are reported as not found.
+func (s *Service) GetStudySession(ctx context.Context, id int64) (*models.StudySession, error) {
+	user, err := actingUser(ctx)
+	if err != nil {
+		return nil, err
+	}
+	session, err := s.db.GetStudySession(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if session == nil || session.UserID != user.ID {
+		return nil, ErrStudySessionNotFound
+	}
+	return session, nil
 }
 
-func (s *Service) GetStudyProgress() (*models.StudyProgress, error) {
-	return s.db.GetStudyProgress()
+// EndStudySession completes one of the acting user's active study sessions
+// and returns its summary
+func (s *Service) EndStudySession(ctx context.Context, id int64) (*models.StudySessionSummary, error) {
+	if _, err := s.GetStudySession(ctx, id); err != nil {
+		return nil, err
+	}
+	ended, err := s.db.EndStudySession(ctx, id, s.now())
+	if err != nil {
+		return nil, err
+	}
+	if !ended {
+		return nil, ErrStudySessionEnded
+	}
+	return s.GetStudySessionSummary(ctx, id)
 }
 
-func (s *Service) GetStudySessionsByActivity(activityID int64, page int) (*models.PaginatedResponse, error) {
-	perPage := 100
-	sessions, pagination, err := s.db.GetStudySessionsByActivity(activityID, page, perPage)
+// GetStudySessionSummary returns how one of the acting user's study sessions
+// went. The duration of an active session runs until now.
+func (s *Service) GetStudySessionSummary(ctx context.Context, id int64) (*models.StudySessionSummary, error) {
+	user, err := actingUser(ctx)
+	if err != nil {
+		return nil, err
+	}
+	summary, err := s.db.GetStudySessionSummary(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if summary == nil || summary.UserID != user.ID {
+		return nil, ErrStudySessionNotFound
+	}
+
+	end := s.now()
+	if summary.EndedAt != nil {
+		end = *summary.EndedAt
+	}
+	if d := end.Sub(summary.CreatedAt); d > 0 {
+		summary.DurationSeconds = int64(d / time.Second)
+	}
+	if summary.ReviewItemCount > 0 {
+		summary.Accuracy = 100 * float64(summary.CorrectCount) / float64(summary.ReviewItemCount)
+	}
+	return summary, nil
+}
+
+// AbandonIdleStudySessions abandons every user's active study sessions that
+// have gone without a review for the idle timeout, and returns how many it
+// abandoned
+func (s *Service) AbandonIdleStudySessions(ctx context.Context) (int64, error) {
+	if s.idleTimeout <= 0 {
+		return 0, nil
+	}
+	return s.db.AbandonIdleStudySessions(ctx, s.now().Add(-s.idleTimeout))
+}
+
+// ReviewWord records a review in one of the acting user's active study
+// sessions and reschedules the word for the user's spaced repetition
+func (s *Service) ReviewWord(ctx context.Context, review *models.WordReviewItem) (*models.WordReviewItem, error) {
+	session, err := s.GetStudySession(ctx, review.StudySessionID)
+	if err != nil {
+		return nil, err
+	}
+	if session.Status != models.StudySessionActive {
+		return nil, ErrStudySessionEnded
+	}
+	if err := s.checkGroupWord(ctx, session.GroupID, review.WordID); err != nil {
+		return nil, err
+	}
+	review.UserID = session.UserID
+
+	created, err := s.db.RecordReview(ctx, review, s.schedule)
+	if errors.Is(err, models.ErrSessionNotActive) {
+		// the session ended since it was checked above
+		return nil, ErrStudySessionEnded
+	}
+	if errors.Is(err, models.ErrMissingReference) {
+		// the session was found above, so the word is missing
+		return nil, ErrWordNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return created, nil
+}
+
+// schedule is the models.Scheduler of reviews made now
+func (s *Service) schedule(progress *models.WordProgress, review *models.WordReviewItem) *models.WordProgress {
+	now := s.now().UTC()
+	if progress == nil {
+		progress = NewWordProgress(review.UserID, review.WordID, now)
+	}
+	return Schedule(progress, review.Grade, now)
+}
+
+// checkGroupWord checks that a word may be reviewed in a study session of a
+// group: it must be in the group unless cross-group reviews are allowed
+func (s *Service) checkGroupWord(ctx context.Context, groupID, wordID int64) error {
+	if s.crossGroup {
+		return nil
+	}
+	member, err := s.db.IsWordInGroup(ctx, groupID, wordID)
+	if err != nil || member {
+		return err
+	}
+	if _, err := s.GetWord(ctx, wordID); err != nil {
+		return err
+	}
+	return ErrWordNotInGroup
+}
+
+// GetDueWords returns the words in a group that the acting user is due to
+// review, including words they have not studied yet
+func (s *Service) GetDueWords(ctx context.Context, groupID int64, page models.PageRequest) (*models.PaginatedResponse, error) {
+	user, err := actingUser(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := s.GetGroup(ctx, groupID); err != nil {
+		return nil, err
+	}
+
+	if _, err := s.checkPage(&page, false); err != nil {
+		return nil, err
+	}
+	words, pagination, err := s.db.GetDueWords(ctx, user.ID, groupID, s.now(), true, page.Page, page.PerPage)
+	if err != nil {
+		return nil, err
+	}
+
+	return &models.PaginatedResponse{
+		Items:      words,
+		Pagination: pagination,
+	}, nil
+}
+
+// GetReviewQueue returns the words the acting user has studied in any group
+// that are due for review
+func (s *Service) GetReviewQueue(ctx context.Context, page models.PageRequest) (*models.PaginatedResponse, error) {
+	user, err := actingUser(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := s.checkPage(&page, false); err != nil {
+		return nil, err
+	}
+	words, pagination, err := s.db.GetDueWords(ctx, user.ID, 0, s.now(), false, page.Page, page.PerPage)
 	if err != nil {
 		return nil, err
 	}
 
 	return &models.PaginatedResponse{
-		Items:      sessions,
-		Pagination: *pagination,
+		Items:      words,
+		Pagination: pagination,
 	}, nil
 }
 
-func (s *Service) GetWordsByGroup(groupID int64, page int) (*models.PaginatedResponse, error) {
-	perPage := 100
-	words, pagination, err := s.db.GetWordsByGroup(groupID, page, perPage)
+func (s *Service) GetQuickStats(ctx context.Context) (*models.QuickStats, error) {
+	user, err := actingUser(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return s.db.GetQuickStats(ctx, user.ID)
+}
+
+// ResetHistory deletes the study history of a user, or of every user when
+// userID is 0
+func (s *Service) ResetHistory(ctx context.Context, userID int64) error {
+	if userID != 0 {
+		if _, err := s.GetUser(ctx, userID); err != nil {
+			return err
+		}
+	}
+	return s.db.ResetHistory(ctx, userID)
+}
+
+func (s *Service) FullReset(ctx context.Context) error {
+	return s.db.FullReset(ctx)
+}
+
+func (s *Service) GetLastStudySession(ctx context.Context) (*models.StudySession, error) {
+	user, err := actingUser(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return s.db.GetLastStudySession(ctx, user.ID)
+}
+
+func (s *Service) GetStudyProgress(ctx context.Context) (*models.StudyProgress, error) {
+	user, err := actingUser(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return s.db.GetStudyProgress(ctx, user.ID)
+}
+
+// GetStudySessions lists the acting user's study sessions by page number, or
+// after a cursor when the listing is ordered by creation time
+func (s *Service) GetStudySessions(ctx context.Context, filter models.StudySessionFilter, page models.PageRequest) (*models.PaginatedResponse, error) {
+	user, err := actingUser(ctx)
+	if err != nil {
+		return nil, err
+	}
+	filter.UserID = user.ID
+	if err := models.ValidateSort(models.StudySessionSortColumns, filter.SortBy, filter.Order); err != nil {
+		return nil, err
+	}
+	if page.Cursor != "" && !sortsByCreation(filter) {
+		return nil, models.ValidationErrors{{Field: "cursor", Message: "cannot be combined with sort_by " + filter.SortBy}}
+	}
+
+	return s.studySessionPage(ctx, filter, page, func(page models.PageRequest) ([]*models.StudySession, *models.Pagination, error) {
+		return s.db.GetStudySessions(ctx, filter, page.Page, page.PerPage)
+	})
+}
+
+func (s *Service) GetStudySessionsByActivity(ctx context.Context, activityID int64, page models.PageRequest) (*models.PaginatedResponse, error) {
+	user, err := actingUser(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	filter := models.StudySessionFilter{UserID: user.ID, StudyActivityID: activityID}
+	return s.studySessionPage(ctx, filter, page, func(page models.PageRequest) ([]*models.StudySession, *models.Pagination, error) {
+		return s.db.GetStudySessionsByActivity(ctx, user.ID, activityID, page.Page, page.PerPage)
+	})
+}
+
+func (s *Service) GetStudySessionsByGroup(ctx context.Context, groupID int64, page models.PageRequest) (*models.PaginatedResponse, error) {
+	user, err := actingUser(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := s.GetGroup(ctx, groupID); err != nil {
+		return nil, err
+	}
+
+	filter := models.StudySessionFilter{UserID: user.ID, GroupID: groupID}
+	return s.studySessionPage(ctx, filter, page, func(page models.PageRequest) ([]*models.StudySession, *models.Pagination, error) {
+		return s.db.GetStudySessionsByGroup(ctx, user.ID, groupID, page.Page, page.PerPage)
+	})
+}
+
+// sortsByCreation reports whether a study session listing is ordered by
+// creation time, the only order cursors follow
+func sortsByCreation(filter models.StudySessionFilter) bool {
+	return filter.SortBy == "" || filter.SortBy == "created_at"
+}
+
+// studySessionPage reads the study sessions matching filter that come after
+// page.Cursor, or the numbered page byNumber reads when there is no cursor.
+// Either way the response carries the cursor of the next page if there is
+// one, so clients can switch from numbered pages to cursors.
+func (s *Service) studySessionPage(ctx context.Context, filter models.StudySessionFilter, page models.PageRequest,
+	byNumber func(models.PageRequest) ([]*models.StudySession, *models.Pagination, error)) (*models.PaginatedResponse, error) {
+	after, err := s.checkPage(&page, true)
+	if err != nil {
+		return nil, err
+	}
+
+	if page.Cursor == "" {
+		sessions, pagination, err := byNumber(page)
+		if err != nil {
+			return nil, err
+		}
+		response := &models.PaginatedResponse{Items: sessions, Pagination: pagination}
+		if len(sessions) > 0 && pagination.CurrentPage < pagination.TotalPages && sortsByCreation(filter) {
+			last := sessions[len(sessions)-1]
+			response.NextCursor = models.CursorAfter(last.CreatedAt, last.ID).String()
+		}
+		return response, nil
+	}
+
+	// one more than a page tells whether there is a next page
+	sessions, err := s.db.GetStudySessionsAfter(ctx, filter, after, page.PerPage+1)
+	if err != nil {
+		return nil, err
+	}
+	response := &models.PaginatedResponse{Items: sessions}
+	if len(sessions) > page.PerPage {
+		sessions = sessions[:page.PerPage]
+		last := sessions[len(sessions)-1]
+		response.Items = sessions
+		response.NextCursor = models.CursorAfter(last.CreatedAt, last.ID).String()
+	}
+	return response, nil
+}
+
+// GetReviews lists the acting user's reviews in the study sessions matching
+// filter, newest first unless filter.Order is "asc". The history can be long,
+// so it is read with cursors only.
+func (s *Service) GetReviews(ctx context.Context, filter models.StudySessionFilter, page models.PageRequest) (*models.PaginatedResponse, error) {
+	user, err := actingUser(ctx)
+	if err != nil {
+		return nil, err
+	}
+	filter.UserID = user.ID
+	if err := models.ValidateSort(nil, "", filter.Order); err != nil {
+		return nil, err
+	}
+	if page.Page > 1 {
+		return nil, models.ValidationErrors{{Field: "page", Message: "is not supported by this listing, follow next_cursor instead"}}
+	}
+	after, err := s.checkPage(&page, true)
+	if err != nil {
+		return nil, err
+	}
+
+	// one more than a page tells whether there is a next page
+	reviews, err := s.db.GetReviewsAfter(ctx, filter, after, page.PerPage+1)
+	if err != nil {
+		return nil, err
+	}
+	response := &models.PaginatedResponse{Items: reviews}
+	if len(reviews) > page.PerPage {
+		reviews = reviews[:page.PerPage]
+		last := reviews[len(reviews)-1]
+		response.Items = reviews
+		response.NextCursor = models.CursorAfter(last.CreatedAt, last.ID).String()
+	}
+	return response, nil
+}
+
+// GetWordsByGroup lists a group's words with the acting user's review stats
+func (s *Service) GetWordsByGroup(ctx context.Context, groupID int64, filter models.WordFilter, page models.PageRequest) (*models.PaginatedResponse, error) {
+	user, err := actingUser(ctx)
+	if err != nil {
+		return nil, err
+	}
+	filter.UserID = user.ID
+	if err := models.ValidateSort(models.WordSortColumns, filter.SortBy, filter.Order); err != nil {
+		return nil, err
+	}
+
+	if _, err := s.checkPage(&page, false); err != nil {
+		return nil, err
+	}
+	words, pagination, err := s.db.GetWordsByGroup(ctx, groupID, filter, page.Page, page.PerPage)
 	if err != nil {
 		return nil, err
 	}
 
 	return &models.PaginatedResponse{
 		Items:      words,
-		Pagination: *pagination,
+		Pagination: pagination,
 	}, nil
 }
 
-func (s *Service) GetWordsByStudySession(sessionID int64, page int) (*models.PaginatedResponse, error) {
-	perPage := 100
-	words, pagination, err := s.db.GetWordsByStudySession(sessionID, page, perPage)
+func (s *Service) GetWordsByStudySession(ctx context.Context, sessionID int64, page models.PageRequest) (*models.PaginatedResponse, error) {
+	session, err := s.GetStudySession(ctx, sessionID)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := s.checkPage(&page, false); err != nil {
+		return nil, err
+	}
+	words, pagination, err := s.db.GetWordsByStudySession(ctx, session.UserID, sessionID, page.Page, page.PerPage)
 	if err != nil {
 		return nil, err
 	}
 
 	return &models.PaginatedResponse{
 		Items:      words,
-		Pagination: *pagination,
+		Pagination: pagination,
 	}, nil
 }
@@ -0,0 +1,70 @@
+package service
+
+import (
+	"math"
+	"time"
+
+	"github.com/gen-ai-bootcamp-2025/backend_go/internal/models"
+)
+
+// SM-2 parameters. See https://super-memory.com/english/ol/sm2.htm
+const (
+	initialEaseFactor = 2.5
+	minEaseFactor     = 1.3
+)
+
+// gradeQuality maps a review grade onto the 0-5 SM-2 quality scale.
+// Anything below 3 counts as a lapse.
+var gradeQuality = map[models.Grade]int{
+	models.GradeAgain: 1,
+	models.GradeHard:  3,
+	models.GradeGood:  4,
+	models.GradeEasy:  5,
+}
+
+// NewWordProgress returns the scheduling state of a word a user has never reviewed
+func NewWordProgress(userID, wordID int64, now time.Time) *models.WordProgress {
+	return &models.WordProgress{
+		UserID:     userID,
+		WordID:     wordID,
+		EaseFactor: initialEaseFactor,
+		DueAt:      now,
+	}
+}
+
+// Schedule applies one SM-2 review to progress and returns the updated state.
+// progress is not modified.
+func Schedule(progress *models.WordProgress, grade models.Grade, now time.Time) *models.WordProgress {
+	next := *progress
+	q, ok := gradeQuality[grade]
+	if !ok {
+		q = gradeQuality[models.GradeAgain]
+	}
+
+	if q < 3 {
+		next.Repetitions = 0
+		next.IntervalDays = 1
+		next.Lapses++
+	} else {
+		switch next.Repetitions {
+		case 0:
+			next.IntervalDays = 1
+		case 1:
+			next.IntervalDays = 6
+		default:
+			next.IntervalDays = int(math.Round(float64(next.IntervalDays) * next.EaseFactor))
+		}
+		next.Repetitions++
+	}
+
+	d := float64(5 - q)
+	next.EaseFactor += 0.1 - d*(0.08+d*0.02)
+	if next.EaseFactor < minEaseFactor {
+		next.EaseFactor = minEaseFactor
+	}
+
+	reviewedAt := now
+	next.LastReviewedAt = &reviewedAt
+	next.DueAt = now.AddDate(0, 0, next.IntervalDays)
+	return &next
+}
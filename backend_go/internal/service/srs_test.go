@@ -0,0 +1,48 @@
+package service
+
+import (
+	"testing"
+	"time"
+
+	"github.com/gen-ai-bootcamp-2025/backend_go/internal/models"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestScheduleSuccessfulReviewsGrowInterval(t *testing.T) {
+	now := time.Date(2025, 1, 1, 9, 0, 0, 0, time.UTC)
+	progress := NewWordProgress(1, 1, now)
+
+	progress = Schedule(progress, models.GradeGood, now)
+	assert.Equal(t, 1, progress.IntervalDays)
+	assert.Equal(t, 1, progress.Repetitions)
+	assert.Equal(t, now.AddDate(0, 0, 1), progress.DueAt)
+
+	progress = Schedule(progress, models.GradeGood, now)
+	assert.Equal(t, 6, progress.IntervalDays)
+
+	progress = Schedule(progress, models.GradeGood, now)
+	assert.Equal(t, 15, progress.IntervalDays)
+	assert.Equal(t, 3, progress.Repetitions)
+	assert.InDelta(t, 2.5, progress.EaseFactor, 0.0001)
+}
+
+func TestScheduleLapseResetsRepetitions(t *testing.T) {
+	now := time.Date(2025, 1, 1, 9, 0, 0, 0, time.UTC)
+	progress := &models.WordProgress{WordID: 1, EaseFactor: 2.5, IntervalDays: 15, Repetitions: 3, DueAt: now}
+
+	next := Schedule(progress, models.GradeAgain, now)
+	assert.Equal(t, 0, next.Repetitions)
+	assert.Equal(t, 1, next.IntervalDays)
+	assert.Equal(t, 1, next.Lapses)
+	assert.InDelta(t, 1.96, next.EaseFactor, 0.0001)
+	assert.Equal(t, 15, progress.IntervalDays, "input must not be modified")
+}
+
+func TestScheduleEaseFactorFloor(t *testing.T) {
+	now := time.Date(2025, 1, 1, 9, 0, 0, 0, time.UTC)
+	progress := NewWordProgress(1, 1, now)
+	for i := 0; i < 10; i++ {
+		progress = Schedule(progress, models.GradeAgain, now)
+	}
+	assert.Equal(t, minEaseFactor, progress.EaseFactor)
+}
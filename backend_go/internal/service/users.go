@@ -0,0 +1,262 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/gen-ai-bootcamp-2025/backend_go/internal/auth"
+	"github.com/gen-ai-bootcamp-2025/backend_go/internal/models"
+)
+
+type contextKey int
+
+const userKey contextKey = iota
+
+// WithUser returns a copy of ctx carrying the user a request acts for
+func WithUser(ctx context.Context, user *models.User) context.Context {
+	return context.WithValue(ctx, userKey, user)
+}
+
+// UserFromContext returns the user stored by WithUser, or nil
+func UserFromContext(ctx context.Context) *models.User {
+	user, _ := ctx.Value(userKey).(*models.User)
+	return user
+}
+
+// actingUser returns the user ctx acts for. Study history is always read and
+// written on behalf of a user, so a missing user is an error.
+func actingUser(ctx context.Context) (*models.User, error) {
+	user := UserFromContext(ctx)
+	if user == nil {
+		return nil, ErrNoUser
+	}
+	return user, nil
+}
+
+func (s *Service) GetUser(ctx context.Context, id int64) (*models.User, error) {
+	user, err := s.db.GetUser(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if user == nil {
+		return nil, ErrUserNotFound
+	}
+	return user, nil
+}
+
+func (s *Service) GetUserByName(ctx context.Context, name string) (*models.User, error) {
+	user, err := s.db.GetUserByName(ctx, strings.TrimSpace(name))
+	if err != nil {
+		return nil, err
+	}
+	if user == nil {
+		return nil, ErrUserNotFound
+	}
+	return user, nil
+}
+
+func (s *Service) GetUsers(ctx context.Context, page models.PageRequest) (*models.PaginatedResponse, error) {
+	if _, err := s.checkPage(&page, false); err != nil {
+		return nil, err
+	}
+	users, pagination, err := s.db.GetUsers(ctx, page.Page, page.PerPage)
+	if err != nil {
+		return nil, err
+	}
+
+	return &models.PaginatedResponse{
+		Items:      users,
+		Pagination: pagination,
+	}, nil
+}
+
+// CreateUser adds a user with a role, learner if none is given; names are
+// unique
+func (s *Service) CreateUser(ctx context.Context, name string, role models.Role) (*models.User, error) {
+	name = strings.TrimSpace(name)
+	if role == "" {
+		role = models.RoleLearner
+	}
+
+	var errs models.ValidationErrors
+	if name == "" {
+		errs = append(errs, models.ValidationError{Field: "name", Message: "is required"})
+	}
+	if !role.Valid() {
+		errs = append(errs, models.ValidationError{Field: "role", Message: "must be one of learner, teacher, admin"})
+	}
+	if len(errs) > 0 {
+		return nil, errs
+	}
+
+	existing, err := s.db.GetUserByName(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+	if existing != nil {
+		return nil, ErrUserExists
+	}
+	user, err := s.db.CreateUser(ctx, name, role)
+	if errors.Is(err, models.ErrDuplicate) {
+		// created since the check above
+		return nil, ErrUserExists
+	}
+	return user, err
+}
+
+// UserPatch holds the changes to a user; nil fields are left unchanged and
+// an empty password removes it. CurrentPassword is only read when users
+// change their own password.
+type UserPatch struct {
+	Role            *models.Role
+	Password        *string
+	CurrentPassword *string
+}
+
+// UpdateUser changes a user's role or password. Admins may change any user;
+// everyone else may only change their own password. Changing your own
+// password takes the current one, so an API key alone cannot take over a
+// password sign-in, and only admins may set a password for a user without
+// one. Session tokens issued before a password change stop working.
+func (s *Service) UpdateUser(ctx context.Context, id int64, patch UserPatch) (*models.User, error) {
+	acting, err := actingUser(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if !acting.Role.Allows(models.RoleAdmin) && (acting.ID != id || patch.Role != nil) {
+		return nil, ErrForbidden
+	}
+
+	user, err := s.GetUser(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if patch.Role != nil {
+		if !patch.Role.Valid() {
+			return nil, models.ValidationErrors{{Field: "role", Message: "must be one of learner, teacher, admin"}}
+		}
+		user.Role = *patch.Role
+	}
+	if patch.Password != nil {
+		if acting.ID == id {
+			if err := checkCurrentPassword(acting, user, patch.CurrentPassword); err != nil {
+				return nil, err
+			}
+		}
+		now := s.now()
+		user.PasswordHash, user.PasswordChangedAt = "", &now
+		if *patch.Password != "" {
+			if len(*patch.Password) < auth.MinPasswordLength {
+				return nil, models.ValidationErrors{{Field: "password", Message: fmt.Sprintf("must be at least %d characters", auth.MinPasswordLength)}}
+			}
+			if user.PasswordHash, err = auth.HashPassword(*patch.Password); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return s.db.UpdateUser(ctx, user)
+}
+
+// checkCurrentPassword checks the current password users give to change
+// their own
+func checkCurrentPassword(acting, user *models.User, current *string) error {
+	if user.PasswordHash == "" {
+		if acting.Role.Allows(models.RoleAdmin) {
+			return nil
+		}
+		return ErrForbidden
+	}
+	if current == nil || *current == "" {
+		return models.ValidationErrors{{Field: "current_password", Message: "is required"}}
+	}
+	if !auth.CheckPassword(user.PasswordHash, *current) {
+		return ErrWrongPassword
+	}
+	return nil
+}
+
+// Login returns the user with a name and password. A wrong name and a wrong
+// password give the same error.
+func (s *Service) Login(ctx context.Context, name, password string) (*models.User, error) {
+	user, err := s.db.GetUserByName(ctx, strings.TrimSpace(name))
+	if err != nil {
+		return nil, err
+	}
+	if user == nil || !auth.CheckPassword(user.PasswordHash, password) {
+		return nil, ErrInvalidCredentials
+	}
+	return user, nil
+}
+
+// AuthenticateAPIKey returns the user an API key acts for
+func (s *Service) AuthenticateAPIKey(ctx context.Context, key string) (*models.User, error) {
+	user, err := s.db.GetUserByAPIKey(ctx, auth.HashAPIKey(key))
+	if err != nil {
+		return nil, err
+	}
+	if user == nil {
+		return nil, ErrInvalidCredentials
+	}
+	return user, nil
+}
+
+// AuthenticateToken returns the user a verified session token was issued
+// to. Tokens issued before the user's password last changed are rejected.
+func (s *Service) AuthenticateToken(ctx context.Context, claims auth.Claims) (*models.User, error) {
+	userID, err := claims.UserID()
+	if err != nil {
+		return nil, err
+	}
+	user, err := s.GetUser(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if user.PasswordChangedAt != nil && claims.IssuedAt < user.PasswordChangedAt.Unix() {
+		return nil, auth.ErrInvalidToken
+	}
+	return user, nil
+}
+
+func (s *Service) GetAPIKeys(ctx context.Context, userID int64) ([]*models.APIKey, error) {
+	if _, err := s.GetUser(ctx, userID); err != nil {
+		return nil, err
+	}
+	return s.db.GetAPIKeys(ctx, userID)
+}
+
+// CreateAPIKey makes a new API key for a learning app to act for a user. The
+// returned key is the only time it is available.
+func (s *Service) CreateAPIKey(ctx context.Context, userID int64, name string) (*models.APIKey, error) {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return nil, models.ValidationErrors{{Field: "name", Message: "is required"}}
+	}
+	if _, err := s.GetUser(ctx, userID); err != nil {
+		return nil, err
+	}
+
+	key, hash := auth.NewAPIKey()
+	apiKey, err := s.db.CreateAPIKey(ctx, userID, name, hash)
+	if err != nil {
+		return nil, err
+	}
+	apiKey.Key = key
+	return apiKey, nil
+}
+
+// DeleteAPIKey revokes one of a user's API keys
+func (s *Service) DeleteAPIKey(ctx context.Context, userID, id int64) error {
+	keys, err := s.GetAPIKeys(ctx, userID)
+	if err != nil {
+		return err
+	}
+	for _, key := range keys {
+		if key.ID == id {
+			return s.db.DeleteAPIKey(ctx, userID, id)
+		}
+	}
+	return ErrAPIKeyNotFound
+}
@@ -0,0 +1,52 @@
+package service
+
+import (
+	"context"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/gen-ai-bootcamp-2025/backend_go/internal/auth"
+	"github.com/gen-ai-bootcamp-2025/backend_go/internal/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestChangePasswordRevokesOlderTokens(t *testing.T) {
+	db := openTestDB(t)
+	svc := NewService(db)
+	ctx := context.Background()
+	now := time.Date(2025, 3, 1, 12, 0, 0, 0, time.UTC)
+	svc.now = func() time.Time { return now }
+
+	user, err := db.CreateUser(ctx, "hana", models.RoleLearner)
+	require.NoError(t, err)
+	user.PasswordHash, err = auth.HashPassword("correct horse")
+	require.NoError(t, err)
+	_, err = db.UpdateUser(ctx, user)
+	require.NoError(t, err)
+
+	tokenAt := func(issued time.Time) auth.Claims {
+		return auth.Claims{Subject: strconv.FormatInt(user.ID, 10), IssuedAt: issued.Unix()}
+	}
+	_, err = svc.AuthenticateToken(ctx, tokenAt(now.Add(-time.Hour)))
+	assert.NoError(t, err, "no password change yet")
+
+	password := func(s string) *string { return &s }
+	ctx = WithUser(ctx, user)
+	_, err = svc.UpdateUser(ctx, user.ID, UserPatch{Password: password("new password")})
+	assert.ErrorIs(t, err, models.ErrValidation)
+	_, err = svc.UpdateUser(ctx, user.ID, UserPatch{Password: password("new password"), CurrentPassword: password("incorrect")})
+	assert.ErrorIs(t, err, ErrWrongPassword)
+	_, err = svc.UpdateUser(ctx, user.ID, UserPatch{Password: password("new password"), CurrentPassword: password("correct horse")})
+	require.NoError(t, err)
+
+	_, err = svc.AuthenticateToken(ctx, tokenAt(now.Add(-time.Hour)))
+	assert.ErrorIs(t, err, auth.ErrInvalidToken)
+	signedIn, err := svc.AuthenticateToken(ctx, tokenAt(now.Add(time.Second)))
+	require.NoError(t, err)
+	assert.Equal(t, user.ID, signedIn.ID)
+
+	_, err = svc.Login(ctx, "hana", "new password")
+	assert.NoError(t, err)
+}
@@ -0,0 +1,398 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/gen-ai-bootcamp-2025/backend_go/internal/models"
+	"github.com/gen-ai-bootcamp-2025/backend_go/internal/xapi"
+)
+
+// StatementQuery selects the statements returned by GetStatements. Limit is
+// capped at the service's largest page size. Cursor is the Next value of the
+// previous page.
+type StatementQuery struct {
+	Since     time.Time
+	Until     time.Time
+	Limit     int
+	Ascending bool
+	Cursor    int64
+}
+
+// StatementPage is a page of statements; Next is the cursor of the following
+// page, or 0 on the last page
+type StatementPage struct {
+	Statements []*xapi.Statement
+	Next       int64
+}
+
+// sessionRef says which study session a statement belongs to: an existing
+// session by id, or the session of a registration, created from GroupID and
+// ActivityID the first time the registration is seen. Ended is set for an
+// existing session that is no longer active.
+type sessionRef struct {
+	ID           int64
+	Registration string
+	GroupID      int64
+	ActivityID   int64
+	Ended        bool
+}
+
+// key identifies the session within a batch of statements
+func (r sessionRef) key() string {
+	if r.ID != 0 {
+		return fmt.Sprint(r.ID)
+	}
+	return "registration " + r.Registration
+}
+
+// plannedStatement is a checked statement ready to be recorded, or one
+// that was recorded before and is skipped
+type plannedStatement struct {
+	id       string
+	recorded bool
+	session  sessionRef
+	review   *models.WordReviewItem
+}
+
+// RecordStatements records "answered" statements about words as reviews and
+// "completed" statements as the end of study sessions, and returns the statement ids,
+// generating any that are missing. Every statement is checked before any is
+// recorded, and the batch is recorded as a whole or not at all. A statement
+// whose id was already recorded is skipped, so apps can safely send a batch
+// again.
+func (s *Service) RecordStatements(ctx context.Context, statements []*xapi.Statement) ([]string, error) {
+	user, err := actingUser(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if len(statements) == 0 {
+		return nil, models.ValidationErrors{{Field: "statements", Message: "at least one statement is required"}}
+	}
+
+	// Statements recorded before are skipped before they are checked, as
+	// their session may have ended since
+	var statementIDs []string
+	for _, st := range statements {
+		if st != nil && st.ID != "" {
+			statementIDs = append(statementIDs, st.ID)
+		}
+	}
+	recordedIDs, err := s.db.GetRecordedStatementIDs(ctx, user.ID, statementIDs)
+	if err != nil {
+		return nil, err
+	}
+	recorded := map[string]bool{}
+	for _, id := range recordedIDs {
+		recorded[id] = true
+	}
+
+	planned := make([]plannedStatement, len(statements))
+	completed := map[string]bool{}
+	var errs models.ValidationErrors
+	for i, st := range statements {
+		field := fmt.Sprintf("statements[%d]", i)
+		if st == nil {
+			errs = append(errs, models.ValidationError{Field: field, Message: "must be an object"})
+			continue
+		}
+		if recorded[st.ID] {
+			planned[i] = plannedStatement{id: st.ID, recorded: true}
+			continue
+		}
+		plan, err := s.planStatement(ctx, st)
+		if err != nil {
+			var fieldErrs models.ValidationErrors
+			if !errors.As(err, &fieldErrs) {
+				return nil, err
+			}
+			for _, fe := range fieldErrs {
+				errs = append(errs, models.ValidationError{Field: field + "." + fe.Field, Message: fe.Message})
+			}
+			continue
+		}
+		if plan.review == nil {
+			completed[plan.session.key()] = true
+		} else if completed[plan.session.key()] {
+			errs = append(errs, models.ValidationError{Field: field + ".context", Message: "study session is completed by an earlier statement"})
+			continue
+		}
+		planned[i] = plan
+	}
+	if len(errs) > 0 {
+		return nil, errs
+	}
+
+	ids := make([]string, len(planned))
+	var records []*models.StatementRecord
+	now := s.now()
+	for i, plan := range planned {
+		if plan.id == "" {
+			plan.id = xapi.NewUUID()
+		}
+		ids[i] = plan.id
+		if plan.recorded {
+			continue
+		}
+		if plan.review != nil {
+			plan.review.StatementID = plan.id
+		}
+		records = append(records, &models.StatementRecord{
+			SessionID:    plan.session.ID,
+			Registration: plan.session.Registration,
+			GroupID:      plan.session.GroupID,
+			ActivityID:   plan.session.ActivityID,
+			Review:       plan.review,
+			EndedAt:      now,
+		})
+	}
+	err = s.db.RecordStatements(ctx, user.ID, records, s.schedule)
+	if errors.Is(err, models.ErrSessionNotActive) {
+		// a session ended since the statements were checked
+		return nil, ErrStudySessionEnded
+	}
+	if errors.Is(err, models.ErrDuplicate) {
+		// the user's own recorded statements were skipped above
+		return nil, ErrStatementIDTaken
+	}
+	if err != nil {
+		return nil, err
+	}
+	return ids, nil
+}
+
+// planStatement checks a statement and works out what recording it means
+func (s *Service) planStatement(ctx context.Context, st *xapi.Statement) (plannedStatement, error) {
+	if err := st.Validate(); err != nil {
+		return plannedStatement{}, models.ValidationErrors{{Field: "statement", Message: err.Error()}}
+	}
+	plan := plannedStatement{id: st.ID}
+
+	kind, objectID, _ := xapi.ParseActivityIRI(s.base, st.Object.ID)
+	switch st.Verb.ID {
+	case xapi.VerbAnswered:
+		if kind != xapi.KindWord {
+			return plan, models.ValidationErrors{{Field: "object.id", Message: fmt.Sprintf("must be a word activity IRI, %s/words/{id}", s.base)}}
+		}
+		if _, err := s.GetWord(ctx, objectID); err != nil {
+			if errors.Is(err, ErrWordNotFound) {
+				return plan, models.ValidationErrors{{Field: "object.id", Message: fmt.Sprintf("word %d not found", objectID)}}
+			}
+			return plan, err
+		}
+		review, err := statementReview(st)
+		if err != nil {
+			return plan, err
+		}
+		review.WordID = objectID
+		plan.review = review
+
+	case xapi.VerbCompleted:
+		switch kind {
+		case xapi.KindStudySession:
+			plan.session.ID = objectID
+		case "":
+			return plan, models.ValidationErrors{{Field: "object.id", Message: fmt.Sprintf("must be an activity IRI under %s", s.base)}}
+		}
+
+	default:
+		return plan, models.ValidationErrors{{Field: "verb.id", Message: fmt.Sprintf("must be %s or %s", xapi.VerbAnswered, xapi.VerbCompleted)}}
+	}
+
+	session, err := s.planSession(ctx, st.Context, plan.session.ID)
+	if err != nil {
+		return plan, err
+	}
+	plan.session = session
+
+	if plan.review != nil {
+		if session.Ended {
+			return plan, models.ValidationErrors{{Field: "context", Message: fmt.Sprintf("study session %d has ended", session.ID)}}
+		}
+		if err := s.checkGroupWord(ctx, session.GroupID, objectID); err != nil {
+			if errors.Is(err, ErrWordNotInGroup) {
+				return plan, models.ValidationErrors{{Field: "object.id", Message: fmt.Sprintf("word %d is not in group %d", objectID, session.GroupID)}}
+			}
+			return plan, err
+		}
+	}
+	return plan, nil
+}
+
+// statementReview reads the review details from a statement's result. The
+// grade comes from the grade extension, or from success when there is none.
+func statementReview(st *xapi.Statement) (*models.WordReviewItem, error) {
+	review := &models.WordReviewItem{}
+	result := st.Result
+	if result == nil {
+		return nil, models.ValidationErrors{{Field: "result", Message: "is required for answered statements"}}
+	}
+
+	var errs models.ValidationErrors
+	if grade, ok := result.Extensions[xapi.ExtensionGrade]; ok {
+		g, _ := grade.(string)
+		review.Grade = models.Grade(g)
+		if !review.Grade.Valid() {
+			errs = append(errs, models.ValidationError{Field: "result.extensions", Message: "grade must be one of again, hard, good, easy"})
+		}
+	} else if result.Success != nil {
+		review.Grade = models.GradeAgain
+		if *result.Success {
+			review.Grade = models.GradeGood
+		}
+	} else {
+		errs = append(errs, models.ValidationError{Field: "result.success", Message: "is required when no grade extension is given"})
+	}
+
+	if direction, ok := result.Extensions[xapi.ExtensionDirection]; ok {
+		d, _ := direction.(string)
+		review.Direction = models.Direction(d)
+		if !review.Direction.Valid() {
+			errs = append(errs, models.ValidationError{Field: "result.extensions", Message: "direction must be one of jp_en, en_jp, kana_kanji"})
+		}
+	}
+
+	if result.Duration != "" {
+		d, err := xapi.ParseDuration(result.Duration)
+		if err != nil {
+			errs = append(errs, models.ValidationError{Field: "result.duration", Message: err.Error()})
+		} else {
+			ms := d.Milliseconds()
+			review.ResponseTimeMs = &ms
+		}
+	}
+	review.Answer = result.Response
+
+	if len(errs) > 0 {
+		return nil, errs
+	}
+	return review, nil
+}
+
+// planSession finds the acting user's study session of a statement:
+// sessionID if the statement names one, else a study session context
+// activity, else the context registration
+func (s *Service) planSession(ctx context.Context, stCtx *xapi.Context, sessionID int64) (sessionRef, error) {
+	if sessionID == 0 {
+		sessionID = stCtx.Find(s.base, xapi.KindStudySession)
+	}
+	if sessionID != 0 {
+		session, err := s.GetStudySession(ctx, sessionID)
+		if err != nil {
+			if errors.Is(err, ErrStudySessionNotFound) {
+				return sessionRef{}, models.ValidationErrors{{Field: "context", Message: fmt.Sprintf("study session %d not found", sessionID)}}
+			}
+			return sessionRef{}, err
+		}
+		return sessionRef{ID: sessionID, GroupID: session.GroupID, Ended: session.Status != models.StudySessionActive}, nil
+	}
+
+	if stCtx == nil || stCtx.Registration == "" {
+		return sessionRef{}, models.ValidationErrors{{Field: "context", Message: "must have a registration or a study session activity"}}
+	}
+	ref := sessionRef{Registration: stCtx.Registration}
+	session, err := s.db.GetStudySessionByRegistration(ctx, stCtx.Registration)
+	if err != nil {
+		return ref, err
+	}
+	if session != nil {
+		user, err := actingUser(ctx)
+		if err != nil {
+			return ref, err
+		}
+		if session.UserID != user.ID {
+			return ref, models.ValidationErrors{{Field: "context.registration", Message: "belongs to another user"}}
+		}
+		ref.ID = session.ID
+		ref.GroupID = session.GroupID
+		ref.Ended = session.Status != models.StudySessionActive
+		return ref, nil
+	}
+
+	// The registration is new, so the context must say what is being studied
+	ref.GroupID = stCtx.Find(s.base, xapi.KindGroup)
+	ref.ActivityID = stCtx.Find(s.base, xapi.KindStudyActivity)
+	if ref.GroupID == 0 || ref.ActivityID == 0 {
+		return ref, models.ValidationErrors{{Field: "context.contextActivities", Message: "must include a group and a study activity to start a session"}}
+	}
+	if _, err := s.GetGroup(ctx, ref.GroupID); err != nil {
+		if errors.Is(err, ErrGroupNotFound) {
+			return ref, models.ValidationErrors{{Field: "context.contextActivities", Message: fmt.Sprintf("group %d not found", ref.GroupID)}}
+		}
+		return ref, err
+	}
+	activity, err := s.GetStudyActivity(ctx, ref.ActivityID)
+	switch {
+	case errors.Is(err, ErrStudyActivityNotFound):
+		return ref, models.ValidationErrors{{Field: "context.contextActivities", Message: fmt.Sprintf("study activity %d not found", ref.ActivityID)}}
+	case err != nil:
+		return ref, err
+	case !activity.Enabled:
+		return ref, models.ValidationErrors{{Field: "context.contextActivities", Message: ErrStudyActivityDisabled.Error()}}
+	}
+	return ref, nil
+}
+
+// GetStatements returns the acting user's reviews as "answered" statements
+func (s *Service) GetStatements(ctx context.Context, query StatementQuery) (*StatementPage, error) {
+	user, err := actingUser(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if query.Limit <= 0 || query.Limit > s.maxPerPage {
+		query.Limit = s.maxPerPage
+	}
+	filter := models.ReviewStatementFilter{
+		UserID:    user.ID,
+		Since:     query.Since,
+		Until:     query.Until,
+		AfterID:   query.Cursor,
+		Ascending: query.Ascending,
+		Limit:     query.Limit + 1,
+	}
+	reviews, err := s.db.GetReviewStatements(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+
+	page := &StatementPage{Statements: []*xapi.Statement{}}
+	if len(reviews) > query.Limit {
+		reviews = reviews[:query.Limit]
+		page.Next = reviews[len(reviews)-1].ID
+	}
+	for _, review := range reviews {
+		page.Statements = append(page.Statements, xapi.FromReview(review, s.base, xapi.Learner(s.base, user.Name)))
+	}
+	return page, nil
+}
+
+// GetStatement returns the acting user's statement with the given id, which
+// is either the id a review was reported under or the id given to a review
+// made through the review endpoint
+func (s *Service) GetStatement(ctx context.Context, id string) (*xapi.Statement, error) {
+	user, err := actingUser(ctx)
+	if err != nil {
+		return nil, err
+	}
+	filter := models.ReviewStatementFilter{UserID: user.ID, StatementID: id, Limit: 1}
+	reviews, err := s.db.GetReviewStatements(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+	if len(reviews) == 0 {
+		reviewID, ok := xapi.ParseReviewStatementID(id)
+		if !ok {
+			return nil, ErrStatementNotFound
+		}
+		filter = models.ReviewStatementFilter{UserID: user.ID, ReviewID: reviewID, Limit: 1}
+		if reviews, err = s.db.GetReviewStatements(ctx, filter); err != nil {
+			return nil, err
+		}
+		if len(reviews) == 0 || reviews[0].StatementID != "" {
+			return nil, ErrStatementNotFound
+		}
+	}
+	return xapi.FromReview(reviews[0], s.base, xapi.Learner(s.base, user.Name)), nil
+}
@@ -0,0 +1,27 @@
+// Package testdb opens databases for tests that need the real schema.
+package testdb
+
+import (
+	"database/sql"
+	"path/filepath"
+	"testing"
+
+	"github.com/gen-ai-bootcamp-2025/backend_go/db/migrations"
+	_ "github.com/mattn/go-sqlite3"
+	"github.com/stretchr/testify/require"
+)
+
+// Open opens a new database file at the current schema, the way the server
+// does, and closes it when the test ends. dsn turns the file's path into the
+// data source name; it is models.DSN, taken as an argument so the tests of
+// package models can use Open too.
+func Open(t testing.TB, dsn func(path string) string) *sql.DB {
+	db, err := sql.Open("sqlite3", dsn(filepath.Join(t.TempDir(), "words.db")))
+	require.NoError(t, err)
+	t.Cleanup(func() { db.Close() })
+	m, err := migrations.New(db, migrations.FS)
+	require.NoError(t, err)
+	_, err = m.Up()
+	require.NoError(t, err)
+	return db
+}
@@ -0,0 +1,101 @@
+package xapi
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gen-ai-bootcamp-2025/backend_go/internal/models"
+)
+
+// reviewStatementPrefix starts the ids given to reviews that did not arrive
+// as statements; the review id follows in the last 12 hex digits
+const reviewStatementPrefix = "00000000-0000-4000-8000-"
+
+// ReviewStatementID returns the statement id of a review: the id of the
+// statement it was reported in, or an id built from the review id
+func ReviewStatementID(review *models.ReviewExport) string {
+	if review.StatementID != "" {
+		return review.StatementID
+	}
+	return fmt.Sprintf("%s%012x", reviewStatementPrefix, review.ID)
+}
+
+// ParseReviewStatementID returns the review id inside an id made by
+// ReviewStatementID for a review that did not arrive as a statement
+func ParseReviewStatementID(id string) (int64, bool) {
+	hexID := strings.TrimPrefix(strings.ToLower(id), reviewStatementPrefix)
+	if hexID == id || len(hexID) != 12 {
+		return 0, false
+	}
+	reviewID, err := strconv.ParseInt(hexID, 16, 64)
+	if err != nil || reviewID <= 0 {
+		return 0, false
+	}
+	return reviewID, true
+}
+
+// Learner is the actor standing for a portal user in the statements the
+// portal emits
+func Learner(base, name string) *Agent {
+	return &Agent{ObjectType: "Agent", Account: &Account{HomePage: base, Name: name}}
+}
+
+// FromReview describes a review by actor as an "answered" statement whose
+// activity IRIs start with base
+func FromReview(review *models.ReviewExport, base string, actor *Agent) *Statement {
+	success := review.Correct
+	result := &Result{
+		Success:    &success,
+		Response:   review.Answer,
+		Extensions: map[string]interface{}{ExtensionGrade: string(review.Grade)},
+	}
+	if review.ResponseTimeMs != nil {
+		result.Duration = FormatDuration(time.Duration(*review.ResponseTimeMs) * time.Millisecond)
+	}
+	if review.Direction != "" {
+		result.Extensions[ExtensionDirection] = string(review.Direction)
+	}
+
+	parent := Activities{{
+		ObjectType: "Activity",
+		ID:         ActivityIRI(base, KindStudySession, review.StudySessionID),
+		Definition: &ActivityDefinition{Type: TypeAttempt},
+	}}
+	grouping := Activities{{
+		ObjectType: "Activity",
+		ID:         ActivityIRI(base, KindGroup, review.GroupID),
+		Definition: &ActivityDefinition{Name: map[string]string{"en-US": review.GroupName}, Type: TypeCourse},
+	}}
+	if review.StudyActivityID != 0 {
+		grouping = append(grouping, Activity{
+			ObjectType: "Activity",
+			ID:         ActivityIRI(base, KindStudyActivity, review.StudyActivityID),
+			Definition: &ActivityDefinition{Name: map[string]string{"en-US": review.ActivityName}},
+		})
+	}
+
+	timestamp := review.CreatedAt.UTC()
+	return &Statement{
+		ID:    ReviewStatementID(review),
+		Actor: actor,
+		Verb:  &Verb{ID: VerbAnswered, Display: map[string]string{"en-US": "answered"}},
+		Object: &Activity{
+			ObjectType: "Activity",
+			ID:         ActivityIRI(base, KindWord, review.WordID),
+			Definition: &ActivityDefinition{
+				Name: map[string]string{"ja-JP": review.Japanese, "en-US": review.English},
+				Type: TypeInteraction,
+			},
+		},
+		Result: result,
+		Context: &Context{
+			Registration:      review.Registration,
+			ContextActivities: &ContextActivities{Parent: parent, Grouping: grouping},
+		},
+		Timestamp: &timestamp,
+		Stored:    &timestamp,
+		Version:   Version,
+	}
+}
@@ -0,0 +1,281 @@
+// Package xapi implements the parts of the Experience API (xAPI, formerly Tin
+// Can) 1.0.3 statement format that the portal understands.
+//
+// A learning app reports a review as an "answered" statement whose object is
+// a word and a finished session as a "completed" statement. Activities are
+// identified by IRIs under the portal's own base, {base}/words/{id},
+// {base}/groups/{id}, {base}/study_activities/{id} or
+// {base}/study_sessions/{id}; IRIs under any other base name activities the
+// portal knows nothing about.
+package xapi
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Version is the xAPI version sent in the X-Experience-API-Version header
+const Version = "1.0.3"
+
+// Verbs the portal records
+const (
+	VerbAnswered  = "http://adlnet.gov/expapi/verbs/answered"
+	VerbCompleted = "http://adlnet.gov/expapi/verbs/completed"
+)
+
+// Result extensions carrying review details that xAPI has no field for
+const (
+	ExtensionGrade     = "urn:lang-portal:extension:grade"
+	ExtensionDirection = "urn:lang-portal:extension:direction"
+)
+
+// Activity kinds, the path segment before the id in an activity IRI
+const (
+	KindWord          = "words"
+	KindGroup         = "groups"
+	KindStudyActivity = "study_activities"
+	KindStudySession  = "study_sessions"
+)
+
+// Activity definition types of the activities the portal emits
+const (
+	TypeInteraction = "http://adlnet.gov/expapi/activities/cmi.interaction"
+	TypeCourse      = "http://adlnet.gov/expapi/activities/course"
+	TypeAttempt     = "http://adlnet.gov/expapi/activities/attempt"
+)
+
+type Statement struct {
+	ID        string     `json:"id,omitempty"`
+	Actor     *Agent     `json:"actor"`
+	Verb      *Verb      `json:"verb"`
+	Object    *Activity  `json:"object"`
+	Result    *Result    `json:"result,omitempty"`
+	Context   *Context   `json:"context,omitempty"`
+	Timestamp *time.Time `json:"timestamp,omitempty"`
+	Stored    *time.Time `json:"stored,omitempty"`
+	Version   string     `json:"version,omitempty"`
+}
+
+type Agent struct {
+	ObjectType string   `json:"objectType,omitempty"`
+	Name       string   `json:"name,omitempty"`
+	Mbox       string   `json:"mbox,omitempty"`
+	Account    *Account `json:"account,omitempty"`
+}
+
+type Account struct {
+	HomePage string `json:"homePage"`
+	Name     string `json:"name"`
+}
+
+type Verb struct {
+	ID      string            `json:"id"`
+	Display map[string]string `json:"display,omitempty"`
+}
+
+type Activity struct {
+	ObjectType string              `json:"objectType,omitempty"`
+	ID         string              `json:"id"`
+	Definition *ActivityDefinition `json:"definition,omitempty"`
+}
+
+type ActivityDefinition struct {
+	Name map[string]string `json:"name,omitempty"`
+	Type string            `json:"type,omitempty"`
+}
+
+type Result struct {
+	Success    *bool                  `json:"success,omitempty"`
+	Completion *bool                  `json:"completion,omitempty"`
+	Response   string                 `json:"response,omitempty"`
+	Duration   string                 `json:"duration,omitempty"`
+	Extensions map[string]interface{} `json:"extensions,omitempty"`
+}
+
+type Context struct {
+	Registration      string             `json:"registration,omitempty"`
+	ContextActivities *ContextActivities `json:"contextActivities,omitempty"`
+}
+
+type ContextActivities struct {
+	Parent   Activities `json:"parent,omitempty"`
+	Grouping Activities `json:"grouping,omitempty"`
+	Category Activities `json:"category,omitempty"`
+	Other    Activities `json:"other,omitempty"`
+}
+
+// Activities is a list of context activities. xAPI allows a single activity
+// in place of a list, so both are accepted.
+type Activities []Activity
+
+func (a *Activities) UnmarshalJSON(data []byte) error {
+	data = bytes.TrimSpace(data)
+	if len(data) > 0 && data[0] == '{' {
+		var one Activity
+		if err := json.Unmarshal(data, &one); err != nil {
+			return err
+		}
+		*a = Activities{one}
+		return nil
+	}
+	var list []Activity
+	if err := json.Unmarshal(data, &list); err != nil {
+		return err
+	}
+	*a = list
+	return nil
+}
+
+// StatementResult is a page of statements; More is the URL of the next page,
+// or empty on the last page
+type StatementResult struct {
+	Statements []*Statement `json:"statements"`
+	More       string       `json:"more"`
+}
+
+// ParseStatements reads a single statement or an array of statements
+func ParseStatements(data []byte) ([]*Statement, error) {
+	data = bytes.TrimSpace(data)
+	if len(data) > 0 && data[0] == '{' {
+		var one Statement
+		if err := json.Unmarshal(data, &one); err != nil {
+			return nil, err
+		}
+		return []*Statement{&one}, nil
+	}
+	var list []*Statement
+	if err := json.Unmarshal(data, &list); err != nil {
+		return nil, err
+	}
+	return list, nil
+}
+
+// ActivityIRI returns the IRI of a portal activity under base
+func ActivityIRI(base, kind string, id int64) string {
+	return strings.TrimRight(base, "/") + "/" + kind + "/" + strconv.FormatInt(id, 10)
+}
+
+// ParseActivityIRI returns the kind and id of an IRI of the form
+// {base}/{kind}/{id}
+func ParseActivityIRI(base, iri string) (kind string, id int64, ok bool) {
+	base = strings.TrimRight(base, "/")
+	if base == "" || !strings.HasPrefix(iri, base+"/") {
+		return "", 0, false
+	}
+	kind, rawID, found := strings.Cut(strings.TrimPrefix(iri, base+"/"), "/")
+	if !found {
+		return "", 0, false
+	}
+	id, err := strconv.ParseInt(rawID, 10, 64)
+	if err != nil || id <= 0 {
+		return "", 0, false
+	}
+	switch kind {
+	case KindWord, KindGroup, KindStudyActivity, KindStudySession:
+		return kind, id, true
+	}
+	return "", 0, false
+}
+
+// Find returns the id of the first context activity of kind under base, or 0
+func (c *Context) Find(base, kind string) int64 {
+	if c == nil || c.ContextActivities == nil {
+		return 0
+	}
+	ca := c.ContextActivities
+	for _, list := range []Activities{ca.Parent, ca.Grouping, ca.Category, ca.Other} {
+		for _, activity := range list {
+			if k, id, ok := ParseActivityIRI(base, activity.ID); ok && k == kind {
+				return id
+			}
+		}
+	}
+	return 0
+}
+
+// ParseDuration reads an ISO 8601 duration such as PT1.5S. Years and months
+// are rejected since their length is not fixed.
+func ParseDuration(s string) (time.Duration, error) {
+	invalid := fmt.Errorf("invalid ISO 8601 duration %q", s)
+	rest := strings.TrimPrefix(s, "P")
+	if rest == s || rest == "" {
+		return 0, invalid
+	}
+
+	var total time.Duration
+	inTime, timeParts := false, 0
+	for rest != "" {
+		if rest[0] == 'T' {
+			if inTime {
+				return 0, invalid
+			}
+			inTime = true
+			rest = rest[1:]
+			continue
+		}
+		if inTime {
+			timeParts++
+		}
+		i := strings.IndexFunc(rest, func(r rune) bool {
+			return (r < '0' || r > '9') && r != '.'
+		})
+		if i <= 0 {
+			return 0, invalid
+		}
+		value, err := strconv.ParseFloat(rest[:i], 64)
+		if err != nil {
+			return 0, invalid
+		}
+		var unit time.Duration
+		switch {
+		case !inTime && rest[i] == 'W':
+			unit = 7 * 24 * time.Hour
+		case !inTime && rest[i] == 'D':
+			unit = 24 * time.Hour
+		case inTime && rest[i] == 'H':
+			unit = time.Hour
+		case inTime && rest[i] == 'M':
+			unit = time.Minute
+		case inTime && rest[i] == 'S':
+			unit = time.Second
+		default:
+			return 0, invalid
+		}
+		total += time.Duration(value * float64(unit))
+		rest = rest[i+1:]
+	}
+	if inTime && timeParts == 0 {
+		return 0, invalid
+	}
+	return total, nil
+}
+
+// FormatDuration writes d as an ISO 8601 duration in seconds, e.g. PT1.5S
+func FormatDuration(d time.Duration) string {
+	return "PT" + strconv.FormatFloat(d.Seconds(), 'f', -1, 64) + "S"
+}
+
+// Validate checks the parts of a statement every verb needs
+func (s *Statement) Validate() error {
+	if s.ID != "" && !ValidUUID(s.ID) {
+		return errors.New("id must be a UUID")
+	}
+	if s.Actor == nil {
+		return errors.New("actor is required")
+	}
+	if s.Verb == nil || s.Verb.ID == "" {
+		return errors.New("verb.id is required")
+	}
+	if s.Object == nil || s.Object.ID == "" {
+		return errors.New("object.id is required")
+	}
+	if s.Context != nil && s.Context.Registration != "" && !ValidUUID(s.Context.Registration) {
+		return errors.New("context.registration must be a UUID")
+	}
+	return nil
+}
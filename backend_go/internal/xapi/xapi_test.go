@@ -0,0 +1,134 @@
+package xapi
+
+import (
+	"testing"
+	"time"
+
+	"github.com/gen-ai-bootcamp-2025/backend_go/internal/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseDuration(t *testing.T) {
+	tests := []struct {
+		in   string
+		want time.Duration
+	}{
+		{"PT1.5S", 1500 * time.Millisecond},
+		{"PT2M3S", 2*time.Minute + 3*time.Second},
+		{"P1DT1H", 25 * time.Hour},
+		{"P1W", 7 * 24 * time.Hour},
+	}
+	for _, tt := range tests {
+		got, err := ParseDuration(tt.in)
+		require.NoError(t, err, tt.in)
+		assert.Equal(t, tt.want, got, tt.in)
+	}
+
+	for _, in := range []string{"", "P", "1S", "PT", "P1M", "PTS", "PT1S2", "P1TT1S"} {
+		_, err := ParseDuration(in)
+		assert.Error(t, err, in)
+	}
+
+	d, err := ParseDuration(FormatDuration(1250 * time.Millisecond))
+	require.NoError(t, err)
+	assert.Equal(t, 1250*time.Millisecond, d)
+}
+
+func TestParseActivityIRI(t *testing.T) {
+	const base = "https://portal.example.com/api"
+	kind, id, ok := ParseActivityIRI(base, "https://portal.example.com/api/words/42")
+	assert.True(t, ok)
+	assert.Equal(t, KindWord, kind)
+	assert.Equal(t, int64(42), id)
+
+	kind, id, ok = ParseActivityIRI(base+"/", "https://portal.example.com/api/groups/3")
+	assert.True(t, ok)
+	assert.Equal(t, KindGroup, kind)
+	assert.Equal(t, int64(3), id)
+
+	for _, iri := range []string{
+		"words/1",
+		"https://evil.example.com/api/words/1",
+		"https://portal.example.com/words/1",
+		"https://portal.example.com/api2/words/1",
+		"https://portal.example.com/api/lessons/1",
+		"https://portal.example.com/api/words/x",
+		"https://portal.example.com/api/words/0",
+		"https://portal.example.com/api/words/1/extra",
+		"https://portal.example.com/api/other/words/1",
+	} {
+		_, _, ok := ParseActivityIRI(base, iri)
+		assert.False(t, ok, iri)
+	}
+	_, _, ok = ParseActivityIRI("", "/words/1")
+	assert.False(t, ok, "no base accepts nothing")
+
+	assert.Equal(t, "https://example.com/api/study_sessions/7", ActivityIRI("https://example.com/api/", KindStudySession, 7))
+}
+
+func TestParseStatements(t *testing.T) {
+	statements, err := ParseStatements([]byte(`{
+		"actor": {"mbox": "mailto:a@example.com"},
+		"verb": {"id": "` + VerbCompleted + `"},
+		"object": {"id": "https://example.com/api/study_sessions/1"},
+		"context": {"contextActivities": {"parent": {"id": "https://example.com/api/groups/2"}}}
+	}`))
+	require.NoError(t, err)
+	require.Len(t, statements, 1)
+	assert.NoError(t, statements[0].Validate())
+	assert.Equal(t, int64(2), statements[0].Context.Find("https://example.com/api", KindGroup))
+	assert.Zero(t, statements[0].Context.Find("https://example.com/api", KindStudyActivity))
+	assert.Zero(t, statements[0].Context.Find("https://other.example.com/api", KindGroup))
+
+	statements, err = ParseStatements([]byte(` [{"id": "nope"}, {}]`))
+	require.NoError(t, err)
+	require.Len(t, statements, 2)
+	assert.EqualError(t, statements[0].Validate(), "id must be a UUID")
+	assert.EqualError(t, statements[1].Validate(), "actor is required")
+
+	_, err = ParseStatements([]byte(`42`))
+	assert.Error(t, err)
+}
+
+func TestUUID(t *testing.T) {
+	id := NewUUID()
+	assert.True(t, ValidUUID(id))
+	assert.Equal(t, byte('4'), id[14])
+	assert.NotEqual(t, id, NewUUID())
+	assert.False(t, ValidUUID("not-a-uuid"))
+}
+
+func TestFromReview(t *testing.T) {
+	responseTime := int64(1500)
+	review := &models.ReviewExport{
+		ID: 26, StudySessionID: 3, GroupID: 1, GroupName: "Animals",
+		StudyActivityID: 2, ActivityName: "Flashcards",
+		WordID: 5, Japanese: "猫", English: "cat",
+		Grade: models.GradeHard, Correct: true, ResponseTimeMs: &responseTime,
+		Answer: "cat", Direction: models.DirectionJapaneseToEnglish,
+		CreatedAt: time.Date(2025, 1, 4, 9, 0, 0, 0, time.UTC),
+	}
+	st := FromReview(review, "https://portal.example.com/api", Learner("https://portal.example.com/api", "hana"))
+
+	assert.Equal(t, "00000000-0000-4000-8000-00000000001a", st.ID)
+	reviewID, ok := ParseReviewStatementID(st.ID)
+	assert.True(t, ok)
+	assert.Equal(t, int64(26), reviewID)
+	_, ok = ParseReviewStatementID(NewUUID())
+	assert.False(t, ok)
+
+	assert.Equal(t, "hana", st.Actor.Account.Name)
+	assert.Equal(t, VerbAnswered, st.Verb.ID)
+	assert.Equal(t, "https://portal.example.com/api/words/5", st.Object.ID)
+	assert.True(t, *st.Result.Success)
+	assert.Equal(t, "PT1.5S", st.Result.Duration)
+	assert.Equal(t, "hard", st.Result.Extensions[ExtensionGrade])
+	assert.Equal(t, "jp_en", st.Result.Extensions[ExtensionDirection])
+	assert.Equal(t, int64(3), st.Context.Find("https://portal.example.com/api", KindStudySession))
+	assert.Equal(t, int64(2), st.Context.Find("https://portal.example.com/api", KindStudyActivity))
+	assert.NoError(t, st.Validate())
+
+	review.StatementID = "2d6e0f4a-1b3c-4d5e-8f7a-9b0c1d2e3f4a"
+	assert.Equal(t, review.StatementID, FromReview(review, "", nil).ID)
+}
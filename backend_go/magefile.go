@@ -2,147 +2,179 @@
 package main
 
 import (
+	"context"
 	"database/sql"
-	"encoding/json"
 	"fmt"
-	"io/ioutil"
 	"os"
-	"path/filepath"
-	"sort"
-	"strings"
 
+	"github.com/gen-ai-bootcamp-2025/backend_go/db/migrations"
+	"github.com/gen-ai-bootcamp-2025/backend_go/internal/config"
+	"github.com/gen-ai-bootcamp-2025/backend_go/internal/importer"
+	"github.com/gen-ai-bootcamp-2025/backend_go/internal/models"
+	"github.com/gen-ai-bootcamp-2025/backend_go/internal/service"
 	_ "github.com/mattn/go-sqlite3"
 )
 
-const dbName = "words.db"
-
-type Word struct {
-	Japanese string `json:"japanese"`
-	Romaji   string `json:"romaji"`
-	English  string `json:"english"`
+// dbName is the database the server would use, from the same config file and
+// environment variables (see internal/config)
+func dbName() string {
+	cfg, _, err := config.Load(nil, os.Getenv)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	return cfg.DBPath
 }
 
 // InitDB initializes the SQLite database
 func InitDB() error {
-	if _, err := os.Stat(dbName); err == nil {
-		fmt.Printf("Database %s already exists\n", dbName)
+	path := dbName()
+	if _, err := os.Stat(path); err == nil {
+		fmt.Printf("Database %s already exists\n", path)
 		return nil
 	}
 
-	file, err := os.Create(dbName)
+	file, err := os.Create(path)
 	if err != nil {
 		return fmt.Errorf("error creating database: %v", err)
 	}
 	file.Close()
 
-	fmt.Printf("Created database %s\n", dbName)
+	fmt.Printf("Created database %s\n", path)
 	return nil
 }
 
-// Migrate runs all migration files in order
+// Migrate applies pending migrations in order
 func Migrate() error {
-	db, err := sql.Open("sqlite3", dbName)
-	if err != nil {
-		return fmt.Errorf("error opening database: %v", err)
-	}
-	defer db.Close()
-
-	files, err := ioutil.ReadDir("db/migrations")
-	if err != nil {
-		return fmt.Errorf("error reading migrations directory: %v", err)
-	}
-
-	var migrations []string
-	for _, f := range files {
-		if strings.HasSuffix(f.Name(), ".sql") {
-			migrations = append(migrations, f.Name())
+	return withMigrator(func(m *migrations.Migrator) error {
+		applied, err := m.Up()
+		for _, migration := range applied {
+			fmt.Printf("Applied migration: %04d_%s\n", migration.Version, migration.Name)
 		}
-	}
-	sort.Strings(migrations)
-
-	for _, migration := range migrations {
-		fmt.Printf("Running migration: %s\n", migration)
-		content, err := ioutil.ReadFile(filepath.Join("db/migrations", migration))
 		if err != nil {
-			return fmt.Errorf("error reading migration file %s: %v", migration, err)
+			return err
+		}
+		if len(applied) == 0 {
+			fmt.Println("Database is up to date")
 		}
+		return nil
+	})
+}
 
-		_, err = db.Exec(string(content))
+// MigrateStatus lists migrations and whether each has been applied
+func MigrateStatus() error {
+	return withMigrator(func(m *migrations.Migrator) error {
+		statuses, err := m.Status()
 		if err != nil {
-			return fmt.Errorf("error executing migration %s: %v", migration, err)
+			return err
 		}
-	}
+		for _, s := range statuses {
+			state := "pending"
+			if s.Applied {
+				state = "applied " + s.AppliedAt.Format("2006-01-02 15:04:05")
+			}
+			if s.Modified {
+				state += " (modified since applied)"
+			}
+			fmt.Printf("%04d_%s  %s\n", s.Version, s.Name, state)
+		}
+		return nil
+	})
+}
 
-	return nil
+// Rollback reverts the given number of most recently applied migrations
+func Rollback(steps int) error {
+	if steps < 1 {
+		return fmt.Errorf("steps must be at least 1")
+	}
+	return withMigrator(func(m *migrations.Migrator) error {
+		reverted, err := m.Rollback(steps)
+		for _, migration := range reverted {
+			fmt.Printf("Rolled back migration: %04d_%s\n", migration.Version, migration.Name)
+		}
+		return err
+	})
 }
 
-// Seed imports data from JSON files in the seeds directory
-func Seed() error {
-	db, err := sql.Open("sqlite3", dbName)
+func withMigrator(fn func(m *migrations.Migrator) error) error {
+	db, err := sql.Open("sqlite3", models.DSN(dbName()))
 	if err != nil {
 		return fmt.Errorf("error opening database: %v", err)
 	}
 	defer db.Close()
 
-	files, err := ioutil.ReadDir("db/seeds")
+	m, err := migrations.New(db, migrations.FS)
 	if err != nil {
-		return fmt.Errorf("error reading seeds directory: %v", err)
+		return fmt.Errorf("error loading migrations: %v", err)
 	}
+	return fn(m)
+}
 
-	for _, f := range files {
-		if !strings.HasSuffix(f.Name(), ".json") {
-			continue
-		}
-
-		groupName := strings.TrimSuffix(f.Name(), ".json")
-		fmt.Printf("Seeding group: %s\n", groupName)
+// Seed imports the JSON files in the seeds directory. Running it again only
+// applies what changed.
+func Seed() error {
+	return seed(false)
+}
 
-		// Create group
-		result, err := db.Exec("INSERT INTO groups (name) VALUES (?)", groupName)
-		if err != nil {
-			return fmt.Errorf("error creating group %s: %v", groupName, err)
-		}
+// SeedDryRun reports what Seed would change without writing anything
+func SeedDryRun() error {
+	return seed(true)
+}
 
-		groupID, err := result.LastInsertId()
-		if err != nil {
-			return fmt.Errorf("error getting group ID: %v", err)
-		}
+func seed(dryRun bool) error {
+	db, err := sql.Open("sqlite3", models.DSN(dbName()))
+	if err != nil {
+		return fmt.Errorf("error opening database: %v", err)
+	}
+	defer db.Close()
 
-		// Read and parse words
-		content, err := ioutil.ReadFile(filepath.Join("db/seeds", f.Name()))
-		if err != nil {
-			return fmt.Errorf("error reading seed file %s: %v", f.Name(), err)
-		}
+	results, err := importer.SeedDir(context.Background(), models.NewDB(db), "db/seeds", dryRun)
+	for _, result := range results {
+		fmt.Println(importer.Summary(result))
+	}
+	return err
+}
 
-		var words []Word
-		if err := json.Unmarshal(content, &words); err != nil {
-			return fmt.Errorf("error parsing seed file %s: %v", f.Name(), err)
-		}
+// Import imports a CSV, TSV or Anki (.apkg) file into the named group,
+// creating it if needed. Set IMPORT_MAPPING to a JSON object such as
+// {"japanese":"Kanji"} when the columns are not named japanese, romaji and
+// english.
+func Import(file, group string) error {
+	db, err := sql.Open("sqlite3", models.DSN(dbName()))
+	if err != nil {
+		return fmt.Errorf("error opening database: %v", err)
+	}
+	defer db.Close()
 
-		// Insert words and create word-group associations
-		for _, word := range words {
-			result, err := db.Exec(
-				"INSERT INTO words (japanese, romaji, english) VALUES (?, ?, ?)",
-				word.Japanese, word.Romaji, word.English,
-			)
-			if err != nil {
-				return fmt.Errorf("error inserting word %v: %v", word, err)
-			}
+	mapping, err := importer.ParseMapping(os.Getenv("IMPORT_MAPPING"))
+	if err != nil {
+		return err
+	}
 
-			wordID, err := result.LastInsertId()
-			if err != nil {
-				return fmt.Errorf("error getting word ID: %v", err)
-			}
+	f, err := os.Open(file)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil {
+		return err
+	}
 
-			_, err = db.Exec(
-				"INSERT INTO words_groups (word_id, group_id) VALUES (?, ?)",
-				wordID, groupID,
-			)
-			if err != nil {
-				return fmt.Errorf("error creating word-group association: %v", err)
-			}
-		}
+	report, err := service.NewService(models.NewDB(db)).ImportWords(context.Background(), service.ImportRequest{
+		FileName:  file,
+		File:      f,
+		Size:      info.Size(),
+		Mapping:   mapping,
+		GroupName: group,
+	})
+	if err != nil {
+		return err
 	}
 
+	fmt.Println(importer.Summary(report.ImportResult))
+	for _, e := range report.Errors {
+		fmt.Printf("  row %d: %s %s\n", e.Row, e.Field, e.Message)
+	}
 	return nil
 }